@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultAIModel --ai-model 的默认值
+const defaultAIModel = "gpt-4o-mini"
+
+// maxAIInput 发送给模型的最大字节数，超出时只发送文件大纲
+const maxAIInput = 24 * 1024
+
+// aiClient --ai-summaries 使用的 HTTP 客户端
+var aiClient = &http.Client{Timeout: 90 * time.Second}
+
+// aiPrompt 生成摘要的系统提示
+const aiPrompt = "You are documenting a source repository. Summarize what the given file does in at most two sentences. " +
+	"Mention its main responsibilities and key types or functions. Reply with the summary only."
+
+// outlineDeclRe 大纲中保留的声明行 (函数、类型、类、导出等)
+var outlineDeclRe = regexp.MustCompile(`^\s*(func|type|class|def|interface|struct|enum|trait|impl|module|export|public|private|protected|fn|pub|package|namespace)\b`)
+
+// aiInput 小文件直接发送全文；大文件发送开头部分与声明行组成的大纲
+func aiInput(content []byte) string {
+	if len(content) <= maxAIInput {
+		return string(content)
+	}
+	var sb strings.Builder
+	sb.WriteString("(file too large, outline follows)\n")
+	sb.Write(content[:maxAIInput/3])
+	sb.WriteString("\n...\n")
+	for _, line := range strings.Split(string(content[maxAIInput/3:]), "\n") {
+		if sb.Len() >= maxAIInput {
+			break
+		}
+		if outlineDeclRe.MatchString(line) {
+			sb.WriteString(strings.TrimRight(line, " \t{") + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// aiEndpointURL 补全 OpenAI 兼容接口的 chat/completions 路径
+func aiEndpointURL(endpoint string) string {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if strings.HasSuffix(endpoint, "/chat/completions") {
+		return endpoint
+	}
+	return endpoint + "/chat/completions"
+}
+
+// aiCachePath 按模型与内容哈希计算摘要缓存文件路径
+func aiCachePath(input string) string {
+	base := config.CacheDir
+	if base == "" {
+		dir, err := defaultCacheDir()
+		if err != nil {
+			return ""
+		}
+		base = dir
+	}
+	h := sha256.Sum256([]byte(config.AIModel + "\x00" + aiPrompt + "\x00" + input))
+	key := hex.EncodeToString(h[:])
+	return filepath.Join(base, "ai-summaries", key[:2], key)
+}
+
+// chatRequest / chatResponse OpenAI 兼容的 chat/completions 请求与响应
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// aiSummary 返回文件的两句话摘要，结果按内容哈希缓存；失败时返回空字符串并计为警告
+func aiSummary(displayPath string, content []byte) string {
+	input := aiInput(content)
+	cachePath := aiCachePath(input)
+	if cachePath != "" {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			return string(data)
+		}
+	}
+
+	summary, err := requestAISummary(displayPath, input)
+	if err != nil {
+		logf("[WARN] AI 摘要失败: %s (%v)\n", displayPath, err)
+		stats.Warnings++
+		return ""
+	}
+	if cachePath != "" && os.MkdirAll(filepath.Dir(cachePath), 0o700) == nil {
+		os.WriteFile(cachePath, []byte(summary), 0o600)
+	}
+	return summary
+}
+
+// requestAISummary 调用 --ai-endpoint 生成摘要，API key 取自 DIR2TXT_AI_KEY 或 OPENAI_API_KEY
+func requestAISummary(displayPath string, input string) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model: config.AIModel,
+		Messages: []chatMessage{
+			{Role: "system", Content: aiPrompt},
+			{Role: "user", Content: fmt.Sprintf("File: %s\n\n%s", displayPath, input)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, aiEndpointURL(config.AIEndpoint), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	key := os.Getenv("DIR2TXT_AI_KEY")
+	if key == "" {
+		key = os.Getenv("OPENAI_API_KEY")
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := aiClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	var parsed chatResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return "", fmt.Errorf("HTTP %d: 响应不是有效的 JSON", resp.StatusCode)
+	}
+	if parsed.Error != nil {
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, parsed.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK || len(parsed.Choices) == 0 {
+		return "", fmt.Errorf("HTTP %d: 响应中没有结果", resp.StatusCode)
+	}
+	summary := strings.Join(strings.Fields(parsed.Choices[0].Message.Content), " ")
+	if summary == "" {
+		return "", fmt.Errorf("模型返回了空摘要")
+	}
+	return summary, nil
+}
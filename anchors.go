@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// headingAnchor 按 GitHub 的规则生成标题锚点：转为小写，去掉字母、数字、_、- 与空格以外的字符，空格替换为 -
+func headingAnchor(text string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case r == ' ':
+			sb.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// anchorSet 按文档中标题出现的顺序分配锚点，重复的锚点与 GitHub 一样依次追加 -1、-2
+type anchorSet map[string]int
+
+func (s anchorSet) next(anchor string) string {
+	n, seen := s[anchor]
+	s[anchor] = n + 1
+	if !seen {
+		return anchor
+	}
+	return fmt.Sprintf("%s-%d", anchor, n)
+}
+
+// headingTextAnchors 按标题文本链接的格式 (--flavor obsidian、Org)：锚点即文件章节的标题
+func headingTextAnchors(r renderer, candidates []candidateFile) map[string]string {
+	anchors := make(map[string]string, len(candidates))
+	for _, c := range candidates {
+		anchors[c.Path] = r.fileHeadingText(filepath.ToSlash(c.Path))
+	}
+	return anchors
+}
+
+// githubAnchors 按 GitHub 的规则为文件章节分配锚点
+func githubAnchors(r renderer, candidates []candidateFile) map[string]string {
+	// 文件章节之前的固定标题
+	used := anchorSet{}
+	used.next(headingAnchor("Project Structure"))
+	used.next(headingAnchor("File Contents"))
+	anchors := make(map[string]string, len(candidates))
+	for _, c := range candidates {
+		anchors[c.Path] = used.next(headingAnchor(r.fileHeadingText(filepath.ToSlash(c.Path))))
+	}
+	return anchors
+}
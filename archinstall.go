@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// releaseDownloadTimeout --release-url 下载原生版本的超时时间
+const releaseDownloadTimeout = 2 * time.Minute
+
+// releaseAssetName 与 build.sh 产物一致的文件名，如 dir2txt_windows_arm64.exe
+func releaseAssetName(goos string, goarch string) string {
+	return "dir2txt_" + goos + "_" + goarch + exeSuffix(goos)
+}
+
+func exeSuffix(goos string) string {
+	if goos == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// releaseURL 展开 --release-url 模板中的 {os}、{arch}、{ext} 与 {name}
+func releaseURL(template string, goos string, goarch string) string {
+	return strings.NewReplacer(
+		"{os}", goos,
+		"{arch}", goarch,
+		"{ext}", exeSuffix(goos),
+		"{name}", releaseAssetName(goos, goarch),
+	).Replace(template)
+}
+
+// installSource 返回 --install 要复制的程序路径
+// 当前程序运行在转译层下 (如 ARM64 Windows 上的 amd64 程序、Rosetta 下的 macOS 程序) 时给出提示，
+// 指定了 --release-url 则下载原生架构的版本安装；--static 要求安装的程序是静态链接的，以便在 musl (Alpine) 容器中运行
+func installSource() (string, func(), error) {
+	noop := func() {}
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", noop, err
+	}
+	if realPath, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = realPath
+	}
+
+	src, cleanup := exePath, noop
+	if native := nativeArch(); native != "" && native != runtime.GOARCH {
+		fmt.Printf("[WARNING] 当前程序为 %s/%s，但系统原生架构为 %s，正在转译层下运行\n", runtime.GOOS, runtime.GOARCH, native)
+		if config.ReleaseURL == "" {
+			fmt.Printf("可通过 --release-url 下载原生版本 (%s)，或直接安装该版本，以避免转译带来的性能损失\n", releaseAssetName(runtime.GOOS, native))
+		} else {
+			path, err := downloadRelease(releaseURL(config.ReleaseURL, runtime.GOOS, native), runtime.GOOS, native)
+			if err != nil {
+				return "", noop, err
+			}
+			src, cleanup = path, func() { os.Remove(path) }
+		}
+	}
+
+	if config.InstallStatic {
+		if err := checkStaticBinary(src); err != nil {
+			cleanup()
+			return "", noop, err
+		}
+	}
+	return src, cleanup, nil
+}
+
+// releaseHTTPClient 下载原生版本使用的 HTTP 客户端：只允许 https，重定向到 http 时中止
+func releaseHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: releaseDownloadTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("拒绝重定向到非 https 地址: %s", req.URL)
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("重定向次数过多")
+			}
+			return nil
+		},
+	}
+}
+
+// fetchReleaseChecksum 读取与程序一同发布的校验文件 <URL>.sha256 (sha256sum 的输出格式，取第一个字段)
+func fetchReleaseChecksum(client *http.Client, rawURL string) ([]byte, error) {
+	checksumURL := rawURL + ".sha256"
+	resp, err := client.Get(checksumURL)
+	if err != nil {
+		return nil, fmt.Errorf("无法下载校验文件: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("无法下载校验文件: %s 返回 %s", checksumURL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return nil, fmt.Errorf("无法下载校验文件: %v", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("校验文件为空: %s", checksumURL)
+	}
+	sum, err := hex.DecodeString(fields[0])
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("校验文件不是 SHA-256 摘要: %s", checksumURL)
+	}
+	return sum, nil
+}
+
+// downloadRelease 通过 https 下载指定平台的程序到临时文件，按发布的 SHA-256 校验内容，并确认其目标平台与预期一致；
+// 任一步失败都会删除临时文件，不会替换已安装的程序
+func downloadRelease(rawURL string, goos string, goarch string) (string, error) {
+	if u, err := url.Parse(rawURL); err != nil || u.Scheme != "https" {
+		return "", fmt.Errorf("只能通过 https 下载程序: %s", rawURL)
+	}
+	fmt.Printf("正在下载原生版本: %s\n", rawURL)
+	client := releaseHTTPClient()
+	want, err := fetchReleaseChecksum(client, rawURL)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("下载失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("下载失败: %s 返回 %s", rawURL, resp.Status)
+	}
+
+	f, err := os.CreateTemp("", "dir2txt-release-*"+exeSuffix(goos))
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("下载失败: %v", err)
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("下载的程序校验失败: SHA-256 为 %x，发布的校验值为 %x", got, want)
+	}
+	fmt.Printf("SHA-256 校验通过: %x\n", want)
+
+	gotOS, gotArch, err := binaryPlatform(f.Name())
+	if err != nil || gotOS != goos || gotArch != goarch {
+		os.Remove(f.Name())
+		if err != nil {
+			return "", fmt.Errorf("下载的文件不是可执行程序: %v", err)
+		}
+		return "", fmt.Errorf("下载的程序为 %s/%s，与系统 %s/%s 不符", gotOS, gotArch, goos, goarch)
+	}
+	os.Chmod(f.Name(), 0o755)
+	return f.Name(), nil
+}
+
+// binaryPlatform 从可执行文件头识别目标系统与架构 (GOOS/GOARCH 命名)，无法识别的部分为空串
+func binaryPlatform(path string) (string, string, error) {
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		return elfOS(f), elfArch(f), nil
+	}
+	if f, err := pe.Open(path); err == nil {
+		defer f.Close()
+		arch := map[uint16]string{pe.IMAGE_FILE_MACHINE_AMD64: "amd64", pe.IMAGE_FILE_MACHINE_ARM64: "arm64", pe.IMAGE_FILE_MACHINE_I386: "386", pe.IMAGE_FILE_MACHINE_ARMNT: "arm"}[f.Machine]
+		return "windows", arch, nil
+	}
+	if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		arch := map[macho.Cpu]string{macho.CpuAmd64: "amd64", macho.CpuArm64: "arm64"}[f.Cpu]
+		return "darwin", arch, nil
+	}
+	return "", "", fmt.Errorf("无法识别的文件格式: %s", path)
+}
+
+// elfOS 按 OS/ABI 字段识别 ELF 程序的目标系统；Linux、NetBSD 与 OpenBSD 的程序通常为 SYSV (0)，
+// 后两者以各自的标识 note 段区分
+func elfOS(f *elf.File) string {
+	switch f.OSABI {
+	case elf.ELFOSABI_FREEBSD:
+		return "freebsd"
+	case elf.ELFOSABI_NETBSD:
+		return "netbsd"
+	case elf.ELFOSABI_OPENBSD:
+		return "openbsd"
+	case elf.ELFOSABI_SOLARIS:
+		return "solaris"
+	case elf.ELFOSABI_NONE, elf.ELFOSABI_LINUX:
+	default:
+		return ""
+	}
+	switch {
+	case f.Section(".note.netbsd.ident") != nil:
+		return "netbsd"
+	case f.Section(".note.openbsd.ident") != nil:
+		return "openbsd"
+	}
+	return "linux"
+}
+
+// elfArch 按机器类型、位数与字节序识别 ELF 程序的架构
+func elfArch(f *elf.File) string {
+	is64 := f.Class == elf.ELFCLASS64
+	le := f.Data == elf.ELFDATA2LSB
+	switch f.Machine {
+	case elf.EM_X86_64:
+		if is64 {
+			return "amd64"
+		}
+	case elf.EM_386:
+		return "386"
+	case elf.EM_AARCH64:
+		return "arm64"
+	case elf.EM_ARM:
+		return "arm"
+	case elf.EM_RISCV:
+		if is64 {
+			return "riscv64"
+		}
+	case elf.EM_LOONGARCH:
+		return "loong64"
+	case elf.EM_S390:
+		if is64 {
+			return "s390x"
+		}
+	case elf.EM_PPC64:
+		if le {
+			return "ppc64le"
+		}
+		return "ppc64"
+	case elf.EM_MIPS:
+		arch := "mips"
+		if is64 {
+			arch = "mips64"
+		}
+		if le {
+			arch += "le"
+		}
+		return arch
+	}
+	return ""
+}
+
+// checkStaticBinary 确认程序是不依赖动态链接器的 Linux 可执行文件；依赖 glibc 的程序无法在 Alpine 等 musl 系统中运行
+func checkStaticBinary(path string) error {
+	f, err := elf.Open(path)
+	if err != nil {
+		return fmt.Errorf("--static 只适用于 Linux 可执行文件: %s", path)
+	}
+	defer f.Close()
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_INTERP {
+			libs, _ := f.ImportedLibraries()
+			return fmt.Errorf("%s 是动态链接的 (依赖 %s)，无法在 musl 容器中运行；请使用 CGO_ENABLED=0 构建的版本 (build.sh 的产物均为静态链接)", path, strings.Join(libs, ", "))
+		}
+	}
+	fmt.Println("已确认程序为静态链接，可在 Alpine 等 musl 系统中运行")
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveOutputPath 输出路径本身是符号链接时改为写入链接指向的文件，避免改名时用普通文件替换掉链接
+func resolveOutputPath(path string) string {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return path
+	}
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+	return target
+}
+
+// partialPath 写入过程中使用的临时文件：与输出文件位于同一目录 (保证改名是原子的)，
+// 以点开头以免出现在目录树中
+func partialPath(outPath string) string {
+	return filepath.Join(filepath.Dir(outPath), "."+filepath.Base(outPath)+".partial")
+}
+
+// commitOutput 将写完的临时文件改名为最终输出，--backup 时先把旧文件保留为 .bak
+func commitOutput(partial string, outPath string) error {
+	if config.Backup {
+		if _, err := os.Stat(outPath); err == nil {
+			if err := os.Rename(outPath, outPath+".bak"); err != nil {
+				return fmt.Errorf("无法备份旧的输出文件: %v", err)
+			}
+			logf("旧的输出已备份为: %s\n", outPath+".bak")
+		}
+	}
+	return os.Rename(partial, outPath)
+}
+
+// versionedPath 输出文件已存在时依次尝试 name.2.md、name.3.md ...，返回第一个不存在的路径
+func versionedPath(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return path
+	}
+	// 压缩后缀之前的部分才是格式后缀，如 name.md.gz -> name.2.md.gz
+	base, method := trimCompressExt(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s.%d%s", stem, n, ext)
+		if method != "" {
+			candidate = compressedPath(candidate, method)
+		}
+		if _, err := os.Stat(candidate); err != nil {
+			return candidate
+		}
+	}
+}
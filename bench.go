@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/pprof"
+	"runtime/trace"
+	"time"
+)
+
+// benchStages --bench 报告的阶段，按处理顺序排列
+var benchStages = []string{"tree", "walk", "read", "convert", "write"}
+
+// stageTimes 各阶段累计耗时
+var stageTimes = map[string]time.Duration{}
+
+// onStage 每个阶段结束时调用的钩子，便于外部代码对各步骤做插桩
+var onStage func(stage string, elapsed time.Duration)
+
+// startStage 开始计时，返回的函数在阶段结束时调用
+//
+//	defer startStage("walk")()
+func startStage(stage string) func() {
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		stageTimes[stage] += elapsed
+		if onStage != nil {
+			onStage(stage, elapsed)
+		}
+	}
+}
+
+// profiling 正在进行的 CPU profile / trace 输出文件
+var profiling struct {
+	cpu   *os.File
+	trace *os.File
+}
+
+// startProfiling 按 --profile / --trace 开始采集
+func startProfiling() error {
+	if config.ProfileFile != "" {
+		f, err := os.Create(config.ProfileFile)
+		if err != nil {
+			return fmt.Errorf("无法创建 profile 文件: %v", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("无法开始 CPU profile: %v", err)
+		}
+		profiling.cpu = f
+	}
+	if config.TraceFile != "" {
+		f, err := os.Create(config.TraceFile)
+		if err != nil {
+			return fmt.Errorf("无法创建 trace 文件: %v", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			return fmt.Errorf("无法开始 trace: %v", err)
+		}
+		profiling.trace = f
+	}
+	return nil
+}
+
+// stopProfiling 结束采集并关闭文件，可重复调用
+func stopProfiling() {
+	if profiling.cpu != nil {
+		pprof.StopCPUProfile()
+		profiling.cpu.Close()
+		fmt.Printf("CPU profile 已写入: %s\n", config.ProfileFile)
+		profiling.cpu = nil
+	}
+	if profiling.trace != nil {
+		trace.Stop()
+		profiling.trace.Close()
+		fmt.Printf("trace 已写入: %s\n", config.TraceFile)
+		profiling.trace = nil
+	}
+}
+
+// printBench 打印 --bench 的各阶段耗时与吞吐量
+func printBench(total time.Duration) {
+	fmt.Println("阶段耗时:")
+	for _, stage := range benchStages {
+		fmt.Printf("  %-8s %10s\n", stage, stageTimes[stage].Round(time.Microsecond))
+	}
+	fmt.Printf("  %-8s %10s\n", "total", total.Round(time.Microsecond))
+	if secs := total.Seconds(); secs > 0 {
+		fmt.Printf("吞吐量: %.0f 文件/秒, %s/秒 (输出 %s, 约 %d tokens)\n",
+			float64(stats.Included)/secs, formatSize(int64(float64(output.bytes)/secs)), formatSize(output.bytes), output.Tokens())
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+// bomSuffix 带 BOM 的文件在编码名称后追加的标记，写入文件标题下的元数据
+const bomSuffix = " with BOM"
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// hasUTF16BOM 以 UTF-16 BOM 开头的文本含有大量 NUL 字节，不能按二进制检测
+func hasUTF16BOM(content []byte) bool {
+	return bytes.HasPrefix(content, utf16LEBOM) || bytes.HasPrefix(content, utf16BEBOM)
+}
+
+// decodeBOM 按 BOM 去除或转换内容，没有 BOM 时 ok 为 false
+func decodeBOM(content []byte) (decoded []byte, encoding string, ok bool) {
+	switch {
+	case bytes.HasPrefix(content, utf8BOM):
+		return content[len(utf8BOM):], "UTF-8" + bomSuffix, true
+	case bytes.HasPrefix(content, utf16LEBOM):
+		// ExpectBOM 会校验并去除 BOM
+		out, err := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Bytes(content)
+		return out, "UTF-16LE" + bomSuffix, err == nil
+	case bytes.HasPrefix(content, utf16BEBOM):
+		out, err := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder().Bytes(content)
+		return out, "UTF-16BE" + bomSuffix, err == nil
+	}
+	return nil, "", false
+}
+
+// bomNote 根据编码名称返回原文件的 BOM 说明，没有 BOM 时为空
+func bomNote(encoding string) string {
+	name, ok := strings.CutSuffix(encoding, bomSuffix)
+	if !ok {
+		return ""
+	}
+	return name
+}
+
+// detectUTF16 检查没有 BOM 的内容是否为 UTF-16：ASCII 为主的 UTF-16 文本中，NUL 字节几乎全部落在奇数或偶数位置
+func detectUTF16(content []byte) (unicode.Endianness, bool) {
+	if len(content) < 4 {
+		return unicode.LittleEndian, false
+	}
+	var even, odd int
+	for i, b := range content {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			even++
+		} else {
+			odd++
+		}
+	}
+	pairs := len(content) / 2
+	switch {
+	case odd*10 >= pairs*7 && even*20 <= pairs:
+		return unicode.LittleEndian, true
+	case even*10 >= pairs*7 && odd*20 <= pairs:
+		return unicode.BigEndian, true
+	}
+	return unicode.LittleEndian, false
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// minTruncateTokens 剩余预算低于该值时不再截断文件，直接丢弃
+const minTruncateTokens = 200
+
+// omittedFile 记录因预算等原因未完整输出的文件
+type omittedFile struct {
+	DisplayPath string
+	Tokens      int64
+	Reason      string
+}
+
+// omitted 本次运行中被丢弃或截断的文件，在内容末尾以附录形式列出
+var omitted []omittedFile
+
+// sectionTokens 估算文件写入后占用的 token 数 (包括标题与代码块)
+func sectionTokens(fc *fileContent) int64 {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	renderFileSection(fc, newDocWriter(w, config.Format))
+	w.Flush()
+	return estimateTokens(buf.Bytes())
+}
+
+// trimPriority 按裁剪策略对文件排序，返回的顺序中越靠前优先级越高 (越不容易被丢弃)
+func trimPriority(files []*fileContent, tokens map[*fileContent]int64, strategy string) []*fileContent {
+	ordered := append([]*fileContent{}, files...)
+	switch strategy {
+	case "oldest":
+		// 最近修改的文件优先保留
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].ModTime.After(ordered[j].ModTime)
+		})
+	case "importance":
+		ordered = rankFiles(ordered, scoreFiles(files))
+	default:
+		// size: 契约文件 (.proto、GraphQL、OpenAPI) 优先保留，其余小文件优先，先丢弃最大的文件
+		sort.SliceStable(ordered, func(i, j int) bool {
+			ci := contractKind(filepath.Base(ordered[i].Path)) != ""
+			cj := contractKind(filepath.Base(ordered[j].Path)) != ""
+			if ci != cj {
+				return ci
+			}
+			return tokens[ordered[i]] < tokens[ordered[j]]
+		})
+	}
+	return ordered
+}
+
+// truncateToTokens 按行截断内容，使其估算 token 数不超过 limit
+func truncateToTokens(content []byte, limit int64) ([]byte, int) {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	var used int64
+	for i, line := range lines {
+		used += estimateTokens(line)
+		if used > limit {
+			return bytes.Join(lines[:i], nil), i
+		}
+	}
+	return content, len(lines)
+}
+
+// selectWithinBudget 在剩余预算内按策略挑选文件，必要时截断一个文件
+// 返回需要写入的文件 (保持原有顺序)
+func selectWithinBudget(files []*fileContent, budget int64, strategy string) []*fileContent {
+	tokens := map[*fileContent]int64{}
+	var total int64
+	for _, fc := range files {
+		tokens[fc] = sectionTokens(fc)
+		total += tokens[fc]
+	}
+	if total <= budget {
+		return files
+	}
+
+	keep := map[*fileContent]bool{}
+	remaining := budget
+	for _, fc := range trimPriority(files, tokens, strategy) {
+		if tokens[fc] <= remaining {
+			keep[fc] = true
+			remaining -= tokens[fc]
+			continue
+		}
+		if remaining >= minTruncateTokens {
+			// 为标题、代码块和截断说明预留空间
+			overhead := tokens[fc] - fc.Tokens + 40
+			truncated, lines := truncateToTokens(fc.data(), remaining-overhead)
+			if lines > 0 {
+				logf("[TRIM] 截断文件 (保留前 %d 行): %s\n", lines, fc.DisplayPath)
+				omitted = append(omitted, omittedFile{fc.DisplayPath, tokens[fc], fmt.Sprintf("truncated to %d lines", lines)})
+				// 截断后的内容不再占用预算，写入后的 releaseContent 不会重复归还
+				releaseContent(fc)
+				fc.Content = truncated
+				fc.Note = fmt.Sprintf("(truncated to the first %d lines to fit the token budget)", lines)
+				keep[fc] = true
+				remaining = 0
+				continue
+			}
+		}
+		logf("[TRIM] 超出预算，丢弃文件 (约 %d tokens): %s\n", tokens[fc], fc.DisplayPath)
+		omitted = append(omitted, omittedFile{fc.DisplayPath, tokens[fc], "dropped (token budget)"})
+		noteExcluded(fc.Path, fc.DisplayPath)
+		noteExclusion("超出 token 预算 (--budget-tokens)")
+		recordSkipped(fc.Path)
+		releaseContent(fc)
+	}
+
+	var selected []*fileContent
+	for _, fc := range files {
+		if keep[fc] {
+			selected = append(selected, fc)
+		}
+	}
+	return selected
+}
+
+// writeOmittedAppendix 在内容末尾列出未完整输出的文件
+func writeOmittedAppendix(writer *docWriter) {
+	if len(omitted) == 0 {
+		return
+	}
+	writer.WriteString(writer.docHeading("Omitted Files"))
+	var rows [][]string
+	for _, o := range omitted {
+		rows = append(rows, []string{o.DisplayPath, strconv.FormatInt(o.Tokens, 10), o.Reason})
+	}
+	writer.writeTable([]string{"File", "Est. Tokens", "Reason"}, rows)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSelectWithinBudgetReleasesOnce 丢弃、截断与写入后归还的内存预算之和恰好等于占用的预算
+func TestSelectWithinBudgetReleasesOnce(t *testing.T) {
+	saved := config
+	t.Cleanup(func() {
+		config = saved
+		omitted = nil
+		memoryBudget.used = 0
+	})
+	config.Format = "md"
+	config.MaxMemory = 1 << 20
+
+	line := strings.Repeat("x", 79) + "\n"
+	var files []*fileContent
+	for i, lines := range []int{10, 200, 400} {
+		content := []byte(strings.Repeat(line, lines))
+		fc := &fileContent{Path: string(rune('a'+i)) + ".txt", DisplayPath: string(rune('a'+i)) + ".txt", Content: content, Tokens: estimateTokens(content)}
+		holdContent(fc)
+		files = append(files, fc)
+	}
+	if memoryBudget.used == 0 {
+		t.Fatal("holdContent reserved nothing")
+	}
+
+	selected := selectWithinBudget(files, sectionTokens(files[0])+sectionTokens(files[1])/2, "size")
+	if len(selected) != 2 || selected[0] != files[0] || selected[1] != files[1] {
+		t.Fatalf("selected %d files, want a.txt and truncated b.txt", len(selected))
+	}
+	if files[1].Note == "" {
+		t.Error("b.txt was not truncated")
+	}
+	for _, fc := range selected {
+		releaseContent(fc)
+		releaseContent(fc)
+	}
+	if memoryBudget.used != 0 {
+		t.Errorf("memory budget used = %d after releasing every file, want 0", memoryBudget.used)
+	}
+}
+
+// TestSelectWithinBudgetNotesExclusion 预算丢弃的文件计入排除原因，没有文件输出时的诊断不会误报"没有找到文件"
+func TestSelectWithinBudgetNotesExclusion(t *testing.T) {
+	saved := config
+	t.Cleanup(func() {
+		config = saved
+		omitted = nil
+		exclusionCounts = map[string]int{}
+	})
+	config.Format = "md"
+	exclusionCounts = map[string]int{}
+
+	var files []*fileContent
+	for _, name := range []string{"a.txt", "b.txt"} {
+		content := []byte(strings.Repeat("some text\n", 100))
+		files = append(files, &fileContent{Path: name, DisplayPath: name, Content: content, Tokens: estimateTokens(content)})
+	}
+	if selected := selectWithinBudget(files, 50, "size"); len(selected) != 0 {
+		t.Fatalf("selected %d files with a 50 token budget, want 0", len(selected))
+	}
+	if got := exclusionCounts["超出 token 预算 (--budget-tokens)"]; got != 2 {
+		t.Errorf("budget exclusions = %d, want 2 (counts: %v)", got, exclusionCounts)
+	}
+}
@@ -1,10 +1,10 @@
 #!/bin/bash
 
-# 程序源文件名
-SRC_FILE="dir2txt.go"
+# 程序源码目录
+SRC_FILE="."
 # 输出的基础名称
 APP_NAME="dir2txt"
-# 输出目录
+# 输出目录 (所有产物均以 CGO_ENABLED=0 静态链接，Linux 版本可直接用于 Alpine 等 musl 容器，见 --install --static)
 BUILD_PATH="build"
 
 echo "开始构建..."
@@ -28,5 +28,12 @@ CGO_ENABLED=0 GOOS=windows GOARCH=amd64 go build -ldflags="-s -w" -o "${BUILD_PA
 echo "Building Windows (arm64)..."
 CGO_ENABLED=0 GOOS=windows GOARCH=arm64 go build -ldflags="-s -w" -o "${BUILD_PATH}/${APP_NAME}_windows_arm64.exe" $SRC_FILE
 
+# 为每个产物生成 SHA-256 校验文件 (<文件名>.sha256)，与产物一同发布，--install --release-url 下载后据此校验
+echo "Generating SHA-256 checksums..."
+for f in "$BUILD_PATH"/${APP_NAME}_*; do
+    case "$f" in *.sha256) continue ;; esac
+    (cd "$BUILD_PATH" && sha256sum "$(basename "$f")" > "$(basename "$f").sha256")
+done
+
 echo "构建完成！文件已生成在目录 ${BUILD_PATH}。"
 ls -lh "$BUILD_PATH"
\ No newline at end of file
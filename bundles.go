@@ -0,0 +1,20 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// hashedAssetRe 构建工具生成的带内容哈希的文件名，如 app.3f9c2a.js、index-BQ3kx8y2.css
+var hashedAssetRe = regexp.MustCompile(`^.+[.-]([A-Za-z0-9_]{6,})\.(js|mjs|cjs|css)$`)
+
+// isHashedAsset 判断是否为带内容哈希的打包产物：哈希段需同时包含数字和字母，避免误判 index-helpers.js 之类的普通文件名
+func isHashedAsset(name string) bool {
+	m := hashedAssetRe.FindStringSubmatch(strings.ToLower(filepath.Base(name)))
+	if m == nil {
+		return false
+	}
+	hash := m[1]
+	return strings.ContainsAny(hash, "0123456789") && strings.ContainsAny(hash, "abcdefghijklmnopqrstuvwxyz")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// cacheVersion 读取与转换逻辑变化时递增，使旧缓存失效
+const cacheVersion = 2
+
+// cacheEntry 单个文件的缓存内容：二进制判定或转换后的 UTF-8 内容
+type cacheEntry struct {
+	Binary   bool
+	Encoding string
+	Content  []byte
+	Tokens   int64
+}
+
+// defaultCacheDir 返回默认缓存目录 (如 ~/.cache/dir2txt)
+func defaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("无法确定缓存目录: %v", err)
+	}
+	return filepath.Join(dir, "dir2txt"), nil
+}
+
+// cacheFile 按路径、内容版本以及影响二进制检测与编码转换的设置 (--force-text、.editorconfig 声明的 charset、
+// --binary-window、--binary-threshold、--fallback-encoding) 计算缓存文件路径。
+// 在 git 仓库中且文件与索引一致时，内容版本为 blob OID，否则为修改时间与大小
+func cacheFile(path string, info os.FileInfo, forceText bool, charset string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	version := fmt.Sprintf("%d\x00%d", info.ModTime().UnixNano(), info.Size())
+	if oid, ok := gitBlobOID(abs); ok {
+		version = "blob:" + oid
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00%s\x00%t\x00%s\x00%d\x00%g\x00%s", cacheVersion, abs, version, forceText,
+		charset, config.BinaryWindow, config.BinaryThreshold, config.FallbackEncoding)
+	key := hex.EncodeToString(h.Sum(nil))
+	return filepath.Join(config.CacheDir, key[:2], key)
+}
+
+// loadCache 读取缓存，未启用缓存或未命中时返回 false
+func loadCache(path string, info os.FileInfo, forceText bool, charset string) (*cacheEntry, bool) {
+	if config.CacheDir == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(cacheFile(path, info, forceText, charset))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entry); err != nil {
+		return nil, false
+	}
+	stats.CacheHits++
+	return &entry, true
+}
+
+// storeCache 写入缓存，失败时忽略 (缓存只影响速度)
+func storeCache(path string, info os.FileInfo, forceText bool, charset string, entry *cacheEntry) {
+	if config.CacheDir == "" {
+		return
+	}
+	target := cacheFile(path, info, forceText, charset)
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entry); err != nil {
+		return
+	}
+	// 先写临时文件再改名，避免并发运行读到写了一半的缓存
+	tmp := fmt.Sprintf("%s.%d.tmp", target, os.Getpid())
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// exitInterrupted 被 Ctrl-C/SIGTERM 中断时的退出码 (与 shell 约定一致)
+const exitInterrupted = 130
+
+// interruptContext 返回在收到 Ctrl-C/SIGTERM 时取消的 context：
+// 第一次信号让遍历与写入在当前文件结束后停止，第二次信号立即退出
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ch
+		errorf("\n收到中断信号，正在结束当前文件并写入截断说明 (再次按 Ctrl-C 立即退出)\n")
+		cancel()
+		<-ch
+		os.Exit(exitInterrupted)
+	}()
+	return ctx
+}
+
+// writeCancelNotice 在输出末尾注明内容因中断而不完整
+func writeCancelNotice(writer *docWriter) {
+	if !writer.document() {
+		return
+	}
+	label := writer.strong("Output truncated:")
+	writer.WriteString(writer.blockQuote(fmt.Sprintf("%s the run was cancelled after %d files; the content above is incomplete.", label, stats.Included)))
+}
+
+// printCancelSummary 打印中断时已写入的内容概况，outPath 为保存部分内容的临时文件
+func printCancelSummary(outPath string) {
+	errorf("已中断: 已写入 %d 个文件，%s (约 %d tokens)，部分内容保存在: %s\n",
+		stats.Included, formatSize(output.bytes), output.Tokens(), outPath)
+	if _, err := os.Stat(resumeRun.path); resumeRun.path != "" && err == nil {
+		errorf("使用相同参数加上 --resume 可从中断处继续\n")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --ci 模式的约定 (供容器入口与 CI 脚本依赖):
+//  1. --install/--uninstall 直接以退出码 1 拒绝；不读取用户级配置 (~/.config/dir2txt/global)，只使用仓库级 .dir2txt 与命令行参数
+//  2. 不打印进度与 [SKIP]/[INFO] 日志；警告与错误以 JSON 行 {"level","tag","msg"} 写入标准错误
+//  3. 从不等待输入：标准输入不会被读取。外部命令只由显式启用的功能调用：--plugin 与钩子，
+//     git (--history、--cache-dir、--apply-diff 与 compare 子命令)，go list (--go-package、--go-graph)；
+//     这些命令的标准输入为空，git 禁止交互式提示凭据 (GIT_TERMINAL_PROMPT=0)
+//  4. 未指定 -o 或 -o 为目录时，输出文件名固定为 dir2txt_context.md (按 --format 使用对应后缀)
+//  5. 结束时在标准输出打印一行 JSON 运行结果 (输出路径、退出码、文件数、大小、token 数、警告数)，退出码与帮助中列出的一致
+
+// ciOutputName --ci 模式下固定的输出文件名 (不含后缀)
+const ciOutputName = "dir2txt_context"
+
+// ciLog --ci 模式下的一条结构化日志
+type ciLog struct {
+	Level string `json:"level"`
+	Tag   string `json:"tag,omitempty"`
+	Msg   string `json:"msg"`
+}
+
+// logLevels 日志标签对应的级别，未列出的标签与无标签的进度信息均为 info
+var logLevels = map[string]string{
+	"WARN":    "warn",
+	"WARNING": "warn",
+	"ERROR":   "error",
+}
+
+// splitLogTag 拆出消息开头的 [TAG]
+func splitLogTag(msg string) (tag, rest string) {
+	if strings.HasPrefix(msg, "[") {
+		if end := strings.Index(msg, "]"); end > 0 {
+			return msg[1:end], strings.TrimSpace(msg[end+1:])
+		}
+	}
+	return "", msg
+}
+
+// writeCILog 以 JSON 行写入标准错误，info 级别的日志被丢弃
+func writeCILog(defaultLevel string, msg string) {
+	msg = strings.TrimSpace(msg)
+	tag, rest := splitLogTag(msg)
+	level, ok := logLevels[tag]
+	if !ok {
+		level = defaultLevel
+	}
+	if level == "info" || rest == "" {
+		return
+	}
+	line, _ := json.Marshal(ciLog{Level: level, Tag: tag, Msg: rest})
+	fmt.Fprintln(os.Stderr, string(line))
+}
+
+// logf 打印运行日志 (进度、[SKIP]、[WARN] 等)；--ci 模式下只保留警告与错误
+func logf(format string, args ...any) {
+	if !config.CI {
+		progress.clearForLog()
+		fmt.Printf(format, args...)
+		return
+	}
+	writeCILog("info", fmt.Sprintf(format, args...))
+}
+
+// errorf 向标准错误打印错误信息；--ci 模式下为 JSON 行
+func errorf(format string, args ...any) {
+	if !config.CI {
+		progress.clear()
+		fmt.Fprintf(os.Stderr, format, args...)
+		return
+	}
+	writeCILog("error", fmt.Sprintf(format, args...))
+}
+
+// ciResult --ci 模式结束时打印的运行结果
+type ciResult struct {
+	Output   string `json:"output"`
+	ExitCode int    `json:"exit_code"`
+	Files    int    `json:"files"`
+	Bytes    int64  `json:"bytes"`
+	Tokens   int64  `json:"tokens"`
+	Warnings int    `json:"warnings"`
+}
+
+// printCIResult 在标准输出打印一行 JSON 运行结果
+func printCIResult(outPath string, code int) {
+	result := ciResult{
+		Output:   outPath,
+		ExitCode: code,
+		Files:    stats.Included,
+		Warnings: stats.Warnings + stats.EncodingErrors,
+	}
+	if abs, err := filepath.Abs(outPath); err == nil {
+		result.Output = abs
+	}
+	if output != nil {
+		result.Bytes, result.Tokens = output.bytes, output.Tokens()
+	}
+	line, _ := json.Marshal(result)
+	fmt.Println(string(line))
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// optionSetter 将解析到的参数值写入目标；values 的个数已按参数的取值方式检查过
+type optionSetter func(s *cliState, name string, values []string) error
+
+// cliState 解析命令行过程中收集的、不直接写入 config 的结果
+type cliState struct {
+	dirs             rawStringList
+	softFilters      multiValue // -f / --filter / -filter : 只过滤内容，不排除树
+	hardFilters      multiValue // -F / --Filter : 完全过滤，树和内容都不出现
+	out              string
+	help             bool
+	install          bool
+	uninstall        bool
+	noDefaultIgnores bool
+	ignoreDirs       multiValue
+	ignoreExts       multiValue
+	textExts         multiValue
+	unignore         multiValue
+	assets           multiValue
+	leftover         []string // 位置参数
+	layer            int      // 正在解析的参数组 (配置层) 序号，从 1 开始
+	formatLayer      int      // 指定 --format 的参数组序号；同一组中再次出现时追加为其余格式，后面的组中出现时替换
+}
+
+// 参数的取值方式，由 Arg 占位符决定
+const (
+	arityNone     = iota // 开关
+	arityOne             // 一个值: --x V 或 --x=V
+	arityOptional        // 可选值: --x 或 --x=V
+	arityMany            // 一个或多个值: 依次读取后续不以 - 开头的参数，或 --x=V
+)
+
+// arity 按 Arg 占位符返回取值方式：空为开关，[=V] 为可选值，以 ... 结尾为多个值
+func (opt *cliOption) arity() int {
+	switch {
+	case opt.Arg == "":
+		return arityNone
+	case strings.HasPrefix(opt.Arg, "["):
+		return arityOptional
+	case strings.HasSuffix(opt.Arg, "..."):
+		return arityMany
+	}
+	return arityOne
+}
+
+// optionIndex 参数名 (含别名) 到参数定义的索引
+func optionIndex() map[string]*cliOption {
+	index := map[string]*cliOption{}
+	for _, group := range optionGroups() {
+		for i := range group.Options {
+			for _, name := range group.Options[i].Names {
+				index[name] = &group.Options[i]
+			}
+		}
+	}
+	return index
+}
+
+// parse 按参数表解析命令行；单独出现的 -- 之后的参数均为位置参数，紧跟在多值参数后的 -- 之后的参数均为该参数的值；
+// 以 - 开头的值也可以写作 --x=V (单值参数还可直接写在参数名之后)
+func (s *cliState) parse(args []string) error {
+	index := optionIndex()
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			s.leftover = append(s.leftover, args[i+1:]...)
+			return nil
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			s.leftover = append(s.leftover, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(arg, "=")
+		opt, ok := index[name]
+		if !ok {
+			return unknownOptionError(name, index)
+		}
+		var values []string
+		switch opt.arity() {
+		case arityNone:
+			if hasValue {
+				return fmt.Errorf("%s 不接受参数值: %s", name, arg)
+			}
+		case arityOptional:
+			if hasValue {
+				values = []string{value}
+			}
+		case arityOne:
+			if hasValue {
+				values = []string{value}
+			} else if i+1 < len(args) {
+				i++
+				values = []string{args[i]}
+			} else {
+				return fmt.Errorf("%s 需要参数值 %s", name, opt.Arg)
+			}
+		case arityMany:
+			if hasValue {
+				values = []string{value}
+			}
+			// 紧跟在参数后的 -- 表示其后的参数全部是该参数的值，用于传入以 - 开头的目录或规则，如 --dir -- -weird-dir
+			if !hasValue && i+1 < len(args) && args[i+1] == "--" {
+				values = append(values, args[i+2:]...)
+				i = len(args)
+			}
+			for !hasValue && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				values = append(values, args[i])
+			}
+			if len(values) == 0 {
+				if i+1 < len(args) {
+					return fmt.Errorf("%s 需要参数值 %s (以 - 开头的值请写作 %s=%s 或 %s -- %s)", name, opt.Arg, name, args[i+1], name, args[i+1])
+				}
+				return fmt.Errorf("%s 需要参数值 %s", name, opt.Arg)
+			}
+		}
+		if err := opt.Set(s, name, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unknownOptionError 未知参数的错误，名称相近时给出建议
+func unknownOptionError(name string, index map[string]*cliOption) error {
+	best, bestDist := "", 3
+	names := make([]string, 0, len(index))
+	for candidate := range index {
+		names = append(names, candidate)
+	}
+	sort.Strings(names)
+	for _, candidate := range names {
+		if d := editDistance(name, candidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	if best != "" {
+		return fmt.Errorf("未知参数: %s (是否想使用 %s?)", name, best)
+	}
+	return fmt.Errorf("未知参数: %s (dir2txt --help 查看全部参数；以 - 开头的目录请写在 -- 之后)", name)
+}
+
+// editDistance 两个字符串的编辑距离 (相邻字符交换计为一次编辑)
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// 常用的 optionSetter
+
+// setTrue 开关参数
+func setTrue(target *bool) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		*target = true
+		return nil
+	}
+}
+
+// setString 直接保存参数值
+func setString(target *string) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		*target = values[0]
+		return nil
+	}
+}
+
+// setChoice 参数值只能是 choices 之一
+func setChoice(target *string, choices ...string) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		for _, choice := range choices {
+			if values[0] == choice {
+				*target = values[0]
+				return nil
+			}
+		}
+		return fmt.Errorf("%s 只支持 %s: %s", name, strings.Join(choices, "、"), values[0])
+	}
+}
+
+// setURL 参数值必须是 http:// 或 https:// 开头的 URL
+func setURL(target *string) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		if u, err := url.Parse(values[0]); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s 需要 http:// 或 https:// 开头的 URL: %s", name, values[0])
+		}
+		*target = values[0]
+		return nil
+	}
+}
+
+// setHTTPSURL 参数值必须是 https:// 开头的 URL，用于下载可执行程序等不能被中间人篡改的内容
+func setHTTPSURL(target *string) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		if u, err := url.Parse(values[0]); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("%s 需要 https:// 开头的 URL: %s", name, values[0])
+		}
+		*target = values[0]
+		return nil
+	}
+}
+
+// setInt 参数值必须是 [lo, hi] 之间的整数，want 为出错时的说明
+func setInt(target *int, lo int, hi int, want string) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		n, err := strconv.Atoi(values[0])
+		if err != nil || n < lo || n > hi {
+			return fmt.Errorf("%s 需要%s: %s", name, want, values[0])
+		}
+		*target = n
+		return nil
+	}
+}
+
+// setPositiveInt64 参数值必须是正整数
+func setPositiveInt64(target *int64) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		n, err := strconv.ParseInt(values[0], 10, 64)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("%s 需要一个正整数: %s", name, values[0])
+		}
+		*target = n
+		return nil
+	}
+}
+
+// setSize 参数值为带单位的大小，如 10M
+func setSize(target *int64) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		n, err := parseSize(values[0])
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		*target = n
+		return nil
+	}
+}
+
+// setPercent 参数值为 0-100 的百分比，可带 % 后缀
+func setPercent(target *float64) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		p, err := strconv.ParseFloat(strings.TrimSuffix(values[0], "%"), 64)
+		if err != nil || p < 0 || p > 100 {
+			return fmt.Errorf("%s 需要一个 0-100 的百分比: %s", name, values[0])
+		}
+		*target = p
+		return nil
+	}
+}
+
+// appendValues 将参数值依次追加到列表
+func appendValues(target *[]string) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		*target = append(*target, values...)
+		return nil
+	}
+}
+
+// addKeys 将参数值加入集合
+func addKeys(target map[string]bool) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		for _, v := range values {
+			target[v] = true
+		}
+		return nil
+	}
+}
+
+// addDirs 追加目录参数，每个值作为一个完整路径 (含空格的目录名不拆分)
+func addDirs(s *cliState, name string, values []string) error {
+	for _, v := range values {
+		s.dirs.Set(v)
+	}
+	return nil
+}
+
+// addFields 将参数值按空白拆分后追加到解析状态中的列表 (multiValue)
+func addFields(target func(s *cliState) *multiValue) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		for _, v := range values {
+			target(s).Set(v)
+		}
+		return nil
+	}
+}
+
+// loadFilterFile 读取 -c/-fc/-Fc 指定的规则文件，追加为软过滤或硬过滤
+func loadFilterFile(hard bool) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		patterns, err := loadPatternsFromFile(values[0])
+		if err != nil {
+			return err
+		}
+		if hard {
+			s.hardFilters = append(s.hardFilters, patterns...)
+		} else {
+			s.softFilters = append(s.softFilters, patterns...)
+		}
+		return nil
+	}
+}
+
+// setRootFilters --filter-for/--Filter-for: 第一个值为根目录，其余为只作用于该根目录的规则
+func setRootFilters(target map[string][]string) optionSetter {
+	return func(s *cliState, name string, values []string) error {
+		if len(values) < 2 {
+			return fmt.Errorf("%s 需要一个目录和至少一个表达式", name)
+		}
+		root := values[0]
+		for _, v := range values[1:] {
+			target[root] = append(target[root], strings.Fields(v)...)
+		}
+		return nil
+	}
+}
+
+// choicesOf 集合中的全部取值 (排序后)，用于以已有的查找表作为 setChoice 的候选
+func choicesOf[V any](m map[string]V) []string {
+	choices := make([]string, 0, len(m))
+	for k := range m {
+		choices = append(choices, k)
+	}
+	sort.Strings(choices)
+	return choices
+}
+
+// 需要额外处理的参数
+
+func setGrep(s *cliState, name string, values []string) error {
+	re, err := regexp.Compile(values[0])
+	if err != nil {
+		return fmt.Errorf("%s 正则表达式无效: %v", name, err)
+	}
+	config.Grep = re
+	return nil
+}
+
+func setPermFilter(s *cliState, name string, values []string) error {
+	kinds, err := parsePermFilter(values[0])
+	if err != nil {
+		return err
+	}
+	config.PermFilter = append(config.PermFilter, kinds...)
+	return nil
+}
+
+func setFileLang(s *cliState, name string, values []string) error {
+	file, lang, found := strings.Cut(values[0], "=")
+	if !found || file == "" || lang == "" {
+		return fmt.Errorf("%s 需要 文件名=语言，如 Tiltfile=python: %s", name, values[0])
+	}
+	config.FileLangs[file] = lang
+	return nil
+}
+
+func setBinaryWindow(s *cliState, name string, values []string) error {
+	n, err := parseSize(values[0])
+	if err != nil || n <= 0 {
+		return fmt.Errorf("%s 需要一个正的大小: %s", name, values[0])
+	}
+	config.BinaryWindow = int(n)
+	return nil
+}
+
+func setFallbackEncoding(s *cliState, name string, values []string) error {
+	return setChoice(&config.FallbackEncoding, choicesOf(fallbackEncodings)...)(s, name, []string{strings.ToLower(values[0])})
+}
+
+func setFormat(s *cliState, name string, values []string) error {
+	format := values[0]
+	if _, ok := outputExts[format]; !ok {
+		return fmt.Errorf("不支持的输出格式: %s", format)
+	}
+	if s.formatLayer != s.layer {
+		// 后面的配置层 (如命令行参数) 中的 --format 替换前面各层指定的全部格式
+		config.Format, config.ExtraFormats, s.formatLayer = format, nil, s.layer
+		return nil
+	}
+	for _, f := range append([]string{config.Format}, config.ExtraFormats...) {
+		if f == format {
+			return fmt.Errorf("%s %s 重复指定", name, format)
+		}
+	}
+	config.ExtraFormats = append(config.ExtraFormats, format)
+	return nil
+}
+
+func setExplodeExt(s *cliState, name string, values []string) error {
+	return setChoice(&config.ExplodeExt, ".md", ".txt")(s, name, []string{normalizeExt(values[0])})
+}
+
+func setPromptOption(s *cliState, name string, values []string) error {
+	return setPrompt(name, values[0])
+}
+
+// setGoGraph --go-graph 不带值时输出文本边
+func setGoGraph(s *cliState, name string, values []string) error {
+	if len(values) == 0 {
+		config.GoGraph = "text"
+		return nil
+	}
+	return setChoice(&config.GoGraph, "text", "mermaid")(s, name, values)
+}
+
+// setPlan --plan 不带值时列出 20 条
+func setPlan(s *cliState, name string, values []string) error {
+	if len(values) == 0 {
+		config.Plan = 20
+		return nil
+	}
+	return setInt(&config.Plan, 1, math.MaxInt, "一个正整数")(s, name, values)
+}
+
+func setDefaultCacheDir(s *cliState, name string, values []string) error {
+	dir, err := defaultCacheDir()
+	if err != nil {
+		return err
+	}
+	config.CacheDir = dir
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestParseDirs --dir 与位置参数中的目录按原样保存，含空格的路径不被拆分
+func TestParseDirs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--dir", "My Project"}, []string{"My Project"}},
+		{[]string{"-d", "a b", "c"}, []string{"a b", "c"}},
+		{[]string{"--dir=My Project"}, []string{"My Project"}},
+		{[]string{"--dir", "--", "-weird dir"}, []string{"-weird dir"}},
+	}
+	for _, tt := range tests {
+		var s cliState
+		if err := s.parse(tt.args); err != nil {
+			t.Errorf("parse(%q): %v", tt.args, err)
+			continue
+		}
+		if got := []string(s.dirs); !slices.Equal(got, tt.want) {
+			t.Errorf("parse(%q) dirs = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
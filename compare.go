@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gitChange 记录两个版本之间单个文件的变化
+type gitChange struct {
+	Status string // git diff --name-status 的状态字母 (A/M/D/R...)
+	Path   string // 变化后的路径 (删除时为原路径)
+	Old    string // 重命名/复制前的路径
+}
+
+// parseCompareArgs 解析 compare 子命令参数
+func parseCompareArgs(args []string) (repo string, base string, head string, out string, help bool, err error) {
+	repo = "."
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--base" || arg == "-b":
+			if i+1 >= len(args) {
+				return repo, base, head, out, help, fmt.Errorf("--base 需要一个 git 引用")
+			}
+			i++
+			base = args[i]
+		case strings.HasPrefix(arg, "--base="):
+			base = strings.TrimPrefix(arg, "--base=")
+		case arg == "--head":
+			if i+1 >= len(args) {
+				return repo, base, head, out, help, fmt.Errorf("--head 需要一个 git 引用")
+			}
+			i++
+			head = args[i]
+		case strings.HasPrefix(arg, "--head="):
+			head = strings.TrimPrefix(arg, "--head=")
+		case arg == "--dir" || arg == "-d":
+			if i+1 >= len(args) {
+				return repo, base, head, out, help, fmt.Errorf("--dir 需要一个路径")
+			}
+			i++
+			repo = args[i]
+		case strings.HasPrefix(arg, "--dir="):
+			repo = strings.TrimPrefix(arg, "--dir=")
+		case arg == "--out" || arg == "-o":
+			if i+1 >= len(args) {
+				return repo, base, head, out, help, fmt.Errorf("--out 需要一个路径")
+			}
+			i++
+			out = args[i]
+		case strings.HasPrefix(arg, "--out="):
+			out = strings.TrimPrefix(arg, "--out=")
+		case arg == "--help" || arg == "-h":
+			help = true
+		default:
+			return repo, base, head, out, help, fmt.Errorf("未知参数: %s", arg)
+		}
+	}
+	if base == "" && !help {
+		return repo, base, head, out, help, fmt.Errorf("compare 需要 --base 参数")
+	}
+	return repo, base, head, out, help, nil
+}
+
+func compareUsage() {
+	fmt.Fprintf(os.Stderr, "用法: dir2txt compare --base <ref> [--head <ref>] [--dir <repo>] [--out <path>]\n")
+	fmt.Fprintf(os.Stderr, "  只输出对比引用相对基准引用的改动 (与 git diff base...head 相同，从两者的合并基点开始对比)：\n")
+	fmt.Fprintf(os.Stderr, "  修改的文件给出统一 diff，新增的文件给出完整内容\n")
+	fmt.Fprintf(os.Stderr, "  --base/-b     基准引用 (如 main)\n")
+	fmt.Fprintf(os.Stderr, "  --head        对比引用 (如 feature/x)；省略时与当前工作区对比，包括未跟踪 (且未被忽略) 的文件\n")
+	fmt.Fprintf(os.Stderr, "  --dir/-d      git 仓库目录 (默认当前目录)\n")
+	fmt.Fprintf(os.Stderr, "  --out/-o      指定输出文件路径或输出目录\n")
+}
+
+// runCompare 实现 dir2txt compare 子命令
+func runCompare(args []string) error {
+	repo, base, head, out, help, err := parseCompareArgs(args)
+	if help {
+		compareUsage()
+		return nil
+	}
+	if err != nil {
+		compareUsage()
+		return err
+	}
+
+	absRepo, err := filepath.Abs(repo)
+	if err != nil {
+		return err
+	}
+	topLevel, err := runGit(absRepo, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return fmt.Errorf("%s 不是 git 仓库: %v", repo, err)
+	}
+	absRepo = filepath.Clean(strings.TrimSpace(topLevel))
+
+	// 与 git diff base...head 相同，从合并基点开始对比，只包含 head 一侧的改动
+	from, err := gitMergeBase(absRepo, base, head)
+	if err != nil {
+		return err
+	}
+	changes, err := gitChangedFiles(absRepo, from, head)
+	if err != nil {
+		return err
+	}
+
+	finalOutPath, err := determineOutputPath([]string{absRepo}, out)
+	if err != nil {
+		return err
+	}
+	if out == "" || !strings.EqualFold(filepath.Ext(out), ".md") {
+		finalOutPath = filepath.Join(filepath.Dir(finalOutPath), filepath.Base(absRepo)+"_compare.md")
+	}
+	if err := os.MkdirAll(filepath.Dir(finalOutPath), 0o755); err != nil {
+		return fmt.Errorf("无法创建输出目录: %v", err)
+	}
+	outFile, err := os.Create(finalOutPath)
+	if err != nil {
+		return fmt.Errorf("无法创建输出文件: %v", err)
+	}
+	defer outFile.Close()
+
+	// 对比结果总是 Markdown
+	writer := newDocWriter(bufio.NewWriter(outFile), "md")
+	defer writer.Flush()
+
+	logf("结果将写入: %s\n", finalOutPath)
+
+	headLabel := head
+	if headLabel == "" {
+		headLabel = "working tree"
+	}
+	writer.WriteString(writer.docHeading(fmt.Sprintf("Comparison: %s...%s", base, headLabel)))
+	var list strings.Builder
+	for _, c := range changes {
+		if c.Old != "" {
+			list.WriteString(fmt.Sprintf("%s %s -> %s\n", c.Status, c.Old, c.Path))
+		} else {
+			list.WriteString(fmt.Sprintf("%s %s\n", c.Status, c.Path))
+		}
+	}
+	writer.writeCodeBlock("text", []byte(list.String()))
+	writer.writeSeparator()
+
+	writer.WriteString(writer.docHeading("File Changes"))
+	for _, c := range changes {
+		if err := writeChange(absRepo, from, head, c, writer); err != nil {
+			errorf("处理 %s 时出错: %v\n", c.Path, err)
+		}
+	}
+
+	logf("共 %d 个文件存在差异\n", len(changes))
+	logf("完成！\n")
+	return nil
+}
+
+// gitMergeBase 返回 base 与 head (为空时为 HEAD) 的合并基点
+func gitMergeBase(repo string, base string, head string) (string, error) {
+	if head == "" {
+		head = "HEAD"
+	}
+	output, err := runGit(repo, "merge-base", base, head)
+	if err != nil {
+		return "", fmt.Errorf("无法确定 %s 与 %s 的合并基点: %v", base, head, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// gitChangedFiles 列出 base 与 head 之间变化的文件；head 为空时与工作区对比，未跟踪的文件作为新增文件
+func gitChangedFiles(repo string, base string, head string) ([]gitChange, error) {
+	// -z 输出不加引号的原始路径，含非 ASCII 字符、制表符或引号的文件名也能直接读取
+	args := []string{"diff", "--name-status", "-z", "-M", base}
+	if head != "" {
+		args = append(args, head)
+	}
+	output, err := runGit(repo, args...)
+	if err != nil {
+		return nil, err
+	}
+	changes := parseNameStatus(output)
+	if head != "" {
+		return changes, nil
+	}
+
+	untracked, err := runGit(repo, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range strings.Split(untracked, "\x00") {
+		if path != "" {
+			changes = append(changes, gitChange{Status: "A", Path: path})
+		}
+	}
+	return changes, nil
+}
+
+// parseNameStatus 解析 git diff --name-status -z 的输出：每条记录依次为状态与路径，重命名/复制 (R/C) 有新旧两个路径
+func parseNameStatus(output string) []gitChange {
+	var changes []gitChange
+	fields := strings.Split(output, "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status := fields[i]
+		if status == "" {
+			break
+		}
+		c := gitChange{Status: status[:1], Path: fields[i+1]}
+		if (c.Status == "R" || c.Status == "C") && i+2 < len(fields) {
+			c.Old = c.Path
+			c.Path = fields[i+2]
+			i++
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// writeChange 写入单个变化文件：新增文件输出完整内容，其他输出统一 diff
+func writeChange(repo string, base string, head string, c gitChange, writer *docWriter) error {
+	switch c.Status {
+	case "A":
+		var content []byte
+		var err error
+		if head == "" {
+			content, err = os.ReadFile(filepath.Join(repo, filepath.FromSlash(c.Path)))
+		} else {
+			var s string
+			s, err = runGit(repo, "show", head+":"+c.Path)
+			content = []byte(s)
+		}
+		if err != nil {
+			return err
+		}
+		if isAsset(filepath.Base(c.Path)) || isBinary(content) {
+			logf("[SKIP] 检测到二进制文件: %s\n", c.Path)
+			return nil
+		}
+		utf8Content, _, err := convertToUTF8(content)
+		if err != nil {
+			logf("[WARN] 无法识别文件编码 (已跳过): %s\n", c.Path)
+			stats.Warnings++
+			return nil
+		}
+		logf("正在处理: %s\n", c.Path)
+		writer.WriteString(writer.subHeading(fmt.Sprintf("File: %s (added)", c.Path)))
+		writeFence(writer, codeLang(c.Path), utf8Content)
+	default:
+		args := []string{"diff", "--no-color", "-M", base}
+		if head != "" {
+			args = append(args, head)
+		}
+		args = append(args, "--")
+		if c.Old != "" {
+			args = append(args, c.Old)
+		}
+		args = append(args, c.Path)
+		diff, err := runGit(repo, args...)
+		if err != nil {
+			return err
+		}
+		logf("正在处理: %s\n", c.Path)
+		writer.WriteString(writer.subHeading(fmt.Sprintf("File: %s (%s)", c.Path, changeLabel(c.Status))))
+		writeFence(writer, "diff", []byte(diff))
+	}
+	return nil
+}
+
+func changeLabel(status string) string {
+	switch status {
+	case "M":
+		return "modified"
+	case "D":
+		return "deleted"
+	case "R":
+		return "renamed"
+	case "C":
+		return "copied"
+	case "T":
+		return "type changed"
+	}
+	return status
+}
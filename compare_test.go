@@ -0,0 +1,21 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestParseNameStatus -z 输出中的路径按原样解析，重命名与复制占两个字段
+func TestParseNameStatus(t *testing.T) {
+	output := "M\x00中文.go\x00R087\x00old name.go\x00new\tname.go\x00A\x00\"quoted\".txt\x00C100\x00a.go\x00b.go\x00D\x00gone.go\x00"
+	want := []gitChange{
+		{Status: "M", Path: "中文.go"},
+		{Status: "R", Path: "new\tname.go", Old: "old name.go"},
+		{Status: "A", Path: "\"quoted\".txt"},
+		{Status: "C", Path: "b.go", Old: "a.go"},
+		{Status: "D", Path: "gone.go"},
+	}
+	if got := parseNameStatus(output); !slices.Equal(got, want) {
+		t.Errorf("parseNameStatus = %+v, want %+v", got, want)
+	}
+}
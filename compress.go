@@ -0,0 +1,125 @@
+package main
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressExts 各压缩方式对应的附加后缀
+var compressExts = map[string]string{
+	"gzip": ".gz",
+	"zstd": ".zst",
+}
+
+// trimCompressExt 去掉路径末尾的压缩后缀，返回去掉后的路径与对应的压缩方式
+func trimCompressExt(path string) (string, string) {
+	for method, ext := range compressExts {
+		if strings.HasSuffix(strings.ToLower(path), ext) {
+			return path[:len(path)-len(ext)], method
+		}
+	}
+	return path, ""
+}
+
+// compressedPath 为输出路径追加压缩后缀 (已带后缀时不重复追加)
+func compressedPath(path string, method string) string {
+	ext := compressExts[method]
+	if strings.HasSuffix(strings.ToLower(path), ext) {
+		return path
+	}
+	return path + ext
+}
+
+// newCompressor 创建流式压缩写入器，关闭时写出压缩尾部 (不会关闭底层文件)
+func newCompressor(w io.Writer, method string) (io.WriteCloser, error) {
+	switch method {
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "zstd":
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("不支持的压缩方式: %s", method)
+}
+
+// archivePath 计算 --archive zip 的归档路径：拆分模式为 <目录>.zip，否则替换输出文件后缀
+func archivePath(outPath string) string {
+	if config.Explode != "" {
+		return config.Explode + ".zip"
+	}
+	base, _ := trimCompressExt(outPath)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + ".zip"
+}
+
+// writeZipArchive 将输出文件 (或 --explode 的整个输出目录) 打包为一个 zip 文件
+func writeZipArchive(src string, dest string) error {
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(f)
+
+	info, err := os.Stat(src)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	root := filepath.Dir(src)
+	if info.IsDir() {
+		// 归档内保留以输出目录名开头的目录结构
+		err = filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			return addZipFile(zw, root, path)
+		})
+	} else {
+		err = addZipFile(zw, root, src)
+	}
+	if err != nil {
+		zw.Close()
+		f.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// addZipFile 将单个文件以相对 root 的路径写入归档
+func addZipFile(zw *zip.Writer, root string, path string) error {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(rel)
+	header.Method = zip.Deflate
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(w, in)
+	return err
+}
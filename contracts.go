@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// contractKind 判断文件是否为接口契约文件 (protobuf、GraphQL schema、OpenAPI/Swagger)，返回其类型
+func contractKind(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".proto"):
+		return "protobuf"
+	case strings.HasSuffix(lower, ".graphql") || strings.HasSuffix(lower, ".graphqls") || strings.HasSuffix(lower, ".gql"):
+		return "graphql"
+	}
+	stem := strings.TrimSuffix(lower, filepath.Ext(lower))
+	switch filepath.Ext(lower) {
+	case ".yaml", ".yml", ".json":
+		if stem == "openapi" || stem == "swagger" || strings.HasSuffix(stem, ".openapi") || strings.HasSuffix(stem, ".swagger") {
+			return "openapi"
+		}
+	}
+	return ""
+}
+
+// contractSummary 单个契约文件的摘要
+type contractSummary struct {
+	Path  string // 显示路径
+	Kind  string
+	Lines []string // Markdown 列表项
+}
+
+// contractSummarizers 各类契约文件的摘要函数
+var contractSummarizers = map[string]func(name string, content []byte) []string{
+	"protobuf": summarizeProto,
+	"graphql":  summarizeGraphQL,
+	"openapi":  summarizeOpenAPI,
+}
+
+// collectContracts 在各个根目录中查找契约文件并生成摘要
+func collectContracts(ctx context.Context, dirs []string, hardFilters []string) []contractSummary {
+	var summaries []contractSummary
+	for _, dir := range dirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		rootName := filepath.Base(absDir)
+		rootHard := filtersForRoot(absDir, hardFilters, config.RootHard)
+		walkFollowSymlinks(ctx, absDir, func(logicalRel string, fullPath string, d os.DirEntry) error {
+			if isJunk(d.Name()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			relSlash := filepath.ToSlash(logicalRel)
+			if rootHard.Match(relSlash).Matched {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			kind := contractKind(d.Name())
+			if kind == "" || d.IsDir() {
+				return nil
+			}
+			content, err := os.ReadFile(fullPath)
+			if err != nil {
+				return nil
+			}
+			summaries = append(summaries, contractSummary{
+				Path:  rootName + "/" + relSlash,
+				Kind:  kind,
+				Lines: contractSummarizers[kind](d.Name(), content),
+			})
+			return nil
+		})
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Path < summaries[j].Path
+	})
+	return summaries
+}
+
+// writeContractsSummary 输出契约文件的服务/消息/接口摘要
+func writeContractsSummary(summaries []contractSummary, writer *docWriter) {
+	writer.WriteString(writer.docHeading("API Contracts"))
+	if len(summaries) == 0 {
+		writer.WriteString("No contract files (.proto, GraphQL schema, OpenAPI/Swagger) found.\n\n")
+		writer.writeSeparator()
+		return
+	}
+	for _, s := range summaries {
+		writer.WriteString(writer.subHeading(fmt.Sprintf("%s (%s)", s.Path, s.Kind)))
+		if len(s.Lines) == 0 {
+			writer.WriteString("No definitions recognized.\n\n")
+			continue
+		}
+		for _, line := range s.Lines {
+			writer.WriteString(line + "\n")
+		}
+		writer.WriteString("\n")
+	}
+	writer.writeSeparator()
+}
+
+var (
+	protoPackageRe = regexp.MustCompile(`^\s*package\s+([\w.]+)\s*;`)
+	protoBlockRe   = regexp.MustCompile(`^\s*(service|message|enum)\s+(\w+)`)
+	protoRPCRe     = regexp.MustCompile(`^\s*rpc\s+(\w+)\s*\(\s*(stream\s+)?([\w.]+)\s*\)\s*returns\s*\(\s*(stream\s+)?([\w.]+)\s*\)`)
+)
+
+// summarizeProto 列出 package、service 及其 rpc、顶层 message 与 enum
+func summarizeProto(name string, content []byte) []string {
+	var lines, messages, enums []string
+	depth := 0
+	for _, line := range strings.Split(string(content), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		if m := protoPackageRe.FindStringSubmatch(line); m != nil {
+			lines = append(lines, fmt.Sprintf("- package `%s`", m[1]))
+		}
+		if m := protoBlockRe.FindStringSubmatch(line); m != nil && depth == 0 {
+			switch m[1] {
+			case "service":
+				lines = append(lines, fmt.Sprintf("- service `%s`", m[2]))
+			case "message":
+				messages = append(messages, "`"+m[2]+"`")
+			case "enum":
+				enums = append(enums, "`"+m[2]+"`")
+			}
+		}
+		if m := protoRPCRe.FindStringSubmatch(line); m != nil {
+			lines = append(lines, fmt.Sprintf("  - `rpc %s(%s%s) returns (%s%s)`", m[1], m[2], m[3], m[4], m[5]))
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+	}
+	if len(messages) > 0 {
+		lines = append(lines, "- messages: "+strings.Join(messages, ", "))
+	}
+	if len(enums) > 0 {
+		lines = append(lines, "- enums: "+strings.Join(enums, ", "))
+	}
+	return lines
+}
+
+var graphQLTypeRe = regexp.MustCompile(`^\s*(?:extend\s+)?(type|input|interface|enum|union|scalar)\s+(\w+)`)
+
+// summarizeGraphQL 列出 Query/Mutation/Subscription 的字段，其余类型按种类列出名称
+func summarizeGraphQL(name string, content []byte) []string {
+	var lines []string
+	others := map[string][]string{}
+	var kinds []string
+	root := "" // 当前所在的根操作类型
+	for _, line := range strings.Split(string(content), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		trimmed := strings.TrimSpace(line)
+		if m := graphQLTypeRe.FindStringSubmatch(line); m != nil {
+			if m[1] == "type" && (m[2] == "Query" || m[2] == "Mutation" || m[2] == "Subscription") {
+				root = m[2]
+				lines = append(lines, fmt.Sprintf("- %s", m[2]))
+			} else {
+				if _, ok := others[m[1]]; !ok {
+					kinds = append(kinds, m[1])
+				}
+				others[m[1]] = append(others[m[1]], "`"+m[2]+"`")
+			}
+			continue
+		}
+		if root == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "}") {
+			root = ""
+			continue
+		}
+		if trimmed != "" && !strings.HasPrefix(trimmed, "\"") {
+			lines = append(lines, fmt.Sprintf("  - `%s`", trimmed))
+		}
+	}
+	for _, kind := range kinds {
+		lines = append(lines, fmt.Sprintf("- %s: %s", kind, strings.Join(others[kind], ", ")))
+	}
+	return lines
+}
+
+// openAPIMethods OpenAPI 中表示操作的 HTTP 方法键
+var openAPIMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true,
+}
+
+// summarizeOpenAPI 列出每个路径的操作 (方法、operationId 与 summary)
+func summarizeOpenAPI(name string, content []byte) []string {
+	if strings.HasSuffix(strings.ToLower(name), ".json") {
+		return summarizeOpenAPIJSON(content)
+	}
+	return summarizeOpenAPIYAML(content)
+}
+
+// summarizeOpenAPIJSON 解析 JSON 格式的 OpenAPI/Swagger 文档
+func summarizeOpenAPIJSON(content []byte) []string {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil
+	}
+	var paths []string
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var lines []string
+	for _, p := range paths {
+		var methods []string
+		for method := range doc.Paths[p] {
+			if openAPIMethods[method] {
+				methods = append(methods, method)
+			}
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			var op struct {
+				OperationID string `json:"operationId"`
+				Summary     string `json:"summary"`
+			}
+			json.Unmarshal(doc.Paths[p][method], &op)
+			lines = append(lines, openAPILine(method, p, op.OperationID, op.Summary))
+		}
+	}
+	return lines
+}
+
+// summarizeOpenAPIYAML 按缩进解析 YAML 格式 OpenAPI 文档的 paths 部分 (不依赖 YAML 库)
+func summarizeOpenAPIYAML(content []byte) []string {
+	var lines []string
+	inPaths := false
+	pathIndent, methodIndent := -1, -1
+	current, method, opID, summary := "", "", "", ""
+	flush := func() {
+		if method != "" {
+			lines = append(lines, openAPILine(method, current, opID, summary))
+		}
+		method, opID, summary = "", "", ""
+	}
+	for _, raw := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		key, value, _ := strings.Cut(trimmed, ":")
+		key = strings.Trim(key, `"'`)
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if indent == 0 {
+			flush()
+			inPaths = key == "paths"
+			continue
+		}
+		if !inPaths {
+			continue
+		}
+		switch {
+		case pathIndent < 0 || indent == pathIndent:
+			flush()
+			pathIndent, methodIndent = indent, -1
+			current = key
+		case indent > pathIndent && (methodIndent < 0 || indent == methodIndent):
+			flush()
+			if openAPIMethods[key] {
+				methodIndent = indent
+				method = key
+			}
+		case method != "" && indent > methodIndent:
+			if key == "operationId" && opID == "" {
+				opID = value
+			} else if key == "summary" && summary == "" {
+				summary = value
+			}
+		}
+	}
+	flush()
+	return lines
+}
+
+// openAPILine 格式化单个操作
+func openAPILine(method, path, opID, summary string) string {
+	line := fmt.Sprintf("- `%s %s`", strings.ToUpper(method), path)
+	if opID != "" {
+		line += fmt.Sprintf(" (%s)", opID)
+	}
+	if summary != "" {
+		line += " — " + summary
+	}
+	return line
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+// ansiEscapeRe ANSI 转义序列：CSI (ESC [ ... 终止字节)、OSC (ESC ] ... BEL 或 ESC \) 以及两字节的 ESC 序列
+var ansiEscapeRe = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// controlHit 单个文件中处理的控制字符数量
+type controlHit struct {
+	DisplayPath string
+	ANSI        int // ANSI 转义序列
+	Other       int // 其他控制字符
+}
+
+// controlHits 本次运行中含有控制字符的文件，结束时汇总
+var controlHits []controlHit
+
+// isControlRune 判断是否为需要处理的控制字符；制表符、换行以及 CRLF 中的 \r 保留
+func isControlRune(r rune, next byte) bool {
+	switch {
+	case r == '\t' || r == '\n':
+		return false
+	case r == '\r':
+		return next != '\n'
+	case r < 0x20 || r == 0x7f:
+		return true
+	case r >= 0x80 && r <= 0x9f:
+		return true
+	}
+	return false
+}
+
+// escapeControl 以可见形式表示控制字符，如 \x1b、\u0085
+func escapeControl(s string) string {
+	out := ""
+	for _, r := range s {
+		switch {
+		case r < 0x80:
+			if r < 0x20 || r == 0x7f {
+				out += fmt.Sprintf(`\x%02x`, r)
+			} else {
+				out += string(r)
+			}
+		case r <= 0x9f:
+			out += fmt.Sprintf(`\u%04x`, r)
+		default:
+			out += string(r)
+		}
+	}
+	return out
+}
+
+// sanitizeControl 按 --control-chars 去除或转义 ANSI 转义序列和其他控制字符，记录处理数量
+func sanitizeControl(displayPath string, content []byte) []byte {
+	if config.ControlChars == "keep" || !hasControl(content) {
+		return content
+	}
+	hit := controlHit{DisplayPath: displayPath}
+	content = ansiEscapeRe.ReplaceAllFunc(content, func(m []byte) []byte {
+		hit.ANSI++
+		if config.ControlChars == "escape" {
+			return []byte(escapeControl(string(m)))
+		}
+		return nil
+	})
+
+	out := make([]byte, 0, len(content))
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRune(content[i:])
+		var next byte
+		if i+size < len(content) {
+			next = content[i+size]
+		}
+		if isControlRune(r, next) {
+			hit.Other++
+			if config.ControlChars == "escape" {
+				out = append(out, escapeControl(string(r))...)
+			}
+		} else {
+			out = append(out, content[i:i+size]...)
+		}
+		i += size
+	}
+	if hit.ANSI > 0 || hit.Other > 0 {
+		controlHits = append(controlHits, hit)
+	}
+	return out
+}
+
+// hasControl 快速检查内容中是否存在需要处理的控制字符
+func hasControl(content []byte) bool {
+	for i, b := range content {
+		if b >= 0x20 && b != 0x7f && b != 0xc2 {
+			continue
+		}
+		var next byte
+		if i+1 < len(content) {
+			next = content[i+1]
+		}
+		if b == 0xc2 {
+			if next >= 0x80 && next <= 0x9f {
+				return true
+			}
+			continue
+		}
+		if isControlRune(rune(b), next) {
+			return true
+		}
+	}
+	return false
+}
+
+// printControlSummary 汇总处理过控制字符的文件
+func printControlSummary() {
+	if len(controlHits) == 0 {
+		return
+	}
+	action := "已去除"
+	if config.ControlChars == "escape" {
+		action = "已转义"
+	}
+	logf("控制字符: %d 个文件中含有 ANSI 转义序列或控制字符 (%s，可用 --control-chars 调整):\n", len(controlHits), action)
+	for _, h := range controlHits {
+		logf("  %s  ANSI %d, 其他 %d\n", h.DisplayPath, h.ANSI, h.Other)
+	}
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// daemonPollInterval --watch 检查文件变化的默认间隔
+const daemonPollInterval = 2 * time.Second
+
+// daemonLogLimit 启动时日志超过该大小则清空重写
+const daemonLogLimit = 4 * 1024 * 1024
+
+// daemonOptions daemon 子命令自身的参数，其余参数原样传给每次生成
+type daemonOptions struct {
+	Every   time.Duration
+	Watch   bool
+	Poll    time.Duration
+	Profile string // 读取参数的 profile
+	Name    string // 区分多个后台进程的 PID、状态与日志文件，默认与 profile 相同
+	Args    []string
+}
+
+// daemonState 后台进程写入的状态文件，供 daemon status 读取
+type daemonState struct {
+	PID          int       `json:"pid"`
+	Started      time.Time `json:"started"`
+	Dir          string    `json:"dir"`
+	Args         []string  `json:"args"`
+	Every        string    `json:"every,omitempty"`
+	Watch        bool      `json:"watch"`
+	Runs         int       `json:"runs"`
+	LastRun      time.Time `json:"last_run,omitempty"`
+	LastExit     int       `json:"last_exit"`
+	LastDuration string    `json:"last_duration,omitempty"`
+	NextRun      time.Time `json:"next_run,omitempty"`
+}
+
+const daemonHelp = `用法: dir2txt daemon [start] [--every 15m] [--watch] [--poll 2s] [--profile NAME] [参数...]
+       dir2txt daemon run  ...    在前台运行 (供 systemd、launchd 等管理)
+       dir2txt daemon stop   [--profile NAME]
+       dir2txt daemon status [--profile NAME]
+  在后台按计划重新生成上下文文件，其余参数 (目录、-o、过滤规则等) 原样传给每次生成
+  --every D      每隔 D 重新生成一次，如 15m、1h
+  --watch        目录中的文件变化时重新生成 (按 --poll 间隔轮询，变化停止一个间隔后才运行)
+  --profile NAME 读取 ~/.config/dir2txt/profiles/NAME 中的参数 (格式与配置文件相同，追加在命令行参数之后)，并以 NAME 区分多个后台进程；
+                 注意与生成时的 --profile FILE (CPU profile) 不同
+  PID、状态与日志文件位于用户缓存目录 (如 ~/.cache/dir2txt/daemon-NAME.pid)`
+
+// runDaemon 实现 dir2txt daemon 子命令
+func runDaemon(args []string) error {
+	action := "start"
+	if len(args) > 0 {
+		switch args[0] {
+		case "start", "run", "stop", "status":
+			action, args = args[0], args[1:]
+		}
+	}
+	opts, help, err := parseDaemonArgs(args)
+	if help {
+		fmt.Println(daemonHelp)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "stop":
+		return stopDaemon(opts.Name)
+	case "status":
+		return printDaemonStatus(opts.Name)
+	}
+
+	if opts.Every <= 0 && !opts.Watch {
+		return fmt.Errorf("daemon 需要 --every 或 --watch")
+	}
+	if opts.Profile != "" {
+		profileArgs, err := loadConfigLayer(daemonProfilePath(opts.Profile))
+		if err != nil {
+			return fmt.Errorf("无法读取 profile %s: %v", opts.Profile, err)
+		}
+		// 追加在命令行参数之后：生成时 profile 与命令行参数拼接为同一组参数，profile 中的多值参数 (如 -f a b) 放在前面会吞掉命令行上的目录参数
+		opts.Args = append(opts.Args, profileArgs...)
+	}
+	if action == "run" {
+		return daemonLoop(opts)
+	}
+	return startDaemon(opts)
+}
+
+// parseDaemonArgs 解析 daemon 自身的参数；-- 之后以及不认识的参数都传给每次生成
+func parseDaemonArgs(args []string) (daemonOptions, bool, error) {
+	opts := daemonOptions{Poll: daemonPollInterval}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch name {
+		case "--help", "-h":
+			return opts, true, nil
+		case "--watch":
+			opts.Watch = true
+		case "--every", "--poll", "--profile", "--daemon-name":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return opts, false, fmt.Errorf("%s 需要参数值", name)
+				}
+				i++
+				value = args[i]
+			}
+			if name == "--profile" || name == "--daemon-name" {
+				if value == "" || strings.ContainsAny(value, `/\`) {
+					return opts, false, fmt.Errorf("%s 需要一个名称: %s", name, value)
+				}
+				if name == "--profile" {
+					opts.Profile = value
+				}
+				opts.Name = value
+				continue
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return opts, false, fmt.Errorf("%s 需要一个时长，如 15m: %s", name, value)
+			}
+			if name == "--every" {
+				opts.Every = d
+			} else {
+				opts.Poll = d
+			}
+		case "--":
+			opts.Args = append(opts.Args, args[i+1:]...)
+			return opts, false, nil
+		default:
+			opts.Args = append(opts.Args, arg)
+		}
+	}
+	return opts, false, nil
+}
+
+// daemonProfilePath 命名 profile 的参数文件
+func daemonProfilePath(name string) string {
+	return filepath.Join(filepath.Dir(globalConfigPath()), "profiles", name)
+}
+
+// daemonFile 后台进程的 PID、状态或日志文件路径，不同 profile 互不影响
+func daemonFile(profile string, ext string) (string, error) {
+	dir, err := defaultCacheDir()
+	if err != nil {
+		return "", err
+	}
+	if profile == "" {
+		profile = "default"
+	}
+	return filepath.Join(dir, "daemon-"+profile+ext), nil
+}
+
+// runningDaemon 读取 PID 文件，返回仍在运行的后台进程号 (0 表示没有运行)
+func runningDaemon(profile string) int {
+	pidPath, err := daemonFile(profile, ".pid")
+	if err != nil {
+		return 0
+	}
+	data, err := os.ReadFile(pidPath)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || !processAlive(pid) {
+		return 0
+	}
+	return pid
+}
+
+// startDaemon 先在前台检查参数，再以 daemon run 启动脱离终端的后台进程
+func startDaemon(opts daemonOptions) error {
+	if pid := runningDaemon(opts.Name); pid != 0 {
+		return fmt.Errorf("后台进程已在运行 (PID %d)，可用 dir2txt daemon stop 停止", pid)
+	}
+	if _, err := daemonWatchDirs(opts.Args); err != nil {
+		return err
+	}
+	logPath, err := daemonFile(opts.Name, ".log")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		return err
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if info, err := os.Stat(logPath); err == nil && info.Size() > daemonLogLimit {
+		flags |= os.O_TRUNC
+	}
+	logFile, err := os.OpenFile(logPath, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	runArgs := []string{"daemon", "run", "--poll", opts.Poll.String()}
+	if opts.Every > 0 {
+		runArgs = append(runArgs, "--every", opts.Every.String())
+	}
+	if opts.Watch {
+		runArgs = append(runArgs, "--watch")
+	}
+	if opts.Name != "" {
+		// profile 中的参数已经展开，后台进程只需要用名称区分 PID 与状态文件
+		runArgs = append(runArgs, "--daemon-name", opts.Name)
+	}
+	runArgs = append(append(runArgs, "--"), opts.Args...)
+	cmd := exec.Command(exe, runArgs...)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	cmd.SysProcAttr = detachedProcAttr()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("无法启动后台进程: %v", err)
+	}
+	fmt.Printf("后台进程已启动 (PID %d)，日志: %s\n", cmd.Process.Pid, logPath)
+	return cmd.Process.Release()
+}
+
+// daemonLoop 前台循环：按计划或文件变化依次运行生成，收到中断信号时退出并删除 PID 文件
+func daemonLoop(opts daemonOptions) error {
+	if pid := runningDaemon(opts.Name); pid != 0 && pid != os.Getpid() {
+		return fmt.Errorf("后台进程已在运行 (PID %d)", pid)
+	}
+	dirs, err := daemonWatchDirs(opts.Args)
+	if err != nil {
+		return err
+	}
+	pidPath, err := daemonFile(opts.Name, ".pid")
+	if err != nil {
+		return err
+	}
+	statePath, _ := daemonFile(opts.Name, ".json")
+	if err := os.MkdirAll(filepath.Dir(pidPath), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+		return err
+	}
+	defer os.Remove(pidPath)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	wd, _ := os.Getwd()
+	state := daemonState{PID: os.Getpid(), Started: time.Now(), Dir: wd, Args: opts.Args, Watch: opts.Watch}
+	if opts.Every > 0 {
+		state.Every = opts.Every.String()
+	}
+
+	var fingerprint string
+	var nextRun time.Time
+	for {
+		start := time.Now()
+		fmt.Printf("[%s] 开始生成: dir2txt %s\n", start.Format(time.DateTime), strings.Join(opts.Args, " "))
+		cmd := exec.CommandContext(ctx, exe, opts.Args...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		err := cmd.Run()
+		if ctx.Err() != nil {
+			fmt.Printf("[%s] 已停止\n", time.Now().Format(time.DateTime))
+			return nil
+		}
+		code := 0
+		if err != nil {
+			code = exitError
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			}
+		}
+		fmt.Printf("[%s] 生成结束 (退出码 %d，用时 %s)\n", time.Now().Format(time.DateTime), code, time.Since(start).Round(time.Millisecond))
+
+		// 生成结束后重新取指纹，本次运行写入的输出、缓存与快照不会再次触发生成
+		if opts.Watch {
+			fingerprint = treeFingerprint(ctx, dirs)
+		}
+		if opts.Every > 0 {
+			nextRun = time.Now().Add(opts.Every)
+		}
+		state.Runs++
+		state.LastRun, state.LastExit, state.LastDuration, state.NextRun = start, code, time.Since(start).Round(time.Millisecond).String(), nextRun
+		if data, err := json.MarshalIndent(state, "", "  "); err == nil {
+			os.WriteFile(statePath, data, 0o644)
+		}
+
+		if !waitForNextRun(ctx, opts, dirs, fingerprint, nextRun) {
+			fmt.Printf("[%s] 已停止\n", time.Now().Format(time.DateTime))
+			return nil
+		}
+	}
+}
+
+// waitForNextRun 等待到计划时间或文件变化 (变化停止一个轮询间隔后)，收到中断信号时返回 false
+func waitForNextRun(ctx context.Context, opts daemonOptions, dirs []string, fingerprint string, nextRun time.Time) bool {
+	var timer <-chan time.Time
+	if !nextRun.IsZero() {
+		timer = time.After(time.Until(nextRun))
+	}
+	var poll <-chan time.Time
+	if opts.Watch {
+		ticker := time.NewTicker(opts.Poll)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	changed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer:
+			return true
+		case <-poll:
+			current := treeFingerprint(ctx, dirs)
+			if current == fingerprint {
+				if changed {
+					fmt.Printf("[%s] 检测到文件变化\n", time.Now().Format(time.DateTime))
+					return true
+				}
+				continue
+			}
+			fingerprint, changed = current, true
+		}
+	}
+}
+
+// daemonWatchDirs 按与生成相同的方式解析参数 (含配置文件)，返回要监视的目录与直接给出的文件；参数错误在启动前报告
+func daemonWatchDirs(args []string) ([]string, error) {
+	layers, err := configLayers(args)
+	if err != nil {
+		return nil, err
+	}
+	dirs, _, _, _, _, install, uninstall, err := parseCommandLine(layerArgs(layers)...)
+	if err != nil {
+		return nil, err
+	}
+	if install || uninstall {
+		return nil, fmt.Errorf("daemon 不能与 --install/--uninstall 一起使用")
+	}
+	if len(dirs) == 0 {
+		dirs = append(dirs, ".")
+	}
+	return expandDirGlobs(dirs)
+}
+
+// treeFingerprint 目录中全部文件的路径、大小与修改时间的摘要 (跳过 .git、node_modules 等默认忽略的目录)
+func treeFingerprint(ctx context.Context, dirs []string) string {
+	var sb strings.Builder
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return filepath.SkipAll
+			}
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != dir && isJunk(d.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				fmt.Fprintf(&sb, "%s\x00%d\x00%d\n", path, info.Size(), info.ModTime().UnixNano())
+			}
+			return nil
+		})
+	}
+	return sb.String()
+}
+
+// stopDaemon 通知后台进程退出
+func stopDaemon(profile string) error {
+	pid := runningDaemon(profile)
+	if pid == 0 {
+		return fmt.Errorf("没有正在运行的后台进程")
+	}
+	if err := terminateProcess(pid); err != nil {
+		return fmt.Errorf("无法停止后台进程 (PID %d): %v", pid, err)
+	}
+	for i := 0; i < 50 && processAlive(pid); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	// Windows 上进程被直接结束，不会自行删除 PID 文件
+	if pidPath, err := daemonFile(profile, ".pid"); err == nil {
+		os.Remove(pidPath)
+	}
+	fmt.Printf("已停止后台进程 (PID %d)\n", pid)
+	return nil
+}
+
+// printDaemonStatus 打印后台进程是否在运行以及最近一次生成的结果
+func printDaemonStatus(profile string) error {
+	pid := runningDaemon(profile)
+	if pid == 0 {
+		fmt.Println("状态: 未运行")
+	} else {
+		fmt.Printf("状态: 运行中 (PID %d)\n", pid)
+	}
+	statePath, err := daemonFile(profile, ".json")
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil
+	}
+	var state daemonState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil
+	}
+	fmt.Printf("目录: %s\n", state.Dir)
+	fmt.Printf("参数: %s\n", strings.Join(state.Args, " "))
+	if state.Every != "" {
+		fmt.Printf("间隔: %s\n", state.Every)
+	}
+	if state.Watch {
+		fmt.Println("监视文件变化: 是")
+	}
+	fmt.Printf("已生成 %d 次，最近一次: %s (退出码 %d，用时 %s)\n", state.Runs, state.LastRun.Format(time.DateTime), state.LastExit, state.LastDuration)
+	if pid != 0 && !state.NextRun.IsZero() {
+		fmt.Printf("下次生成: %s\n", state.NextRun.Format(time.DateTime))
+	}
+	if logPath, err := daemonFile(profile, ".log"); err == nil {
+		fmt.Printf("日志: %s\n", logPath)
+	}
+	return nil
+}
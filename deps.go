@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// dependency 表示清单文件中声明的一个第三方依赖
+type dependency struct {
+	Manifest  string // 清单文件的显示路径
+	Ecosystem string
+	Name      string
+	Version   string
+	Scope     string // runtime/dev/build/indirect 等
+}
+
+// manifestParsers 已知清单文件名到解析函数的映射
+var manifestParsers = map[string]struct {
+	ecosystem string
+	parse     func(content []byte) []dependency
+}{
+	"go.mod":           {"go", parseGoMod},
+	"package.json":     {"npm", parsePackageJSON},
+	"requirements.txt": {"pypi", parseRequirements},
+	"Cargo.toml":       {"cargo", parseCargoToml},
+	"pom.xml":          {"maven", parsePomXML},
+}
+
+// collectDependencies 在各个根目录中查找已知清单文件并解析依赖
+func collectDependencies(ctx context.Context, dirs []string, hardFilters []string) []dependency {
+	var deps []dependency
+	for _, dir := range dirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		rootName := filepath.Base(absDir)
+		rootHard := filtersForRoot(absDir, hardFilters, config.RootHard)
+		walkFollowSymlinks(ctx, absDir, func(logicalRel string, fullPath string, d os.DirEntry) error {
+			if isJunk(d.Name()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			relSlash := filepath.ToSlash(logicalRel)
+			if rootHard.Match(relSlash).Matched {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			parser, ok := manifestParsers[d.Name()]
+			if !ok || d.IsDir() {
+				return nil
+			}
+			content, err := os.ReadFile(fullPath)
+			if err != nil {
+				return nil
+			}
+			for _, dep := range parser.parse(content) {
+				dep.Manifest = rootName + "/" + relSlash
+				dep.Ecosystem = parser.ecosystem
+				deps = append(deps, dep)
+			}
+			return nil
+		})
+	}
+	sort.SliceStable(deps, func(i, j int) bool {
+		if deps[i].Manifest != deps[j].Manifest {
+			return deps[i].Manifest < deps[j].Manifest
+		}
+		return deps[i].Name < deps[j].Name
+	})
+	return deps
+}
+
+// writeDepsSummary 输出依赖汇总表
+func writeDepsSummary(deps []dependency, writer *docWriter) {
+	writer.WriteString(writer.docHeading("Dependencies"))
+	if len(deps) == 0 {
+		writer.WriteString("No dependency manifests found.\n\n")
+		writer.writeSeparator()
+		return
+	}
+	var rows [][]string
+	for _, dep := range deps {
+		version := dep.Version
+		if version == "" {
+			version = "*"
+		}
+		rows = append(rows, []string{dep.Manifest, dep.Ecosystem, dep.Name, version, dep.Scope})
+	}
+	writer.writeTable([]string{"Manifest", "Ecosystem", "Package", "Version", "Scope"}, rows)
+	writer.writeSeparator()
+}
+
+// parseGoMod 解析 go.mod 中的 require 语句 (单行与块形式)
+func parseGoMod(content []byte) []dependency {
+	var deps []dependency
+	inBlock := false
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		indirect := strings.Contains(line, "// indirect")
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		switch {
+		case line == "require (":
+			inBlock = true
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case !inBlock:
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		scope := "runtime"
+		if indirect {
+			scope = "indirect"
+		}
+		deps = append(deps, dependency{Name: fields[0], Version: fields[1], Scope: scope})
+	}
+	return deps
+}
+
+// parsePackageJSON 解析 package.json 中的各类依赖字段
+func parsePackageJSON(content []byte) []dependency {
+	var pkg map[string]json.RawMessage
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil
+	}
+	fields := []struct{ key, scope string }{
+		{"dependencies", "runtime"},
+		{"devDependencies", "dev"},
+		{"peerDependencies", "peer"},
+		{"optionalDependencies", "optional"},
+	}
+	var deps []dependency
+	for _, f := range fields {
+		raw, ok := pkg[f.key]
+		if !ok {
+			continue
+		}
+		var m map[string]string
+		if err := json.Unmarshal(raw, &m); err != nil {
+			continue
+		}
+		for name, version := range m {
+			deps = append(deps, dependency{Name: name, Version: version, Scope: f.scope})
+		}
+	}
+	return deps
+}
+
+// parseRequirements 解析 requirements.txt，忽略注释与 -r/-e 等选项行
+func parseRequirements(content []byte) []dependency {
+	var deps []dependency
+	for _, line := range strings.Split(string(content), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "-") {
+			continue
+		}
+		if i := strings.Index(line, ";"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		name, version := line, ""
+		if i := strings.IndexAny(line, "=<>!~"); i >= 0 {
+			name, version = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i:])
+		}
+		if i := strings.Index(name, "["); i >= 0 {
+			name = name[:i]
+		}
+		deps = append(deps, dependency{Name: name, Version: version, Scope: "runtime"})
+	}
+	return deps
+}
+
+// parseCargoToml 解析 Cargo.toml 中 [dependencies] 等表，支持内联表与 [dependencies.foo] 形式
+func parseCargoToml(content []byte) []dependency {
+	scopes := map[string]string{
+		"dependencies":           "runtime",
+		"dev-dependencies":       "dev",
+		"build-dependencies":     "build",
+		"workspace.dependencies": "workspace",
+	}
+	var deps []dependency
+	section := ""
+	tableDep := -1 // 当前 [dependencies.foo] 形式对应的依赖下标
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[] ")
+			tableDep = -1
+			for prefix, scope := range scopes {
+				if strings.HasPrefix(section, prefix+".") {
+					deps = append(deps, dependency{Name: strings.TrimPrefix(section, prefix+"."), Scope: scope})
+					tableDep = len(deps) - 1
+				}
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.Trim(strings.TrimSpace(key), "\"")
+		value = strings.TrimSpace(value)
+		if tableDep >= 0 {
+			if key == "version" {
+				deps[tableDep].Version = strings.Trim(value, "\"'")
+			}
+			continue
+		}
+		scope, ok := scopes[section]
+		if !ok {
+			continue
+		}
+		version := strings.Trim(value, "\"'")
+		if strings.HasPrefix(value, "{") {
+			version = ""
+			for _, part := range strings.Split(strings.Trim(value, "{}"), ",") {
+				k, v, ok := strings.Cut(part, "=")
+				if ok && strings.TrimSpace(k) == "version" {
+					version = strings.Trim(strings.TrimSpace(v), "\"'")
+				}
+			}
+		}
+		deps = append(deps, dependency{Name: key, Version: version, Scope: scope})
+	}
+	return deps
+}
+
+// parsePomXML 解析 pom.xml 中 project/dependencies 下的依赖
+func parsePomXML(content []byte) []dependency {
+	var pom struct {
+		Dependencies []struct {
+			GroupID    string `xml:"groupId"`
+			ArtifactID string `xml:"artifactId"`
+			Version    string `xml:"version"`
+			Scope      string `xml:"scope"`
+		} `xml:"dependencies>dependency"`
+	}
+	if err := xml.Unmarshal(content, &pom); err != nil {
+		return nil
+	}
+	var deps []dependency
+	for _, d := range pom.Dependencies {
+		scope := d.Scope
+		if scope == "" {
+			scope = "compile"
+		}
+		deps = append(deps, dependency{Name: d.GroupID + ":" + d.ArtifactID, Version: d.Version, Scope: scope})
+	}
+	return deps
+}
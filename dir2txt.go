@@ -3,19 +3,24 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
 	"golang.org/x/text/transform"
 )
 
@@ -28,77 +33,182 @@ const (
 
 // Config 配置需要忽略的目录和文件后缀
 type Config struct {
-	OutputFile   string
-	IgnoredDirs  map[string]bool
-	IgnoredExts  map[string]bool
-	IgnoredFiles map[string]bool // 指定要完全隐藏的文件 (既不在树中显示，也不读取内容)
-	MaxFileSize  int64           // 忽略过大的文件
-	TextExts     map[string]bool // 强制视为文本的文件后缀
-	NoFold       bool            // 是否关闭目录树文件折叠
+	OutputFile          string
+	IgnoredDirs         map[string]bool
+	IgnoredExts         map[string]bool
+	IgnoredFiles        map[string]bool     // 指定要完全隐藏的文件 (既不在树中显示，也不读取内容)，支持通配符
+	AssetFiles          map[string]bool     // 按文件名跳过内容的文件 (仍在树中显示)，支持通配符，如锁文件
+	KeepFiles           map[string]bool     // --keep 指定的文件名，豁免上述所有名称/后缀规则
+	HiddenPolicy        string              // 隐藏文件 (以 . 开头) 策略: include/tree-only/exclude
+	HiddenAllow         map[string]bool     // 无论隐藏策略如何都会完整输出的隐藏文件/目录，支持通配符
+	RootSoft            map[string][]string // --filter-for 指定的根目录 -> 仅作用于该根目录的软过滤
+	RootHard            map[string][]string // --Filter-for 指定的根目录 -> 仅作用于该根目录的硬过滤
+	MaxFileSize         int64               // 忽略过大的文件
+	TextExts            map[string]bool     // 强制视为文本的文件后缀
+	NoFold              bool                // 是否关闭目录树文件折叠
+	History             int                 // 每个文件附带的最近提交条数 (0 表示不输出)
+	ApplyDiff           string              // 补丁文件路径，非空时只输出补丁及其涉及的文件
+	Grep                *regexp.Regexp      // 只包含内容命中该正则的文件
+	GrepContext         int                 // 只输出命中行及其前后 N 行 (-1 表示输出整个文件)
+	GoPackages          []string            // 只输出这些 Go 包的源文件
+	WithDeps            bool                // 同时输出 GoPackages 在主模块内的依赖包
+	IncludeOnly         map[string]bool     // 非空时只输出这些文件 (绝对路径) 的内容
+	GoGraph             string              // Go 包依赖图格式 (text/mermaid)，空表示不输出
+	DepsSummary         bool                // 是否输出依赖清单汇总表
+	Strict              bool                // 将单个文件的警告 (编码无法识别、读取失败等) 视为失败
+	FailOverTokens      int64               // 输出估算 token 数超过该值时以非零退出码结束 (0 表示不限制)
+	FailOverSize        int64               // 输出字节数超过该值时以非零退出码结束 (0 表示不限制)
+	BudgetTokens        int64               // 文档 token 预算，超出时按 TrimStrategy 丢弃或截断文件 (0 表示不限制)
+	TrimStrategy        string              // 预算裁剪策略: size/importance/oldest
+	Rank                bool                // 按重要性排列文件内容
+	ShowRank            bool                // 在控制台打印重要性排名
+	Format              string              // 输出格式: md/rag-jsonl
+	ExtraFormats        []string            // --format 多次指定时，主格式之外的其余格式 (同一次遍历写出)
+	Explode             string              // 非空时每个文件单独写入该目录，主输出为索引文件
+	ExplodeExt          string              // 拆分文件的后缀 (.md/.txt)
+	Compress            string              // 非空时以 gzip/zstd 流式压缩输出文件
+	Archive             string              // 非空时 (zip) 在完成后将输出打包为归档
+	Resume              bool                // 从上次中断的位置继续写入
+	Snapshot            string              // 快照库路径，非空时记录本次运行的文件
+	SinceLast           bool                // 只输出自上次快照以来变化的文件
+	OneFileSystem       bool                // 遍历时不跨越挂载点 (类似 du -x)
+	SkipUnreadable      bool                // 无读取权限的文件/目录静默跳过，并在目录树中标注
+	PermFilter          []string            // 不输出内容的权限条件 (world-writable/setuid/setgid)
+	ShowPerms           bool                // 在文件标题下输出权限与属主
+	CacheDir            string              // 非空时启用跨运行缓存的目录
+	ProfileFile         string              // --profile 的 CPU profile 输出文件
+	TraceFile           string              // --trace 的执行 trace 输出文件
+	Bench               bool                // 结束时打印各阶段耗时
+	MaxMemory           int64               // 先读取全部文件时缓冲内容的上限 (字节)，超出时暂存到临时文件
+	Backup              bool                // 覆盖输出前将旧文件保留为 .bak
+	NoOverwrite         bool                // 输出文件已存在时拒绝覆盖
+	Versioned           bool                // 输出文件已存在时写入带序号的新文件
+	PreHook             string              // 生成前执行的命令
+	PostHook            string              // 生成后执行的命令
+	Plugins             []string            // --plugin 注册的外部处理器
+	ContractsSummary    bool                // 输出契约文件摘要
+	EnvValues           string              // .env 文件中值的处理方式: keep/mask/drop
+	PIIScan             bool                // 扫描内容中的邮箱、电话号码与身份证号
+	PIIMask             bool                // 在输出中遮盖扫描到的个人信息
+	Licenses            bool                // 输出许可证汇总
+	Todos               bool                // 汇总 TODO/FIXME 注释
+	TestMap             bool                // 输出源文件与测试文件对应表
+	ExtStats            bool                // 结束时按扩展名统计包含/跳过情况
+	ShowEffectiveConfig bool                // 打印合并后的配置后退出
+	CI                  bool                // 非交互的 CI/容器模式
+	UserInstall         bool                // --install/--uninstall 作用于用户目录
+	EmitFilterFile      string              // 运行结束后写出过滤规则建议的文件
+	TreeFormat          string              // 目录树格式: ascii/indent/paths/json
+	ASCIIGlyphs         bool                // 目录树只使用 ASCII 连接符
+	TreeOrder           string              // 目录树排列: dirs-first/mixed/files-first
+	TreeMarkFiltered    bool                // 目录树中标注而不是隐藏硬过滤的项
+	MaxFilesPerDir      int                 // 每个目录最多输出的文件数 (0 表示不限制)
+	MaxFiles            int                 // 最多输出内容的文件数 (0 表示不限制)
+	SummarizeExcluded   bool                // 为未输出的文件在附录中写一行摘要
+	AISummaries         bool                // 为每个文件生成 AI 摘要
+	AIEndpoint          string              // OpenAI 兼容接口地址
+	AIModel             string              // 生成摘要使用的模型
+	Preamble            string              // 写在目录树之前的说明 (--preamble / --prompt)
+	Postamble           string              // 写在文件内容之后的说明 (--postamble)
+	MaxOutputSize       int64               // 预估输出大小上限 (0 表示不限制)
+	Oversize            string              // 预估大小超限时的处理: abort 或 outline
+	Plan                int                 // --plan 报告列出的条数 (0 表示不打印报告)
+	WarnShare           float64             // 单个文件占内容 token 数的百分比超过该值时提示 (0 表示不提示)
+	KeepMinified        bool                // 输出压缩后的 JS/CSS 文件内容
+	HashedAssets        bool                // 带内容哈希的打包产物 (如 app.3f9c2a.js) 只在树中显示
+	Workspaces          []string            // --workspace 指定的工作区包名，按工作区清单解析为目录
+	NoDirMarkers        bool                // 忽略目录中的 .dir2txt-skip / .dir2txt-soft 标记文件
+	NoEditorconfig      bool                // 不读取 .editorconfig 的 charset 声明
+	FallbackEncoding    string              // 无法识别编码时尽力使用的单字节编码 (windows-1252 或 latin1)
+	ControlChars        string              // ANSI 转义序列与控制字符的处理: strip、escape 或 keep
+	BinaryWindow        int                 // 二进制检测读取的开头字节数
+	BinaryThreshold     float64             // 不可打印字节占比超过该百分比时视为二进制
+	ForceText           []string            // 始终按文本读取的路径模式
+	ForceBinary         []string            // 始终视为二进制的路径模式
+	UnknownExt          string              // 不在文本后缀白名单中的文件: include 或 skip
+	FileLangs           map[string]string   // 常见无后缀文件名到代码块语言的映射 (如 Dockerfile → dockerfile)
+	AllowEmpty          bool                // 没有任何文件内容被写入时仍然输出只有目录树的文档
+	Progress            string              // 状态行: 空为仅在终端中显示，always 重定向时也定期打印，never 关闭
+	IORetries           int                 // 暂时性 I/O 错误的重试次数
+	IOConcurrency       int                 // 同时打开的文件与目录数上限 (0 表示不限制)
+	TreeLinks           bool                // 目录树输出为 Markdown 列表，文件链接到内容章节
+	HeadingLevel        int                 // 文件章节的标题级别 (2-6)，文档级章节比它高一级
+	NumberedHeadings    bool                // 文件章节标题按写入顺序编号
+	Flavor              string              // Markdown 方言: github/obsidian
+	SARIF               string              // 非空时将 --pii-scan 与 --todos 的发现导出为 SARIF 文件
+	OTelEndpoint        string              // 非空时结束后以 OTLP/HTTP 向该地址推送指标与 trace
+	InstallStatic       bool                // --install 前确认程序为静态链接 (musl 容器)
+	ReleaseURL          string              // --install 在转译层下运行时下载原生版本的 URL 模板
 }
 
-// walkFollowSymlinks 遍历目录，跟随符号链接的目录，保持逻辑路径用于过滤
-func walkFollowSymlinks(root string, fn func(logicalRel string, fullPath string, d os.DirEntry) error) error {
-	type node struct {
-		fsPath string // 实际文件系统路径（可能为解析后的目标路径）
-		rel    string // 相对 root 的逻辑路径（使用符号链接名字串接）
-	}
-
-	stack := []node{{fsPath: root, rel: ""}}
-	seen := map[string]bool{}
-
-	for len(stack) > 0 {
-		n := stack[len(stack)-1]
-		stack = stack[:len(stack)-1]
-
-		entries, err := os.ReadDir(n.fsPath)
-		if err != nil {
-			return err
-		}
+// unreadableDirs 遍历中无法读取而被跳过的目录，同一目录只报告一次 (依赖汇总等会再次遍历)
+var unreadableDirs = map[string]bool{}
 
-		for _, entry := range entries {
-			name := entry.Name()
-			logicalRel := name
-			if n.rel != "" {
-				logicalRel = filepath.Join(n.rel, name)
-			}
-
-			childFSPath := filepath.Join(n.fsPath, name)
-			childIsDir := entry.IsDir()
+// reportUnreadableDir 打印无法读取的子目录并计入目录遍历失败数
+func reportUnreadableDir(path string, err error) {
+	if unreadableDirs[path] {
+		return
+	}
+	unreadableDirs[path] = true
+	if config.SkipUnreadable && isPermissionDenied(err) {
+		logf("[SKIP] 无读取权限的目录: %s\n", path)
+		return
+	}
+	errorf("[WARN] 无法读取目录，已跳过: %s (%v)\n", path, err)
+	stats.WalkErrors++
+}
 
-			// 跟随符号链接目录
-			if entry.Type()&os.ModeSymlink != 0 {
-				target, err := filepath.EvalSymlinks(childFSPath)
-				if err == nil {
-					if info, err := os.Stat(target); err == nil && info.IsDir() {
-						childIsDir = true
-						childFSPath = target
-					}
-				}
-			}
+// reportedLoops 已报告过的目录环/重复目录 (按逻辑路径)，同一位置只报告一次
+var reportedLoops = map[string]bool{}
 
-			// 先把当前条目交给回调
-			if err := fn(logicalRel, childFSPath, entry); err != nil {
-				if errors.Is(err, filepath.SkipDir) {
-					continue
-				}
-				return err
-			}
+// newSeenDirs 创建记录已展开目录的表 (目录标识 -> 首次出现的逻辑路径)，并登记根目录
+func newSeenDirs(root string) map[string]string {
+	seen := map[string]string{}
+	if id, ok := dirIdentity(root); ok {
+		seen[id] = root
+	}
+	return seen
+}
 
-			if childIsDir {
-				real, err := filepath.EvalSymlinks(childFSPath)
-				if err == nil {
-					if seen[real] {
-						continue
-					}
-					seen[real] = true
-				}
-				stack = append(stack, node{fsPath: childFSPath, rel: logicalRel})
-			}
+// loopMarker 返回重复访问目录时在目录树中显示的说明，首次遇到时打印日志
+// logical 为本次访问的逻辑路径，first 为该目录第一次出现的逻辑路径
+func loopMarker(logical string, first string) string {
+	rel, err := filepath.Rel(filepath.Dir(logical), first)
+	if err != nil {
+		rel = first
+	}
+	rel = filepath.ToSlash(rel)
+	isCycle := hasPathPrefix(logical, first)
+	if !reportedLoops[logical] {
+		reportedLoops[logical] = true
+		if isCycle {
+			logf("[CYCLE] 检测到目录环，停止展开: %s -> %s\n", logical, first)
+		} else {
+			logf("[SKIP] 目录已在其他位置展开 (符号链接或挂载点指向同一目录): %s -> %s\n", logical, first)
 		}
 	}
+	if isCycle {
+		return fmt.Sprintf("(cycle to %s)", rel)
+	}
+	return fmt.Sprintf("(same directory as %s)", rel)
+}
 
-	return nil
+// crossesFileSystem 在 --one-file-system 下判断目录是否位于与根目录不同的文件系统上 (挂载点)
+// 首次遇到时打印日志
+func crossesFileSystem(root string, dir string, logical string) bool {
+	if !config.OneFileSystem {
+		return false
+	}
+	rootDev, ok1 := dirDevice(root)
+	dev, ok2 := dirDevice(dir)
+	if !ok1 || !ok2 || rootDev == dev {
+		return false
+	}
+	if !reportedLoops[logical] {
+		reportedLoops[logical] = true
+		logf("[SKIP] 挂载点位于其他文件系统 (--one-file-system): %s\n", logical)
+	}
+	return true
 }
 
 // multiValue 允许通过空格或多次传参传入多个值，例如：
@@ -134,144 +244,114 @@ func (m *rawStringList) Set(s string) error {
 	return nil
 }
 
-// SimpleDirEntry 用于在目录树中创建伪造节点（如省略号）
-type SimpleDirEntry struct {
-	name  string
-	isDir bool
-}
+// parseCommandLine 依次解析各组参数 (配置层按层传入)，再检查参数之间的组合
+func parseCommandLine(argSets ...[]string) (rawStringList, multiValue, multiValue, string, bool, bool, bool, error) {
+	var state cliState
+	var err error
+	for i, args := range argSets {
+		state.layer = i + 1
+		if err = state.parse(args); err != nil {
+			break
+		}
+	}
+	dirs, softFilters, hardFilters, out, help, install, uninstall := state.dirs, state.softFilters, state.hardFilters, state.out, state.help, state.install, state.uninstall
+	if err != nil {
+		return dirs, softFilters, hardFilters, out, help, install, uninstall, err
+	}
 
-func (e *SimpleDirEntry) Name() string               { return e.name }
-func (e *SimpleDirEntry) IsDir() bool                { return e.isDir }
-func (e *SimpleDirEntry) Type() os.FileMode          { return 0 }
-func (e *SimpleDirEntry) Info() (os.FileInfo, error) { return nil, nil }
-
-func parseCommandLine() (rawStringList, multiValue, multiValue, string, bool, bool, bool, error) {
-	var dirs rawStringList
-	var softFilters multiValue // -f / --filter / -filter : 只过滤内容，不排除树
-	var hardFilters multiValue // -F / --Filter : 完全过滤，树和内容都不出现
-	var out string
-	var help bool
-	var install bool
-	var uninstall bool
-	args := os.Args[1:]
-	var leftover []string
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
-		switch {
-		case arg == "--" && i+1 < len(args):
-			leftover = append(leftover, args[i+1:]...)
-			i = len(args)
-		case arg == "--install":
-			install = true
-		case arg == "--uninstall":
-			uninstall = true
-		case arg == "--no-fold":
-			config.NoFold = true
-		case arg == "--config" || arg == "-c" || arg == "-fc":
-			if i+1 >= len(args) {
-				return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--config 需要一个文件路径")
-			}
-			i++
-			patterns, err := loadPatternsFromFile(args[i])
-			if err != nil {
-				return dirs, softFilters, hardFilters, out, help, install, uninstall, err
-			}
-			for _, p := range patterns {
-				softFilters = append(softFilters, p)
-			}
-		case strings.HasPrefix(arg, "--config="):
-			patterns, err := loadPatternsFromFile(strings.TrimPrefix(arg, "--config="))
-			if err != nil {
-				return dirs, softFilters, hardFilters, out, help, install, uninstall, err
-			}
-			for _, p := range patterns {
-				softFilters = append(softFilters, p)
-			}
-		case strings.HasPrefix(arg, "-fc="):
-			patterns, err := loadPatternsFromFile(strings.TrimPrefix(arg, "-fc="))
-			if err != nil {
-				return dirs, softFilters, hardFilters, out, help, install, uninstall, err
-			}
-			for _, p := range patterns {
-				softFilters = append(softFilters, p)
-			}
-		case arg == "-Fc":
-			if i+1 >= len(args) {
-				return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("-Fc 需要一个文件路径")
-			}
-			i++
-			patterns, err := loadPatternsFromFile(args[i])
-			if err != nil {
-				return dirs, softFilters, hardFilters, out, help, install, uninstall, err
-			}
-			for _, p := range patterns {
-				hardFilters = append(hardFilters, p)
-			}
-		case strings.HasPrefix(arg, "-Fc="):
-			patterns, err := loadPatternsFromFile(strings.TrimPrefix(arg, "-Fc="))
-			if err != nil {
-				return dirs, softFilters, hardFilters, out, help, install, uninstall, err
-			}
-			for _, p := range patterns {
-				hardFilters = append(hardFilters, p)
-			}
-		case arg == "--dir" || arg == "-d":
-			consumed := 0
-			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
-				i++
-				dirs.Set(args[i])
-				consumed++
-			}
-			if consumed == 0 {
-				return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--dir 需要一个路径")
-			}
-		case strings.HasPrefix(arg, "--dir="):
-			dirs.Set(strings.TrimPrefix(arg, "--dir="))
-		case arg == "--filter" || arg == "-filter" || arg == "-f":
-			consumed := 0
-			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
-				i++
-				softFilters.Set(args[i])
-				consumed++
-			}
-			if consumed == 0 {
-				return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--filter 需要一个表达式")
-			}
-		case strings.HasPrefix(arg, "--filter=") || strings.HasPrefix(arg, "-filter="):
-			softFilters.Set(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "filter="))
-		case arg == "--Filter" || arg == "-Filter" || arg == "-F":
-			consumed := 0
-			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
-				i++
-				hardFilters.Set(args[i])
-				consumed++
-			}
-			if consumed == 0 {
-				return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--Filter 需要一个表达式")
-			}
-		case strings.HasPrefix(arg, "--Filter=") || strings.HasPrefix(arg, "-Filter="):
-			hardFilters.Set(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "Filter="))
-		case arg == "--out" || arg == "-o":
-			if i+1 >= len(args) {
-				return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--out 需要一个路径")
-			}
-			i++
-			out = args[i]
-		case strings.HasPrefix(arg, "--out="):
-			out = strings.TrimPrefix(arg, "--out=")
-		case arg == "--help" || arg == "-h":
-			help = true
-		default:
-			leftover = append(leftover, arg)
+	for _, format := range append([]string{config.Format}, config.ExtraFormats...) {
+		if (format == "rst" || format == "plain") && config.TreeLinks {
+			return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--tree-links 不支持 %s 输出", format)
+		}
+		if format != "md" && obsidianFlavor() {
+			return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--flavor obsidian 只适用于 Markdown 输出")
 		}
 	}
+	if len(config.ExtraFormats) > 0 && (config.Explode != "" || config.Archive != "" || config.Resume || config.ApplyDiff != "") {
+		return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("多个 --format 不能与 --explode、--archive、--resume 或 --apply-diff 同时使用")
+	}
+	if _, ok := renderers[config.Format].(registeredRenderer); ok && (config.Explode != "" || config.Resume) {
+		// 登记的格式按整个输出写入 (如 JSON 数组)，不能拆分或从中间续写
+		return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--format %s 不能与 --explode 或 --resume 同时使用", config.Format)
+	}
+	if config.Format != "md" && config.ExplodeExt == ".md" {
+		config.ExplodeExt = outputExts[config.Format]
+	}
+	if (config.InstallStatic || config.ReleaseURL != "") && !install {
+		return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--static 与 --release-url 需要与 --install 一起使用")
+	}
+	if config.SARIF != "" && !config.PIIScan && !config.Todos {
+		return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--sarif 需要与 --pii-scan 或 --todos 一起使用")
+	}
+	if config.TreeLinks && config.NumberedHeadings {
+		// 编号取决于实际写入的文件，生成目录树时还无法确定锚点
+		return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--tree-links 不能与 --numbered-headings 一起使用")
+	}
+	if config.TreeLinks && config.TreeFormat == "json" {
+		return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--tree-links 不能与 --tree-format json 一起使用")
+	}
+	if config.GrepContext >= 0 && config.Grep == nil {
+		return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--grep-context 需要与 --grep 一起使用")
+	}
+
+	if config.WithDeps && len(config.GoPackages) == 0 {
+		return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--with-deps 需要与 --go-package 一起使用")
+	}
+
+	switch config.HiddenPolicy {
+	case "include", "tree-only", "exclude":
+	default:
+		return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--hidden 只支持 include、tree-only 或 exclude: %s", config.HiddenPolicy)
+	}
 
+	// 内置忽略表的覆盖与参数顺序无关：先清空，再追加，最后移除
+	if state.noDefaultIgnores {
+		config.IgnoredDirs = map[string]bool{}
+		config.IgnoredExts = map[string]bool{}
+		config.IgnoredFiles = map[string]bool{}
+		config.AssetFiles = map[string]bool{}
+		config.HashedAssets = false
+	}
+	for _, name := range state.assets {
+		config.AssetFiles[name] = true
+	}
+	for _, name := range state.ignoreDirs {
+		config.IgnoredDirs[name] = true
+	}
+	for _, ext := range state.ignoreExts {
+		config.IgnoredExts[normalizeExt(ext)] = true
+	}
+	for _, ext := range state.textExts {
+		config.TextExts[normalizeExt(ext)] = true
+	}
+	for _, name := range state.unignore {
+		delete(config.IgnoredDirs, name)
+		delete(config.IgnoredFiles, name)
+		delete(config.AssetFiles, name)
+		delete(config.IgnoredExts, normalizeExt(name))
+		config.KeepFiles[name] = true
+	}
+
+	if config.SinceLast && config.Snapshot == "" {
+		return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--since-last (dir2txt changes) 需要通过 --snapshot 指定快照库")
+	}
 	if install && uninstall {
 		return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--install 与 --uninstall 不能同时使用")
 	}
+	if config.AISummaries && config.AIEndpoint == "" {
+		return dirs, softFilters, hardFilters, out, help, install, uninstall, fmt.Errorf("--ai-summaries 需要通过 --ai-endpoint 指定接口地址")
+	}
 
-	for _, arg := range leftover {
-		if strings.HasPrefix(arg, "!") || strings.ContainsAny(arg, "*?[]") {
+	// 含通配符的位置参数只有在没有给出任何目录时才可能是目录模式 (dir2txt 'services/*/api')；
+	// 否则 dir2txt . 'vendor/*' 会因 vendor 下恰好都是目录而把它们变成额外的根目录
+	dirGlobs := len(dirs) == 0
+	for _, arg := range state.leftover {
+		if !strings.HasPrefix(arg, "!") && !strings.ContainsAny(arg, "*?[]") {
+			dirGlobs = false
+		}
+	}
+	for _, arg := range state.leftover {
+		if strings.HasPrefix(arg, "!") || (strings.ContainsAny(arg, "*?[]") && !(dirGlobs && isDirGlob(arg))) {
 			softFilters.Set(arg)
 			continue
 		}
@@ -280,6 +360,28 @@ func parseCommandLine() (rawStringList, multiValue, multiValue, string, bool, bo
 	return dirs, softFilters, hardFilters, out, help, install, uninstall, nil
 }
 
+// normalizeExt 将后缀统一为小写并带前导点，如 "MD" -> ".md"
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// filtersForRoot 返回作用于某个根目录的过滤规则集：全局规则在前，该根目录专属规则在后
+func filtersForRoot(absDir string, global []string, perRoot map[string][]string) *FilterSet {
+	rules := global
+	for root, patterns := range perRoot {
+		absRoot, err := filepath.Abs(root)
+		if err != nil || absRoot != absDir {
+			continue
+		}
+		rules = append(append([]string{}, rules...), normalizeFilters(patterns)...)
+	}
+	return newFilterSet(rules)
+}
+
 func normalizeFilters(filters []string) []string {
 	var out []string
 	for _, f := range filters {
@@ -348,7 +450,7 @@ func determineOutputPath(dirs []string, userOut string) (string, error) {
 
 	cleanOut := filepath.Clean(userOut)
 	dirHint := strings.HasSuffix(userOut, string(os.PathSeparator)) || strings.HasSuffix(userOut, "/") || strings.HasSuffix(userOut, "\\")
-	if strings.EqualFold(filepath.Ext(cleanOut), ".md") {
+	if uncompressed, _ := trimCompressExt(cleanOut); isOutputExt(filepath.Ext(uncompressed)) {
 		return cleanOut, nil
 	}
 
@@ -367,8 +469,11 @@ func determineOutputPath(dirs []string, userOut string) (string, error) {
 }
 
 func buildOutputFileName(absDirs []string) string {
+	if config.CI {
+		return ciOutputName + outputExts[config.Format]
+	}
 	if len(absDirs) == 1 {
-		return fmt.Sprintf("%s_context.md", filepath.Base(absDirs[0]))
+		return fmt.Sprintf("%s_context%s", filepath.Base(absDirs[0]), outputExts[config.Format])
 	}
 	common := findCommonAncestor(absDirs)
 	base := "merged_project"
@@ -378,7 +483,26 @@ func buildOutputFileName(absDirs []string) string {
 	if base == "" {
 		base = "merged_project"
 	}
-	return fmt.Sprintf("%s_context.md", base)
+	return fmt.Sprintf("%s_context%s", base, outputExts[config.Format])
+}
+
+// outputExts 各输出格式对应的文件后缀
+var outputExts = map[string]string{
+	"md":        ".md",
+	"rst":       ".rst",
+	"org":       ".org",
+	"plain":     ".txt",
+	"rag-jsonl": ".jsonl",
+}
+
+// isOutputExt 判断后缀是否为某种输出格式的后缀 (此时 --out 视为文件而非目录)
+func isOutputExt(ext string) bool {
+	for _, e := range outputExts {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
 }
 
 func findCommonAncestor(paths []string) string {
@@ -423,6 +547,17 @@ var config = Config{
 		"target":       true,
 		".next":        true,
 		"coverage":     true,
+		// 前端框架的构建输出与缓存目录
+		".nuxt":            true,
+		".output":          true,
+		".svelte-kit":      true,
+		".angular":         true,
+		".turbo":           true,
+		".parcel-cache":    true,
+		".vercel":          true,
+		".docusaurus":      true,
+		".astro":           true,
+		"storybook-static": true,
 	},
 	IgnoredExts: map[string]bool{
 		// 图片/媒体
@@ -443,6 +578,44 @@ var config = Config{
 		"dir2txt":     true,
 		"dir2txt.exe": true,
 	},
+	// 锁文件等机器生成的清单：在目录树中显示，但不读取内容
+	AssetFiles: map[string]bool{
+		"*.lock":              true,
+		"package-lock.json":   true,
+		"npm-shrinkwrap.json": true,
+		"pnpm-lock.yaml":      true,
+		"go.sum":              true,
+		"composer.lock":       true,
+		// source map 与打包产物
+		"*.map":        true,
+		"*.bundle.js":  true,
+		"*.bundle.css": true,
+		"*.chunk.js":   true,
+		"*.chunk.css":  true,
+	},
+	HashedAssets: true,
+	KeepFiles:    map[string]bool{},
+	RootSoft:     map[string][]string{},
+	RootHard:     map[string][]string{},
+	// 隐藏文件默认排除，但以下常见的项目配置始终保留
+	HiddenPolicy: "exclude",
+	HiddenAllow: map[string]bool{
+		".env":           true,
+		".env.example":   true,
+		".gitignore":     true,
+		".gitattributes": true,
+		".github":        true,
+		".gitlab-ci.yml": true,
+		".dockerignore":  true,
+		".editorconfig":  true,
+		".eslintrc*":     true,
+		".prettierrc*":   true,
+		".babelrc":       true,
+		".npmrc":         true,
+		".nvmrc":         true,
+		".golangci.yml":  true,
+		".golangci.yaml": true,
+	},
 	TextExts: map[string]bool{
 		".md": true, ".txt": true, ".log": true,
 		".go": true, ".java": true, ".py": true, ".js": true, ".ts": true,
@@ -450,48 +623,126 @@ var config = Config{
 		".html": true, ".css": true, ".xml": true, ".yaml": true, ".yml": true,
 		".json": true, ".sql": true, ".properties": true, ".ini": true,
 		".sh": true, ".bat": true, ".conf": true, ".toml": true,
+		".rs": true, ".rb": true, ".php": true, ".cs": true, ".kt": true, ".swift": true,
+		".scala": true, ".lua": true, ".pl": true, ".r": true, ".dart": true, ".zig": true,
+		".jsx": true, ".tsx": true, ".mjs": true, ".cjs": true, ".vue": true, ".svelte": true,
+		".scss": true, ".less": true, ".graphql": true, ".proto": true, ".gradle": true,
+		".cmake": true, ".mk": true, ".tf": true, ".hcl": true, ".ps1": true, ".bash": true,
+		".zsh": true, ".fish": true, ".rst": true, ".adoc": true, ".cfg": true, ".env": true,
+		".csv": true, ".tsv": true,
 	},
-	MaxFileSize: 1024 * 1024, // 1MB
+	// 常见的无后缀 (或后缀不代表类型) 的文本文件：始终输出，并用于代码块语言标记；可用 --file-lang 扩展
+	FileLangs: map[string]string{
+		"Dockerfile": "dockerfile", "Containerfile": "dockerfile", "Earthfile": "dockerfile",
+		"Makefile": "makefile", "GNUmakefile": "makefile", "makefile": "makefile",
+		"CMakeLists.txt": "cmake", "Jenkinsfile": "groovy",
+		"Vagrantfile": "ruby", "Gemfile": "ruby", "Rakefile": "ruby", "Brewfile": "ruby", "Podfile": "ruby", "Fastfile": "ruby",
+		"Tiltfile": "python", "Snakefile": "python", "BUILD": "python", "WORKSPACE": "python",
+		"Justfile": "just", "justfile": "just", "Procfile": "text", "Caddyfile": "text",
+		"README": "text", "LICENSE": "text", "COPYING": "text", "NOTICE": "text", "AUTHORS": "text",
+		"CHANGELOG": "text", "CODEOWNERS": "text", "OWNERS": "text",
+		".gitignore": "gitignore", ".dockerignore": "gitignore", ".gitattributes": "text",
+		".editorconfig": "ini", ".npmrc": "ini", ".nvmrc": "text",
+	},
+	MaxFileSize:     1024 * 1024, // 1MB
+	GrepContext:     -1,
+	TrimStrategy:    "size",
+	Format:          "md",
+	EnvValues:       "mask",
+	ExplodeExt:      ".md",
+	TreeFormat:      "ascii",
+	TreeOrder:       "dirs-first",
+	Flavor:          "github",
+	HeadingLevel:    2,
+	IORetries:       3,
+	Oversize:        "abort",
+	WarnShare:       25,
+	ControlChars:    "strip",
+	BinaryWindow:    8192,
+	BinaryThreshold: 30,
+	UnknownExt:      "include",
+	AIModel:         defaultAIModel,
 }
 
 func main() {
-	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "dir2txt %s\n", version)
-		fmt.Fprintf(flag.CommandLine.Output(), "用法: dir2txt [--dir <path> ...] [--filter <pattern> ...] [dir|filter ...]\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "示例:\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  dir2txt --dir . ../other --filter '*.png *.jpg' '!keep.png'\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  dir2txt --filter '*.png' --filter '!keep.png' src test\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  dir2txt -F 'dist/**' -f '*.png' src\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "参数:\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  --dir/-d      指定要扫描的目录，可重复；也可用位置参数追加目录\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  --filter/-f   软过滤：仅跳过文件内容输出，目录和树仍显示；支持 * ? [] 与 ! 反向\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  --Filter/-F   硬过滤：目录树和文件内容都不显示；支持 * ? [] 与 ! 反向\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  --config/-c   指定配置文件路径 (默认作为软过滤); 行首 # 视为注释\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  -fc           指定配置文件路径 (强制作为软过滤); 行首 # 视为注释\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  -Fc           指定配置文件路径 (强制作为硬过滤); 行首 # 视为注释\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  Pattern 语法: ? 单字符 (test?.log); * 任意串 (*.go); [] 字符范围 (file[0-9].txt); 前缀 ! 取反 (!important.txt)\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  --out/-o      指定输出文件路径或输出目录\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  --no-fold     在目录树中不折叠长文件列表，始终显示全部文件 (默认超过 %d 个文件折叠)\n", maxDisplayFiles)
-		fmt.Fprintf(flag.CommandLine.Output(), "  --install     安装程序到系统 (Linux: /usr/local/bin; Windows: Program Files 并添加 PATH)\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  --uninstall   从系统中卸载程序\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  位置参数      未被 --dir 消耗的参数：若含 * ? [] 或以 ! 开头视为软过滤，其它视为目录\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  --help/-h     显示此帮助\n")
-	}
-
-	parsedDirs, parsedSoftFilters, parsedHardFilters, outFlag, help, install, uninstall, err := parseCommandLine()
+	flag.Usage = printUsage
+
+	if len(os.Args) > 1 && (os.Args[1] == "help-full" || os.Args[1] == "man") {
+		runHelpCommand(os.Args[1])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		if err := runCompare(os.Args[2:]); err != nil {
+			errorf("错误: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "test-filter" {
+		if err := runTestFilter(os.Args[2:]); err != nil {
+			errorf("错误: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "daemon" {
+		if err := runDaemon(os.Args[2:]); err != nil {
+			errorf("错误: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "gen-fixture" {
+		if err := runGenFixture(os.Args[2:]); err != nil {
+			errorf("错误: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "install-shell" {
+		if err := runInstallShell(os.Args[2:]); err != nil {
+			errorf("错误: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// 分层配置：内置默认值 → 用户级配置 → 仓库级 .dir2txt → 命令行参数
+	defaults, _ := json.Marshal(config)
+	cliArgs := os.Args[1:]
+	if len(cliArgs) > 0 && cliArgs[0] == "changes" {
+		// dir2txt changes 等同于 --since-last
+		cliArgs = append([]string{"--since-last"}, cliArgs[1:]...)
+	}
+	layers, err := configLayers(cliArgs)
+	if err != nil {
+		errorf("错误: %v\n", err)
+		os.Exit(1)
+	}
+	parsedDirs, parsedSoftFilters, parsedHardFilters, outFlag, help, install, uninstall, err := parseCommandLine(layerArgs(layers)...)
 	if help {
 		flag.Usage()
 		return
 	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		errorf("错误: %v\n", err)
 		flag.Usage()
 		os.Exit(1)
 	}
 
+	if config.CI && (install || uninstall) {
+		errorf("错误: --ci 模式下不允许 --install/--uninstall\n")
+		os.Exit(exitError)
+	}
+
 	if install {
 		if err := manageInstallation(true); err != nil {
-			fmt.Fprintf(os.Stderr, "安装失败: %v\n", err)
+			errorf("安装失败: %v\n", err)
 			os.Exit(1)
 		}
 		return
@@ -499,7 +750,7 @@ func main() {
 
 	if uninstall {
 		if err := manageInstallation(false); err != nil {
-			fmt.Fprintf(os.Stderr, "卸载失败: %v\n", err)
+			errorf("卸载失败: %v\n", err)
 			os.Exit(1)
 		}
 		return
@@ -511,87 +762,382 @@ func main() {
 	if len(dirs) == 0 {
 		dirs = append(dirs, ".")
 	}
+	if dirs, err = expandDirGlobs(dirs); err != nil {
+		errorf("错误: %v\n", err)
+		os.Exit(exitError)
+	}
+	if dirs, rootFiles, err = splitRootFiles(dirs); err != nil {
+		errorf("错误: %v\n", err)
+		os.Exit(exitError)
+	}
+	// 只给出文件时不遍历任何目录；需要目录的选项仍然需要目录参数
+	if len(dirs) == 0 && (len(config.Workspaces) > 0 || len(config.GoPackages) > 0 || config.ApplyDiff != "") {
+		errorf("错误: --workspace、--go-package 与 --apply-diff 需要目录参数\n")
+		os.Exit(exitError)
+	}
+	if config.ShowEffectiveConfig {
+		showEffectiveConfig(layers, defaults, dirs, softFilters, hardFilters)
+		return
+	}
+
+	for _, spec := range config.Plugins {
+		p, err := newExecProcessor(spec)
+		if err != nil {
+			errorf("错误: %v\n", err)
+			os.Exit(exitError)
+		}
+		processors = append(processors, p)
+	}
+
+	if len(config.Workspaces) > 0 {
+		resolved, err := resolveWorkspaces(dirs[0], config.Workspaces)
+		if err != nil {
+			errorf("错误: %v\n", err)
+			os.Exit(exitError)
+		}
+		for i, dir := range resolved {
+			logf("工作区包 %s: %s\n", config.Workspaces[i], dir)
+		}
+		dirs = resolved
+	}
+
+	dirs = dedupeRoots(dirs)
 
-	finalOutPath, err := determineOutputPath(dirs, outFlag)
+	if len(config.GoPackages) > 0 {
+		files, err := goPackageFiles(dirs[0], config.GoPackages, config.WithDeps)
+		if err != nil {
+			errorf("错误: %v\n", err)
+			os.Exit(1)
+		}
+		logf("Go 包共包含 %d 个源文件\n", len(files))
+		config.IncludeOnly = files
+	}
+
+	// 只给出文件时按当前目录命名输出文件
+	nameDirs := dirs
+	if len(nameDirs) == 0 {
+		nameDirs = []string{"."}
+	}
+	finalOutPath, err := determineOutputPath(nameDirs, outFlag)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "错误: 无法确定输出路径: %v\n", err)
+		errorf("错误: 无法确定输出路径: %v\n", err)
 		os.Exit(1)
 	}
 
+	if _, method := trimCompressExt(finalOutPath); method != "" && config.Compress == "" {
+		config.Compress = method
+	}
+	if config.Compress != "" && (config.Explode != "" || config.Archive != "") {
+		errorf("错误: --compress 不能与 --explode 或 --archive 同时使用\n")
+		os.Exit(exitError)
+	}
+	if config.Compress != "" {
+		finalOutPath = compressedPath(finalOutPath, config.Compress)
+	}
+
 	config.OutputFile = filepath.Base(finalOutPath)
+	if config.Explode != "" {
+		// 拆分模式下主输出为索引文件，目录树中隐藏整个输出目录
+		absExplode, err := filepath.Abs(config.Explode)
+		if err != nil {
+			errorf("错误: 无法确定输出目录: %v\n", err)
+			os.Exit(1)
+		}
+		config.Explode = absExplode
+		finalOutPath = filepath.Join(absExplode, "index"+outputExts[config.Format])
+		config.OutputFile = filepath.Base(absExplode)
+	}
 	if err := os.MkdirAll(filepath.Dir(finalOutPath), 0o755); err != nil {
-		fmt.Fprintf(os.Stderr, "无法创建输出目录: %v\n", err)
+		errorf("无法创建输出目录: %v\n", err)
 		os.Exit(1)
 	}
+	// 先写入同目录下的临时文件，成功后再改名，崩溃时不会留下写了一半的输出
+	finalOutPath = resolveOutputPath(finalOutPath)
+	if config.Versioned {
+		finalOutPath = versionedPath(finalOutPath)
+		if config.Explode == "" {
+			config.OutputFile = filepath.Base(finalOutPath)
+		}
+	} else if config.NoOverwrite {
+		if _, err := os.Stat(finalOutPath); err == nil {
+			errorf("错误: 输出文件已存在 (--no-overwrite): %s\n", finalOutPath)
+			os.Exit(exitError)
+		}
+	}
+	excludePriorOutputs(finalOutPath)
+	if err := planExtraOutputs(finalOutPath); err != nil {
+		errorf("错误: %v\n", err)
+		os.Exit(exitError)
+	}
+	if err := initSnapshot(dirs); err != nil {
+		errorf("错误: %v\n", err)
+		os.Exit(exitError)
+	}
+	writePath := partialPath(finalOutPath)
 
-	outFile, err := os.Create(finalOutPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "无法创建输出文件: %v\n", err)
-		os.Exit(1)
+	if config.PreHook != "" {
+		if err := runHook(config.PreHook, newHookSummary("pre", finalOutPath, dirs, exitOK)); err != nil {
+			errorf("错误: %v\n", err)
+			os.Exit(exitError)
+		}
 	}
-	defer outFile.Close()
 
-	writer := bufio.NewWriter(outFile)
-	defer writer.Flush()
+	if resumable() {
+		resumeRun.path = resumeStatePath(finalOutPath)
+		resumeRun.args = resumeArgs(cliArgs)
+	} else if config.Resume {
+		errorf("错误: --resume 不支持与 --budget-tokens、--rank、--show-rank、--max-files、--compress、--explode 或 --apply-diff 同时使用\n")
+		os.Exit(exitError)
+	}
 
-	fmt.Printf("结果将写入: %s\n", finalOutPath)
+	var outFile *os.File
+	if config.Resume {
+		state, err := loadResumeState(resumeRun.path, writePath, resumeRun.args)
+		if err != nil {
+			errorf("无法续传: %v\n", err)
+			os.Exit(exitError)
+		}
+		if outFile, err = openResumedOutput(writePath, state); err != nil {
+			errorf("无法打开输出文件: %v\n", err)
+			os.Exit(exitError)
+		}
+		resumeRun.resumed = state
+		stats = state.Stats
+		logf("从断点继续: 已完成 %d 个文件，最后一个为 %s\n", state.Done, state.LastFile)
+	} else if outFile, err = os.Create(writePath); err != nil {
+		errorf("无法创建输出文件: %v\n", err)
+		os.Exit(exitError)
+	}
+	ctx := interruptContext()
+
+	// 压缩时统计的是压缩前的内容，token 估算与大小限制均按未压缩内容计算
+	var sink io.Writer = outFile
+	var compressor io.WriteCloser
+	if config.Compress != "" {
+		if compressor, err = newCompressor(outFile, config.Compress); err != nil {
+			errorf("无法创建压缩输出: %v\n", err)
+			outFile.Close()
+			os.Exit(exitError)
+		}
+		sink = compressor
+	}
+	output = &countingWriter{w: sink}
+	if state := resumeRun.resumed; state != nil {
+		output.bytes, output.ascii, output.wide = state.Bytes, state.ASCII, state.Wide
+	}
+	writer := newDocWriter(bufio.NewWriter(output), config.Format)
+	closeOutput := func() {
+		writer.Flush()
+		if compressor != nil {
+			compressor.Close()
+		}
+		outFile.Close()
+	}
+	if err := openExtraOutputs(); err != nil {
+		errorf("无法创建输出文件: %v\n", err)
+		closeOutput()
+		os.Remove(writePath)
+		os.Exit(exitError)
+	}
 
-	if err := processDirs(dirs, softFilters, hardFilters, writer, finalOutPath); err != nil {
-		fmt.Fprintf(os.Stderr, "处理目录失败: %v\n", err)
-		os.Exit(1)
+	if config.Plan == 0 {
+		logf("结果将写入: %s\n", finalOutPath)
 	}
 
-	fmt.Println("完成！")
-}
+	if err := startProfiling(); err != nil {
+		errorf("错误: %v\n", err)
+		closeOutput()
+		os.Exit(exitError)
+	}
+	runStart := time.Now()
+	initTelemetry()
+	initProgress()
+	initIOLimit()
 
-func processDirs(dirs []string, softFilters []string, hardFilters []string, writer *bufio.Writer, finalOutPath string) error {
-	absOut, err := filepath.Abs(finalOutPath)
-	if err != nil {
-		return err
+	if config.ApplyDiff != "" {
+		if err := processPatch(dirs[0], config.ApplyDiff, writer); err != nil {
+			errorf("处理补丁失败: %v\n", err)
+			closeOutput()
+			os.Remove(writePath)
+			os.Exit(exitError)
+		}
+	} else if err := processDirs(ctx, dirs, softFilters, hardFilters, writer, finalOutPath); errors.Is(err, context.Canceled) {
+		closeOutput()
+		discardExtraOutputs()
+		stopProfiling()
+		printCancelSummary(writePath)
+		exportTelemetry(exitInterrupted)
+		if config.CI {
+			printCIResult(writePath, exitInterrupted)
+		}
+		os.Exit(exitInterrupted)
+	} else if errors.Is(err, errPlanOnly) {
+		closeOutput()
+		discardExtraOutputs()
+		os.Remove(writePath)
+		os.Exit(exitOK)
+	} else if errors.Is(err, errOutputTooLarge) {
+		closeOutput()
+		discardExtraOutputs()
+		os.Remove(writePath)
+		// 大纲模式在写入过程中才发现超限，已保存的断点没有意义
+		clearResumeState()
+		errorf("[ERROR] %v\n", err)
+		exportTelemetry(exitOverBudget)
+		if config.CI {
+			printCIResult(writePath, exitOverBudget)
+		}
+		os.Exit(exitOverBudget)
+	} else if errors.Is(err, errNoFiles) {
+		closeOutput()
+		discardExtraOutputs()
+		os.Remove(writePath)
+		printNoFilesDiagnostic(5)
+		exportTelemetry(exitNoFiles)
+		if config.CI {
+			printCIResult(writePath, exitNoFiles)
+		}
+		os.Exit(exitNoFiles)
+	} else if errors.Is(err, errResumeMismatch) {
+		closeOutput()
+		errorf("无法续传: %v\n", err)
+		os.Exit(exitError)
+	} else if err != nil {
+		errorf("处理目录失败: %v\n", err)
+	}
+
+	// os.Exit 不会执行 defer，这里显式刷新并关闭输出文件
+	closeOutput()
+	if err := commitOutput(writePath, finalOutPath); err != nil {
+		errorf("无法写入输出文件: %v\n", err)
+		os.Exit(exitError)
+	}
+	clearResumeState()
+	if config.Explode == "" {
+		if err := recordOutput(finalOutPath); err != nil {
+			logf("[WARN] 无法记录输出文件: %v\n", err)
+		}
+	}
+	if err := commitExtraOutputs(); err != nil {
+		errorf("无法写入输出文件: %v\n", err)
+		os.Exit(exitError)
+	}
+	if err := saveSnapshot(); err != nil {
+		errorf("无法写入快照库: %v\n", err)
+		os.Exit(exitError)
 	}
 
-	writer.WriteString("# Project Structure\n\n")
-	writer.WriteString("```text\n")
-	for _, dir := range dirs {
-		absDir, err := filepath.Abs(dir)
-		if err != nil {
-			writer.WriteString(fmt.Sprintf("%s/\n", dir))
-			writer.WriteString(fmt.Sprintf("Error generating tree: %v\n", err))
-			continue
+	if config.EmitFilterFile != "" {
+		if err := writeFilterFile(config.EmitFilterFile, dirs, softFilters, hardFilters); err != nil {
+			errorf("无法写入过滤规则文件: %v\n", err)
+			os.Exit(exitError)
 		}
-		writer.WriteString(filepath.Base(absDir) + "/\n")
-		if err := writeTree(absDir, absDir, absDir, absDir, "", writer, hardFilters, map[string]bool{}); err != nil {
-			writer.WriteString(fmt.Sprintf("Error generating tree for %s: %v\n", dir, err))
+		logf("过滤规则建议已写入: %s\n", config.EmitFilterFile)
+	}
+
+	if config.SARIF != "" {
+		if err := writeSARIF(config.SARIF); err != nil {
+			errorf("无法写入 SARIF 文件: %v\n", err)
+			os.Exit(exitError)
 		}
-		writer.WriteString("\n")
+		logf("扫描结果已导出为 SARIF: %s\n", config.SARIF)
 	}
-	writer.WriteString("```\n\n")
-	writer.WriteString("---\n\n")
 
-	writer.WriteString("# File Contents\n\n")
+	if config.Archive == "zip" {
+		src := finalOutPath
+		if config.Explode != "" {
+			src = config.Explode
+		}
+		dest := archivePath(finalOutPath)
+		if err := writeZipArchive(src, dest); err != nil {
+			errorf("打包失败: %v\n", err)
+			os.Exit(exitError)
+		}
+		logf("已打包: %s\n", dest)
+	}
+
+	stopProfiling()
+	if config.Bench {
+		printBench(time.Since(runStart))
+	}
+
+	if config.PIIScan {
+		printPIISummary()
+	}
+	if config.ExtStats {
+		printExtStats()
+	}
+	printControlSummary()
+	if config.ApplyDiff == "" && !config.CI {
+		printUnusedRules(softFilters, hardFilters)
+	}
+	warnHeavyFiles()
+
+	code := exitCode(output)
+	if config.PostHook != "" {
+		if err := runHook(config.PostHook, newHookSummary("post", finalOutPath, dirs, code)); err != nil {
+			errorf("错误: %v\n", err)
+			if code == exitOK {
+				code = exitError
+			}
+		}
+	}
+	exportTelemetry(code)
+	if config.CI {
+		printCIResult(finalOutPath, code)
+	}
+	if code != exitOK {
+		os.Exit(code)
+	}
+	if config.CacheDir != "" {
+		logf("缓存命中 %d 个文件\n", stats.CacheHits)
+	}
+	logf("完成！\n")
+}
+
+// collectCandidates 遍历各根目录，按过滤与忽略规则收集要输出的文件 (不读取内容)；absOut 为输出文件，遍历时排除
+// 返回第一个目录级错误，其余目录照常遍历；ctx 取消时提前返回，由调用方检查 ctx.Err()
+func collectCandidates(ctx context.Context, dirs, softFilters, hardFilters []string, absOut string) ([]candidateFile, error) {
 	var firstErr error
+	var candidates []candidateFile
 	for _, dir := range dirs {
 		absDir, err := filepath.Abs(dir)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "无法获取目录 %s 绝对路径: %v\n", dir, err)
+			errorf("无法获取目录 %s 绝对路径: %v\n", dir, err)
 			firstErr = err
+			stats.WalkErrors++
 			continue
 		}
-		err = walkFollowSymlinks(absDir, func(logicalRel string, fullPath string, d os.DirEntry) error {
+		rootSoft := filtersForRoot(absDir, softFilters, config.RootSoft)
+		rootHard := filtersForRoot(absDir, hardFilters, config.RootHard)
+		err = walkFollowSymlinks(ctx, absDir, func(logicalRel string, fullPath string, d os.DirEntry) error {
+			progress.scanned()
 			// 排除输出文件自身
 			absPath := fullPath
-			if absPath == absOut {
+			if absPath == absOut || absPath == absOut+".bak" || isPriorOutput(absPath) {
 				if d.IsDir() {
 					return filepath.SkipDir
 				}
 				return nil
 			}
+			if config.Explode != "" && d.IsDir() && hasPathPrefix(absPath, filepath.Dir(absOut)) {
+				return filepath.SkipDir
+			}
 
 			name := d.Name()
-			if isJunk(name) {
+			if isJunk(name) || isHiddenTreeOnly(name) {
+				noteBuiltinExclusion(name, false)
+				reason := "默认忽略的文件"
+				if isHidden(name) {
+					reason = "隐藏文件 (--hidden)"
+				} else if d.IsDir() {
+					reason = "默认忽略的目录 " + name
+				}
 				if d.IsDir() {
+					noteExcludedDir(fullPath, reason)
 					return filepath.SkipDir
 				}
+				noteExclusion(reason)
 				return nil
 			}
 
@@ -601,26 +1147,47 @@ func processDirs(dirs []string, softFilters []string, hardFilters []string, writ
 			}
 
 			if relSlash != "" {
-				matchedHard, _ := checkFilter(relSlash, hardFilters)
-				if matchedHard {
+				hard := rootHard.Match(relSlash)
+				markRuleUsed(hard.Rule)
+				if hard.Matched {
+					reason := fmt.Sprintf("Hard Filter \"%s\"", hard.Rule)
 					if d.IsDir() {
+						noteExcludedDir(fullPath, reason)
+						recordSkippedDir(fullPath)
 						return filepath.SkipDir
 					}
+					noteExclusion(reason)
+					recordSkipped(fullPath)
 					return nil
 				}
+				if d.IsDir() {
+					if marker := dirMarker(fullPath); marker != "" {
+						logf("[SKIP] 忽略目录 (标记文件 %s): %s\n", marker, relSlash)
+						noteExcludedDir(fullPath, "标记文件 "+marker)
+						recordSkippedDir(fullPath)
+						return filepath.SkipDir
+					}
+				}
 			}
 
-			matchedSoft, rule := checkFilter(relSlash, softFilters)
-			if matchedSoft {
+			soft := rootSoft.Match(relSlash)
+			markRuleUsed(soft.Rule)
+			if soft.Matched {
+				rule := soft.Rule
 				display := relSlash
 				if display == "" {
 					display = filepath.ToSlash(fullPath)
 				}
+				reason := fmt.Sprintf("Soft Filter \"%s\"", rule)
 				if d.IsDir() {
-					fmt.Printf("[SKIP] 忽略目录 (Soft Filter: \"%s\"): %s\n", rule, display)
+					logf("[SKIP] 忽略目录 (Soft Filter: \"%s\"): %s\n", rule, display)
+					noteExcludedDir(fullPath, reason)
+					recordSkippedDir(fullPath)
 					return filepath.SkipDir
 				}
-				fmt.Printf("[SKIP] 忽略内容 (Soft Filter: \"%s\"): %s\n", rule, display)
+				logf("[SKIP] 忽略内容 (Soft Filter: \"%s\"): %s\n", rule, display)
+				noteExclusion(reason)
+				recordSkipped(fullPath)
 				return nil
 			}
 
@@ -628,21 +1195,266 @@ func processDirs(dirs []string, softFilters []string, hardFilters []string, writ
 				return nil
 			}
 
-			if isAsset(name) {
+			if isAsset(name) && matchProcessor(fullPath) == nil {
+				noteBuiltinExclusion(name, !matchNameRule(name, config.AssetFiles))
+				noteExclusion("资源文件 (图片、压缩包等)")
+				recordSkipped(fullPath)
+				return nil
+			}
+
+			if config.UnknownExt == "skip" && isUnknownExt(name) && matchProcessor(fullPath) == nil {
+				logf("[SKIP] 未知后缀 (--unknown-ext skip): %s\n", relSlash)
+				noteExclusion("未知后缀 (--unknown-ext skip)")
+				recordSkipped(fullPath)
+				return nil
+			}
+
+			if config.IncludeOnly != nil && !config.IncludeOnly[fullPath] {
+				noteExclusion("不属于 --go-package 指定的包")
 				return nil
 			}
 
-			return processFile(fullPath, writer)
+			rel := filepath.ToSlash(filepath.Join(filepath.Base(absDir), logicalRel))
+			if first, dup := checkHardlink(fullPath, rel, d); dup {
+				logf("[SKIP] 硬链接 (与 %s 为同一文件): %s\n", first, relSlash)
+				noteExclusion("硬链接 (同一文件只输出一次)")
+				return nil
+			}
+			if !noteSnapshotFile(rel, fullPath) && config.SinceLast {
+				noteExclusion("自上次快照以来未变化 (--since-last)")
+				return nil
+			}
+			candidates = append(candidates, candidateFile{
+				Path: fullPath,
+				Rel:  rel,
+			})
+			return nil
 		})
+		if ctx.Err() != nil {
+			return candidates, firstErr
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "处理目录 %s 时出错: %v\n", dir, err)
+			errorf("处理目录 %s 时出错: %v\n", dir, err)
 			firstErr = err
+			stats.WalkErrors++
+		}
+	}
+
+	return appendRootFiles(candidates), firstErr
+}
+
+func processDirs(ctx context.Context, dirs []string, softFilters []string, hardFilters []string, writer *docWriter, finalOutPath string) error {
+	absOut, err := filepath.Abs(finalOutPath)
+	if err != nil {
+		return err
+	}
+
+	defer progress.clear()
+	stopWalk := startStage("walk")
+	progress.startScan()
+	candidates, firstErr := collectCandidates(ctx, dirs, softFilters, hardFilters, absOut)
+	if ctx.Err() != nil {
+		writeCancelNotice(writer)
+		return ctx.Err()
+	}
+	stopWalk()
+
+	if config.MaxFilesPerDir > 0 {
+		candidates = capFilesPerDir(candidates, config.MaxFilesPerDir)
+	}
+	if config.Plan > 0 {
+		printPlan(candidates, config.Plan)
+		return errPlanOnly
+	}
+	if config.MaxOutputSize > 0 && resumeRun.resumed == nil {
+		if err := checkOutputSize(candidates); err != nil {
+			return err
+		}
+	}
+
+	progress.startRead(len(candidates))
+	// 续传时目录树等头部已经写入；--todos 等章节需要先知道哪些文件会被输出
+	if resumeRun.resumed == nil {
+		stopTree := startStage("tree")
+		// 目录树与附加章节只收集一次，各格式共用
+		var header *projectHeader
+		if writer.document() || len(extraOutputs) > 0 {
+			header = collectProjectHeader(ctx, dirs, hardFilters, candidates)
+		}
+		forEachFormat(writer, func(w *docWriter) {
+			w.Begin(w)
+			w.WriteTree(header, w)
+		})
+		stopTree()
+	}
+	if err := ctx.Err(); err != nil {
+		writeCancelNotice(writer)
+		return err
+	}
+
+	if config.BudgetTokens > 0 || config.Rank || config.ShowRank || config.MaxFiles > 0 {
+		// 排序与预算模式需要先读取全部文件，才能决定顺序以及丢弃或截断哪些
+		writer.Flush()
+		defer cleanupSpill()
+		var files []*fileContent
+		for _, c := range candidates {
+			if ctx.Err() != nil {
+				writeCancelNotice(writer)
+				return ctx.Err()
+			}
+			if fc := c.prepare(); fc != nil {
+				holdContent(fc)
+				files = append(files, fc)
+			}
+		}
+		if config.Rank || config.ShowRank || config.MaxFiles > 0 {
+			scores := scoreFiles(files)
+			ranked := rankFiles(files, scores)
+			if config.ShowRank {
+				printRanking(ranked, scores)
+			}
+			if config.MaxFiles > 0 {
+				files, ranked = limitFileCount(files, ranked, config.MaxFiles)
+			}
+			if config.Rank {
+				files = ranked
+			}
+		}
+		if config.BudgetTokens > 0 {
+			// 为末尾的省略文件附录预留少量空间
+			remaining := config.BudgetTokens - output.Tokens() - 100
+			if remaining < 0 {
+				remaining = 0
+			}
+			files = selectWithinBudget(files, remaining, config.TrimStrategy)
+		}
+		for _, fc := range files {
+			if ctx.Err() != nil {
+				writeCancelNotice(writer)
+				return ctx.Err()
+			}
+			writeFileSection(fc, writer)
+			releaseContent(fc)
+			if err := checkOutlineSize(writer); err != nil {
+				return err
+			}
+		}
+	} else {
+		start := 0
+		if state := resumeRun.resumed; state != nil {
+			start = state.Done
+			if start > len(candidates) || (start > 0 && candidates[start-1].Path != state.LastFile) {
+				return errResumeMismatch
+			}
+		}
+		for i := start; i < len(candidates); i++ {
+			if ctx.Err() != nil {
+				// 先保存断点再写截断说明，续传时会截掉说明继续写入
+				if i > 0 {
+					checkpoint(writer.Writer, i, candidates[i-1].Path, true)
+				}
+				writeCancelNotice(writer)
+				return ctx.Err()
+			}
+			if fc := candidates[i].prepare(); fc != nil {
+				writeFileSection(fc, writer)
+				if err := checkOutlineSize(writer); err != nil {
+					return err
+				}
+			}
+			checkpoint(writer.Writer, i+1, candidates[i].Path, false)
 		}
 	}
+	if stats.Included == 0 && firstErr == nil && resumeRun.resumed == nil && !config.AllowEmpty && !sinceLastBaseline() {
+		return errNoFiles
+	}
+	if config.Explode != "" {
+		writeExplodeIndex(writer)
+	}
+	forEachFormat(writer, func(w *docWriter) {
+		w.End(w)
+	})
+	if err := checkOutlineSize(writer); err != nil {
+		return err
+	}
 	return firstErr
 }
 
+// projectHeader 文档开头的目录树与附加章节的内容；只收集一次，再按每种输出格式分别写入
+type projectHeader struct {
+	candidates []candidateFile
+	roots      []*treeNode
+	goGraph    []goGraphModule
+	deps       []dependency
+	contracts  []contractSummary
+	licenses   []licenseFile
+	todos      []todoItem
+	testMap    []testMapping
+}
+
+// collectProjectHeader 生成目录树并收集各附加章节的内容，被中断时返回 nil
+func collectProjectHeader(ctx context.Context, dirs []string, hardFilters []string, candidates []candidateFile) *projectHeader {
+	h := &projectHeader{candidates: candidates}
+	var ok bool
+	if h.roots, ok = buildProjectTree(ctx, dirs, hardFilters); !ok {
+		return nil
+	}
+	if config.GoGraph != "" {
+		h.goGraph = collectGoGraph(dirs, config.GoGraph)
+	}
+	if config.DepsSummary {
+		h.deps = collectDependencies(ctx, dirs, hardFilters)
+	}
+	if config.ContractsSummary {
+		h.contracts = collectContracts(ctx, dirs, hardFilters)
+	}
+	if config.Licenses {
+		h.licenses = collectLicenses(ctx, dirs)
+	}
+	if config.Todos {
+		h.todos = collectTodos(ctx, candidates)
+		todoFindings = h.todos
+	}
+	if config.TestMap {
+		h.testMap = buildTestMap(candidates)
+	}
+	return h
+}
+
+// writeProjectHeader 输出文档的目录树与附加章节
+func writeProjectHeader(h *projectHeader, writer *docWriter) {
+	if h == nil {
+		return
+	}
+	writeProjectTree(h.roots, h.candidates, writer)
+
+	if config.GoGraph != "" {
+		writeGoGraph(h.goGraph, writer)
+	}
+	if config.DepsSummary {
+		writeDepsSummary(h.deps, writer)
+	}
+	if config.ContractsSummary {
+		writeContractsSummary(h.contracts, writer)
+	}
+	if config.Licenses {
+		writeLicensesSummary(h.licenses, writer)
+	}
+	if config.Todos {
+		writeTodos(h.todos, writer)
+	}
+	if config.TestMap {
+		writeTestMap(h.testMap, writer)
+	}
+
+	writer.writeContentsHeading()
+	writeOutlineNotice(writer)
+}
+
 func manageInstallation(isInstall bool) error {
+	if config.UserInstall {
+		return manageUserInstallation(isInstall)
+	}
 	if runtime.GOOS == "windows" {
 		return manageWindows(isInstall)
 	}
@@ -666,14 +1478,11 @@ func manageUnix(isInstall bool) error {
 		return nil
 	}
 
-	exePath, err := os.Executable()
+	realPath, cleanup, err := installSource()
 	if err != nil {
 		return err
 	}
-	realPath, err := filepath.EvalSymlinks(exePath)
-	if err != nil {
-		realPath = exePath
-	}
+	defer cleanup()
 
 	fmt.Printf("正在安装: %s -> %s\n", realPath, targetPath)
 
@@ -706,11 +1515,11 @@ func manageWindows(isInstall bool) error {
 	targetExe := filepath.Join(installDir, "dir2txt.exe")
 
 	if !isInstall {
+		removeInstallDirFromPath(installDir)
 		fmt.Printf("正在移除文件: %s\n", targetExe)
 		os.Remove(targetExe)
 		os.Remove(installDir)
 		fmt.Println("[SUCCESS] 文件已移除。")
-		fmt.Println("[WARNING]  注意: 为了安全起见，程序不会自动修改注册表。请手动从环境变量 PATH 中删除该路径。")
 		return nil
 	}
 
@@ -719,10 +1528,11 @@ func manageWindows(isInstall bool) error {
 		return fmt.Errorf("无法创建目录 (请以管理员身份运行): %v", err)
 	}
 
-	exePath, err := os.Executable()
+	exePath, cleanup, err := installSource()
 	if err != nil {
 		return err
 	}
+	defer cleanup()
 	srcFile, err := os.Open(exePath)
 	if err != nil {
 		return err
@@ -740,158 +1550,361 @@ func manageWindows(isInstall bool) error {
 	}
 	fmt.Println("[SUCCESS] 文件复制成功。")
 
-	fmt.Println("正在配置环境变量...")
+	addInstallDirToPath(installDir)
+	return nil
+}
 
-	psScript := fmt.Sprintf(`
-		$target = "%s"
-		$currentPath = [Environment]::GetEnvironmentVariable("Path", "User")
-		if ($currentPath -like "*$target*") {
-			Write-Host "环境变量已存在，跳过。"
-		} else {
-			$newPath = $currentPath + ";$target"
-			[Environment]::SetEnvironmentVariable("Path", $newPath, "User")
-			Write-Host "环境变量已更新。"
-		}
-	`, installDir)
+// processFile 读取文件并格式化写入 Markdown
+func processFile(path string, writer *docWriter) error {
+	return processFileAs(path, filepath.ToSlash(path), writer)
+}
 
-	cmd := exec.Command("powershell", "-Command", psScript)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		fmt.Printf("[WARNING] 环境变量自动设置失败: %v\n详情: %s\n请手动将 %s 添加到 PATH\n", err, string(output), installDir)
-	} else {
-		fmt.Print(string(output))
-		fmt.Println("安装完成！请重启终端以生效。")
+// processFileAs 与 processFile 相同，但使用 displayPath 作为标题中的路径
+func processFileAs(path string, displayPath string, writer *docWriter) error {
+	if fc := prepareFile(path, displayPath); fc != nil {
+		writeFileSection(fc, writer)
 	}
-
 	return nil
 }
 
-// processFile 读取文件并格式化写入 Markdown
-func processFile(path string, writer *bufio.Writer) error {
+// candidateFile 遍历阶段通过过滤的文件，内容在之后统一读取
+type candidateFile struct {
+	Path string // 文件系统路径
+	Rel  string // 以根目录名开头的逻辑相对路径 (正斜杠分隔)
+}
+
+// prepare 读取候选文件，应跳过时返回 nil
+func (c candidateFile) prepare() *fileContent {
+	fc := prepareFile(c.Path, filepath.ToSlash(c.Path))
+	if fc == nil {
+		progress.fileDone(0)
+		recordSkipped(c.Path)
+		return nil
+	}
+	progress.fileDone(fc.Size)
+	fc.RelPath = c.Rel
+	return fc
+}
+
+// fileContent 表示一个已读取并转换为 UTF-8、等待写入的文件
+type fileContent struct {
+	Path        string   // 文件系统路径
+	DisplayPath string   // 标题中显示的路径
+	RelPath     string   // 以根目录名开头的逻辑相对路径，--explode 等按此组织输出
+	Perms       string   // --show-perms 时的权限与属主
+	Tokens      int64    // Content 的估算 token 数
+	Spill       string   // 超出 --max-memory 时内容暂存的临时文件，此时 Content 为空
+	Reserved    int64    // holdContent 占用的 --max-memory 预算，releaseContent 归还后清零
+	Processor   string   // 转换内容的插件名，非空时 Content 为 Markdown，不再放入代码块
+	Summary     string   // --ai-summaries 生成的摘要
+	BOM         string   // 原文件的 BOM 类型 (如 UTF-8、UTF-16LE)，输出时已去除
+	Content     []byte   // UTF-8 内容
+	GrepRanges  string   // --grep-context 保留的行号区间
+	Note        string   // 附加说明，如截断提示
+	History     []string // --history 对应的最近提交
+	Size        int64
+	ModTime     time.Time
+}
+
+// prepareFile 读取文件并完成大小、二进制、编码与 --grep 检查，应跳过时返回 nil
+func prepareFile(path string, displayPath string) *fileContent {
 	// 1. 获取文件信息与大小检查
-	info, err := os.Stat(path)
+	info, err := statFile(path)
 	if err != nil {
+		logf("[WARN] 无法读取文件信息: %s (%v)\n", path, err)
+		stats.Warnings++
+		noteExclusion("无法读取")
 		return nil
 	}
 
 	// 软链接指向目录时跳过内容读取
 	if info.IsDir() {
-		fmt.Printf("[SKIP] 软链接指向目录: %s\n", path)
+		logf("[SKIP] 软链接指向目录: %s\n", path)
+		noteExclusion("软链接指向目录")
+		return nil
+	}
+	// 遍历时已排除特殊文件，这里防止文件在遍历之后被替换为命名管道
+	if kind := specialModeKind(info.Mode()); kind != "" {
+		reportSpecialFile(path, kind)
 		return nil
 	}
 	if info.Size() > config.MaxFileSize {
-		fmt.Printf("[SKIP] 大文件 (>1MB): %s\n", path)
+		logf("[SKIP] 大文件 (>1MB): %s\n", path)
+		noteOversized(path)
+		noteExclusion("大文件 (>1MB)")
 		return nil
 	}
-
-	// 2. 读取文件内容
-	content, err := os.ReadFile(path)
-	if err != nil {
+	if kind, ok := permExcluded(info); ok {
+		logf("[SKIP] 权限过滤 (%s): %s\n", kind, path)
+		noteExclusion("权限过滤 (" + kind + ")")
 		return nil
 	}
 
 	ext := strings.ToLower(filepath.Ext(path))
-	isForceText := config.TextExts[ext]
-
-	// 3. 二进制检查（非白名单才检查）
-	if !isForceText && isBinary(content) {
-		fmt.Printf("[SKIP] 检测到二进制文件: %s\n", path)
+	// --force-text / --force-binary 按路径指定，优先于按后缀的文本白名单
+	isForceText := matchPathPatterns(path, config.ForceText)
+	if !isForceText && matchPathPatterns(path, config.ForceBinary) {
+		logf("[SKIP] 指定为二进制文件 (--force-binary): %s\n", path)
+		noteExclusion("指定为二进制文件 (--force-binary)")
 		return nil
 	}
+	isForceText = isForceText || config.TextExts[ext]
+
+	// 2. 读取文件内容 (--cache 命中时直接使用上次转换好的结果)
+	// 匹配插件的文件由插件转换，结果不缓存
+	processor := matchProcessor(path)
+	stopRead := startStage("read")
+	var entry *cacheEntry
+	var charset string
+	cached := false
+	if processor == nil {
+		charset = editorconfigCharset(path)
+		entry, cached = loadCache(path, info, isForceText, charset)
+	}
+	if !cached {
+		content, err := readFile(path)
+		stopRead()
+		if err != nil && config.SkipUnreadable && isPermissionDenied(err) {
+			logf("[SKIP] 无读取权限: %s\n", path)
+			noteExclusion("无读取权限")
+			return nil
+		}
+		if err != nil {
+			logf("[WARN] 无法读取文件: %s (%v)\n", path, err)
+			stats.Warnings++
+			noteExclusion("无法读取")
+			return nil
+		}
 
-	// 4. 编码检测与转换
-	utf8Content, encoding, err := convertToUTF8(content)
-	if err != nil {
-		fmt.Printf("[WARN] 无法识别文件编码 (已跳过): %s\n", path)
-		fmt.Printf("       -> 原因: 内容非 UTF-8 且非 GBK，或包含非法字符。\n")
+		stopConvert := startStage("convert")
+		if processor != nil {
+			// 插件直接输出 Markdown，不做二进制与编码检查
+			rendered, err := processor.Process(path, content)
+			stopConvert()
+			if err != nil {
+				logf("[WARN] %v: %s\n", err, path)
+				stats.Warnings++
+				noteExclusion("插件处理失败")
+				return nil
+			}
+			entry = &cacheEntry{Encoding: "UTF-8", Content: rendered, Tokens: estimateTokens(rendered)}
+		} else {
+			// 3. 二进制检查（非白名单才检查）；.editorconfig 声明为 UTF-16 的文件不检查
+			entry = &cacheEntry{Binary: !isForceText && !isUTF16Charset(charset) && !hasUTF16BOM(content) && isBinary(content)}
+			if !entry.Binary {
+				// 4. 编码检测与转换 (优先使用 .editorconfig 声明的 charset)
+				utf8Content, encoding, err := decodeDeclared(content, charset)
+				if err != nil {
+					stopConvert()
+					logf("[WARN] 无法识别文件编码 (已跳过): %s\n", path)
+					logf("       -> 原因: 内容非 UTF-8 且非 GBK，或包含非法字符。可使用 --fallback-encoding windows-1252 尽力转换。\n")
+					stats.EncodingErrors++
+					noteExclusion("无法识别编码")
+					return nil
+				}
+				entry.Content, entry.Encoding, entry.Tokens = utf8Content, encoding, estimateTokens(utf8Content)
+			}
+			stopConvert()
+			storeCache(path, info, isForceText, charset, entry)
+		}
+	} else {
+		stopRead()
+	}
+	if entry.Binary {
+		logf("[SKIP] 检测到二进制文件: %s\n", path)
+		noteExclusion("二进制文件")
 		return nil
 	}
 
 	// 5. 如果发生了转码，发出通知
-	if encoding != "UTF-8" {
-		fmt.Printf("[INFO] 自动转换编码 [%s -> UTF-8]: %s\n", encoding, path)
+	if entry.Encoding != "UTF-8" {
+		logf("[INFO] 自动转换编码 [%s -> UTF-8]: %s\n", entry.Encoding, path)
+	}
+	utf8Content, tokens := entry.Content, entry.Tokens
+	noteGenerated(path, utf8Content)
+	if !config.KeepMinified && processor == nil && isMinified(path, utf8Content) {
+		logf("[SKIP] 压缩代码 (--keep-minified 可保留): %s\n", path)
+		noteExclusion("压缩代码 (--keep-minified)")
+		return nil
 	}
 
-	// 6. 写入 Markdown
-	fmt.Printf("正在处理: %s\n", path)
-
-	// 标准化路径分隔符
-	displayPath := filepath.ToSlash(path)
+	// .env 文件中的值通常是密钥，默认只保留变量名
+	if isEnvFile(filepath.Base(path)) {
+		switch config.EnvValues {
+		case "drop":
+			logf("[SKIP] 环境变量文件 (--env-values drop): %s\n", path)
+			noteExclusion("环境变量文件 (--env-values drop)")
+			return nil
+		case "mask":
+			utf8Content = maskEnvValues(utf8Content)
+			tokens = estimateTokens(utf8Content)
+		}
+	}
 
-	// 确定代码块语言标记
-	codeBlockLang := strings.TrimPrefix(ext, ".")
-	if codeBlockLang == "" {
-		codeBlockLang = "text"
+	if processor == nil {
+		utf8Content = sanitizeControl(displayPath, utf8Content)
+		tokens = estimateTokens(utf8Content)
 	}
 
-	writer.WriteString(fmt.Sprintf("## File: %s\n\n", displayPath))
-	writer.WriteString(fmt.Sprintf("```%s\n", codeBlockLang))
-	writer.Write(utf8Content)
+	// 6. 按 --grep 筛选内容
+	grepRanges := ""
+	if config.Grep != nil {
+		if !config.Grep.Match(utf8Content) {
+			noteExclusion("内容不匹配 --grep")
+			return nil
+		}
+		if config.GrepContext >= 0 {
+			utf8Content, grepRanges = grepRegions(utf8Content, config.Grep, config.GrepContext)
+			tokens = estimateTokens(utf8Content)
+		}
+	}
 
-	// 确保代码块如果没换行符结尾，手动补一个
-	if len(utf8Content) > 0 && utf8Content[len(utf8Content)-1] != '\n' {
-		writer.WriteString("\n")
+	if outlineMode && processor == nil {
+		utf8Content = fileOutline(utf8Content)
+		tokens = estimateTokens(utf8Content)
 	}
 
-	writer.WriteString("```\n\n")
-	writer.WriteString("---\n\n")
+	fc := &fileContent{
+		Path:        path,
+		DisplayPath: displayPath,
+		Content:     utf8Content,
+		Tokens:      tokens,
+		GrepRanges:  grepRanges,
+		History:     gitFileHistory(path, config.History),
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+		BOM:         bomNote(entry.Encoding),
+	}
+	if config.ShowPerms {
+		fc.Perms = formatPerms(info)
+	}
+	if processor != nil {
+		fc.Processor = processor.Name()
+	}
+	return fc
+}
 
-	return nil
+// writeFileSection 将准备好的文件写入 Markdown
+func writeFileSection(fc *fileContent, writer *docWriter) {
+	defer startStage("write")()
+	logf("正在处理: %s\n", fc.Path)
+	applyPIIScan(fc)
+	stats.Included++
+	recordIncluded(fc.Path, fc.Size)
+	recordWritten(fc)
+	if config.AISummaries {
+		// 在选定输出后才生成摘要，被 --max-files 或预算丢弃的文件不调用接口
+		fc.Summary = aiSummary(fc.DisplayPath, fc.data())
+	}
+	if config.Explode != "" {
+		writeExplodedFile(fc)
+		return
+	}
+	forEachFormat(writer, func(w *docWriter) {
+		w.WriteFile(fc, w)
+	})
 }
 
-// checkFilter 检查路径是否命中过滤规则，返回是否匹配以及命中的原始规则
-// 规则：
-// - dir 或 dir/ : 目录前缀匹配，目录本身和其子孙均命中
-// - dir/*       : 目录下的内容命中，目录本身不命中（保留空目录）
-// - glob        : 尝试匹配全路径或文件名
-// - ! 前缀      : 取反（豁免）
-func checkFilter(fullPath string, filters []string) (bool, string) {
-	if fullPath == "" {
-		return false, ""
+// renderFileSection 输出单个文件的标题、附加信息与代码块
+func renderFileSection(fc *fileContent, writer *docWriter) {
+	writer.WriteString(writer.subHeading(writer.fileSectionTitle(fc.DisplayPath, stats.Included)))
+	if fc.Perms != "" {
+		writer.WriteString(fmt.Sprintf("Permissions: %s\n\n", writer.inlineCode(fc.Perms)))
+	}
+	if fc.BOM != "" {
+		writer.WriteString(fmt.Sprintf("Encoding: %s with BOM (BOM stripped)\n\n", writer.inlineCode(fc.BOM)))
+	}
+	if fc.Summary != "" {
+		writer.WriteString(writer.blockQuote("Summary: " + obsidianText(fc.Summary)))
+	}
+	if len(fc.History) > 0 {
+		writer.WriteString("Recent commits:\n")
+		for _, c := range fc.History {
+			writer.WriteString(fmt.Sprintf("- %s\n", obsidianText(c)))
+		}
+		writer.WriteString("\n")
+	}
+	if fc.GrepRanges != "" {
+		writer.WriteString(fmt.Sprintf("Matched lines: %s\n\n", fc.GrepRanges))
+	}
+	if fc.Note != "" {
+		writer.WriteString(fc.Note + "\n\n")
+	}
+	if fc.Processor != "" {
+		writer.WriteString(fmt.Sprintf("Rendered by plugin %s.\n\n", writer.inlineCode(fc.Processor)))
+		content := fc.data()
+		writer.Write(content)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			writer.WriteString("\n")
+		}
+		writer.WriteString("\n")
+		writer.writeSeparator()
+		return
 	}
+	writeFence(writer, codeLang(fc.Path), fc.data())
+}
 
-	full := filepath.ToSlash(fullPath)
+// writeFence 将内容包裹在代码块中写入，并追加分隔线
+func writeFence(writer *docWriter, lang string, content []byte) {
+	writer.writeCodeBlock(lang, content)
+	writer.writeSeparator()
+}
 
-	for _, rule := range filters {
-		if rule == "" {
-			continue
-		}
+// codeLang 根据文件后缀确定代码块语言标记
+func codeLang(path string) string {
+	if lang, ok := fileLang(filepath.Base(path)); ok {
+		return lang
+	}
+	lang := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if lang == "" {
+		return "text"
+	}
+	return lang
+}
 
-		isNeg := strings.HasPrefix(rule, "!")
-		cleanRule := strings.TrimPrefix(rule, "!")
-		cleanRule = filepath.ToSlash(cleanRule)
+// matchRule 检查单条规则是否命中路径，返回是否命中以及规则是否为 ! 取反规则
+func matchRule(full string, rule string) (bool, bool) {
+	isNeg := strings.HasPrefix(rule, "!")
+	cleanRule := strings.TrimPrefix(rule, "!")
+	cleanRule = filepath.ToSlash(cleanRule)
 
-		matched := false
+	if strings.HasSuffix(cleanRule, "/*") {
+		parent := strings.TrimSuffix(cleanRule, "/*")
+		return parent != "" && strings.HasPrefix(full, parent+"/") && full != parent, isNeg
+	}
 
-		if strings.HasSuffix(cleanRule, "/*") {
-			parent := strings.TrimSuffix(cleanRule, "/*")
-			if parent != "" && strings.HasPrefix(full, parent+"/") && full != parent {
-				matched = true
-			}
-		} else {
-			cleanRule = strings.TrimSuffix(cleanRule, "/")
+	cleanRule = strings.TrimSuffix(cleanRule, "/")
+	if cleanRule != "" && (full == cleanRule || strings.HasPrefix(full, cleanRule+"/")) {
+		return true, isNeg
+	}
+	if m, _ := path.Match(cleanRule, full); m {
+		return true, isNeg
+	}
+	if m, _ := path.Match(cleanRule, filepath.Base(full)); m {
+		return true, isNeg
+	}
+	return false, isNeg
+}
 
-			if cleanRule != "" && (full == cleanRule || strings.HasPrefix(full, cleanRule+"/")) {
-				matched = true
-			} else {
-				if m, _ := path.Match(cleanRule, full); m {
-					matched = true
-				}
-				if m, _ := path.Match(cleanRule, filepath.Base(full)); m {
-					matched = true
-				}
+// matchPathPatterns 检查文件路径是否命中任一规则 (过滤规则语法)，规则可匹配路径的任意后缀，如 data/*.bin
+func matchPathPatterns(filePath string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	full := filepath.ToSlash(filePath)
+	for _, pattern := range patterns {
+		for suffix := full; ; {
+			if matched, isNeg := matchRule(suffix, pattern); matched && !isNeg {
+				return true
 			}
-		}
-
-		if matched {
-			if isNeg {
-				return false, rule
+			i := strings.Index(suffix, "/")
+			if i < 0 {
+				break
 			}
-			return true, rule
+			suffix = suffix[i+1:]
 		}
 	}
-
-	return false, ""
+	return false
 }
 
 // isJunk 检查是否为"垃圾"文件/目录 (不应该出现在任何地方)
@@ -902,18 +1915,18 @@ func isJunk(name string) bool {
 		return false
 	}
 
-	// 特例：保留 .env 和 .gitignore，虽然它们以点开头，但通常很重要
-	if name == ".env" || name == ".gitignore" {
+	// --keep 指定的文件名优先于所有忽略规则
+	if matchNameRule(name, config.KeepFiles) {
 		return false
 	}
 
 	// 1. 检查特定文件名忽略列表 (如 code2md.exe) - 这里是完全隐藏
-	if config.IgnoredFiles[name] {
+	if matchNameRule(name, config.IgnoredFiles) {
 		return true
 	}
 
-	// 2. 忽略隐藏文件/目录 (以 . 开头)
-	if strings.HasPrefix(name, ".") {
+	// 2. 隐藏文件/目录 (以 . 开头) 按策略处理，白名单 (如 .env、.github) 始终保留
+	if config.HiddenPolicy == "exclude" && isHidden(name) && !matchNameRule(name, config.HiddenAllow) {
 		return true
 	}
 
@@ -925,9 +1938,34 @@ func isJunk(name string) bool {
 	return false
 }
 
+// isHidden 检查是否为隐藏文件/目录 (以 . 开头)
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// isHiddenTreeOnly 检查隐藏项在 tree-only 策略下是否只出现在目录树中而不读取内容
+func isHiddenTreeOnly(name string) bool {
+	return config.HiddenPolicy == "tree-only" && isHidden(name) &&
+		!matchNameRule(name, config.HiddenAllow) && !matchNameRule(name, config.KeepFiles)
+}
+
 // isAsset 检查是否为"资源"文件 (应该出现在目录树中，但不读取内容)
 // 例如: 图片, 普通可执行文件
 func isAsset(name string) bool {
+	if matchNameRule(name, config.KeepFiles) {
+		return false
+	}
+
+	// 检查特定文件名 (如 go.sum, package-lock.json)
+	if matchNameRule(name, config.AssetFiles) {
+		return true
+	}
+
+	// 带内容哈希的打包产物 (如 app.3f9c2a.js)
+	if config.HashedAssets && isHashedAsset(name) {
+		return true
+	}
+
 	// 检查文件扩展名 (如 .png, .exe)
 	ext := strings.ToLower(filepath.Ext(name))
 	if config.IgnoredExts[ext] {
@@ -936,59 +1974,127 @@ func isAsset(name string) bool {
 	return false
 }
 
+// matchNameRule 检查文件名是否命中规则表：先精确匹配，再尝试含通配符的规则
+func matchNameRule(name string, rules map[string]bool) bool {
+	if rules[name] {
+		return true
+	}
+	for rule, enabled := range rules {
+		if !enabled || !strings.ContainsAny(rule, "*?[") {
+			continue
+		}
+		if m, _ := path.Match(rule, name); m {
+			return true
+		}
+	}
+	return false
+}
+
 // isBinary 通过检查内容中是否包含 NUL 字节来简单判断是否为二进制文件
 func isBinary(content []byte) bool {
-	checkLen := 512
+	checkLen := config.BinaryWindow
 	if len(content) < checkLen {
 		checkLen = len(content)
 	}
+	window := content[:checkLen]
+
+	// 没有 BOM 的 UTF-16 文本每隔一个字节就是 NUL，不能按二进制处理
+	if _, ok := detectUTF16(window); ok {
+		return false
+	}
 
 	// 真正的二进制文件通常包含 NUL 字节
-	if bytes.IndexByte(content[:checkLen], 0) != -1 {
+	if bytes.IndexByte(window, 0) != -1 {
 		return true
 	}
 
-	return false
+	// 不可打印字节 (制表、换行、ESC 等常见控制字符除外) 占比超过 --binary-threshold
+	nonPrintable := 0
+	for _, b := range window {
+		if (b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\b' && b != 0x1b) || b == 0x7f {
+			nonPrintable++
+		}
+	}
+	return checkLen > 0 && float64(nonPrintable)*100/float64(checkLen) > config.BinaryThreshold
 }
 
 // convertToUTF8 尝试将内容转换为 UTF-8
 // 返回: (转换后的内容, 原始编码名称, error)
 func convertToUTF8(content []byte) ([]byte, string, error) {
+	// 0. 带 BOM 的 UTF-8 / UTF-16：去除 BOM 并转换，避免 BOM 混入代码块
+	if decoded, encoding, ok := decodeBOM(content); ok && utf8.Valid(decoded) {
+		return decoded, encoding, nil
+	}
+
+	// 没有 BOM 的 UTF-16 (按 NUL 字节的位置判断字节序)
+	if order, ok := detectUTF16(content); ok {
+		if decoded, err := unicode.UTF16(order, unicode.IgnoreBOM).NewDecoder().Bytes(content); err == nil && utf8.Valid(decoded) {
+			name := "UTF-16LE"
+			if order == unicode.BigEndian {
+				name = "UTF-16BE"
+			}
+			return decoded, name, nil
+		}
+	}
+
 	// 1. 先尝试 UTF-8 校验
 	if utf8.Valid(content) {
 		return content, "UTF-8", nil
 	}
 
 	// 2. 尝试 GBK / GB18030 解码
+	// GBK 解码器会把非法字节替换为 U+FFFD 而不报错；指定了 --fallback-encoding 时出现替换字符视为解码失败
 	reader := transform.NewReader(bytes.NewReader(content), simplifiedchinese.GBK.NewDecoder())
 	decoded, err := io.ReadAll(reader)
 	if err == nil {
-		if utf8.Valid(decoded) {
+		if utf8.Valid(decoded) && (config.FallbackEncoding == "" || !bytes.ContainsRune(decoded, utf8.RuneError) || bytes.Contains(content, []byte("\uFFFD"))) {
 			return decoded, "GBK/GB18030", nil
 		}
 	}
 
-	// 3. 其他编码可在此扩展
+	// 3. --fallback-encoding: 按单字节西欧编码解码，任何字节序列都能转换
+	if fallback, ok := fallbackEncodings[config.FallbackEncoding]; ok {
+		decoded, err := fallback.enc.NewDecoder().Bytes(content)
+		if err == nil {
+			return decoded, fallback.name + " (fallback)", nil
+		}
+	}
+
+	// 4. 其他编码可在此扩展
 	return nil, "Unknown", fmt.Errorf("encoding not recognized")
 }
 
-// writeTree 生成简单的 ASCII 目录树，支持文件折叠，跟随符号链接目录但使用逻辑路径做过滤
-func writeTree(rootFS string, rootLogical string, currentFS string, currentLogical string, prefix string, w *bufio.Writer, hardFilters []string, seen map[string]bool) error {
-	entries, err := os.ReadDir(currentFS)
+// buildTree 生成 fsys 中 currentFS 之下的目录树节点，支持文件折叠，使用逻辑路径做过滤。
+// 本地目录 (localFS) 跟随符号链接目录，并标注挂载点、目录环、特殊文件与硬链接；其他 fs.FS 只列出条目
+func buildTree(ctx context.Context, fsys fs.FS, rootLogical string, currentFS string, currentLogical string, hardFilters *FilterSet) ([]*treeNode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	local, isLocal := fsys.(*localFS)
+	join := path.Join
+	var entries []fs.DirEntry
+	var err error
+	if isLocal {
+		join = filepath.Join
+		entries, err = local.readDir(currentFS)
+	} else {
+		entries, err = fs.ReadDir(fsys, currentFS)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// 过滤掉忽略的项
-	var visibleEntries []os.DirEntry
+	var visibleEntries []fs.DirEntry
+	filtered := map[string]bool{}
 	for _, entry := range entries {
 		name := entry.Name()
 		logicalPath := filepath.Join(currentLogical, name)
 		rel, _ := filepath.Rel(rootLogical, logicalPath)
 		relSlash := filepath.ToSlash(rel)
 
-		// 排除输出文件自身
-		if name == config.OutputFile {
+		// 排除输出文件自身及其备份，以及之前在同一输出目录生成的文件
+		if name == config.OutputFile || name == config.OutputFile+".bak" || (isLocal && isPriorOutput(join(currentFS, name))) {
 			continue
 		}
 
@@ -998,11 +2104,20 @@ func writeTree(rootFS string, rootLogical string, currentFS string, currentLogic
 			continue
 		}
 
-		// 过滤表达式处理（对目录树也生效，仅使用 hardFilters）
+		// 过滤表达式处理（对目录树也生效，仅使用 hardFilters）；含 .dir2txt-skip 的目录同样视为硬过滤
 		if relSlash != "" {
-			matched, _ := checkFilter(relSlash, hardFilters)
+			hard := hardFilters.Match(relSlash)
+			markRuleUsed(hard.Rule)
+			matched := hard.Matched
+			if !matched && isLocal && (entry.IsDir() || entry.Type()&os.ModeSymlink != 0) {
+				matched = dirMarker(join(currentFS, name)) == skipMarker
+			}
 			if matched {
-				// 目录层保留，但被匹配的子节点会被隐藏
+				// 目录层保留，但被匹配的子节点会被隐藏；--tree-mark-filtered 时只标注而不展开
+				if config.TreeMarkFiltered {
+					filtered[name] = true
+					visibleEntries = append(visibleEntries, entry)
+				}
 				continue
 			}
 		}
@@ -1010,77 +2125,86 @@ func writeTree(rootFS string, rootLogical string, currentFS string, currentLogic
 		visibleEntries = append(visibleEntries, entry)
 	}
 
-	// 分离目录与文件，文件过多时折叠
-	var dirs []os.DirEntry
-	var files []os.DirEntry
-	for _, e := range visibleEntries {
-		if e.IsDir() {
-			dirs = append(dirs, e)
+	// 分离目录与文件，按 --tree-order 排列，数量过多时折叠 (被折叠的目录不再展开)
+	var dirs []*treeNode
+	var files []*treeNode
+	var mixed []*treeNode
+	for _, entry := range visibleEntries {
+		node := &treeNode{Name: entry.Name(), Filtered: filtered[entry.Name()], fsPath: join(currentFS, entry.Name()), logical: filepath.Join(currentLogical, entry.Name())}
+		if !isLocal {
+			node.IsDir = entry.IsDir()
+			if node.IsDir {
+				dirs = append(dirs, node)
+			} else {
+				files = append(files, node)
+			}
+			mixed = append(mixed, node)
+			continue
+		}
+		if entry.Type()&os.ModeSymlink != 0 {
+			if target, err := os.Readlink(node.fsPath); err == nil {
+				node.Target = target
+			}
+			if target, err := filepath.EvalSymlinks(node.fsPath); err == nil {
+				if info, err := os.Stat(target); err == nil && info.IsDir() {
+					node.IsDir = true
+					node.fsPath = target
+				}
+			}
+		}
+		if !node.IsDir {
+			node.Special = specialFileKind(node.fsPath, entry)
+			node.HardlinkOf = hardlinkOf[node.fsPath]
+		}
+		if config.SkipUnreadable && !entry.IsDir() && node.Special == "" && !isReadable(node.fsPath) {
+			node.Denied = true
+		}
+		if entry.IsDir() {
+			node.IsDir = true
+			dirs = append(dirs, node)
 		} else {
-			files = append(files, e)
+			files = append(files, node)
 		}
+		mixed = append(mixed, node)
 	}
 
-	if !config.NoFold && len(files) > maxDisplayFiles {
-		display := make([]os.DirEntry, 0, keepHeadFiles+keepTailFiles+1)
-		display = append(display, files[:keepHeadFiles]...)
-		hiddenCount := len(files) - keepHeadFiles - keepTailFiles
-		if hiddenCount < 0 {
-			hiddenCount = 0
-		}
-		display = append(display, &SimpleDirEntry{name: fmt.Sprintf("... (%d files hidden) ...", hiddenCount)})
-		display = append(display, files[len(files)-keepTailFiles:]...)
-		files = display
+	var nodes []*treeNode
+	switch config.TreeOrder {
+	case "mixed":
+		nodes = foldTreeNodes(mixed)
+	case "files-first":
+		nodes = append(foldTreeNodes(files), foldTreeNodes(dirs)...)
+	default:
+		nodes = append(foldTreeNodes(dirs), foldTreeNodes(files)...)
 	}
 
-	finalEntries := make([]os.DirEntry, 0, len(dirs)+len(files))
-	finalEntries = append(finalEntries, dirs...)
-	finalEntries = append(finalEntries, files...)
-
-	for i, entry := range finalEntries {
-		isLast := i == len(finalEntries)-1
-
-		marker := "├── "
-		if isLast {
-			marker = "└── "
+	for _, node := range nodes {
+		if !node.IsDir || node.Note || node.Filtered {
+			continue
 		}
-
-		displayName := entry.Name()
-		if entry.Type()&os.ModeSymlink != 0 {
-			fullPath := filepath.Join(currentFS, entry.Name())
-			if target, err := os.Readlink(fullPath); err == nil {
-				displayName = fmt.Sprintf("%s -> %s", displayName, target)
+		if isLocal {
+			if crossesFileSystem(local.root, node.fsPath, node.logical) {
+				node.Children = []*treeNode{noteNode("(mount point, not crossed)")}
+				continue
 			}
-		}
-
-		w.WriteString(prefix + marker + displayName + "\n")
-
-		childPathFS := filepath.Join(currentFS, entry.Name())
-		childPathLogical := filepath.Join(currentLogical, entry.Name())
-		childIsDir := entry.IsDir()
-		if entry.Type()&os.ModeSymlink != 0 {
-			if target, err := filepath.EvalSymlinks(childPathFS); err == nil {
-				if info, err := os.Stat(target); err == nil && info.IsDir() {
-					childIsDir = true
-					childPathFS = target
-				}
+			if nestedRoots[node.fsPath] {
+				node.Children = []*treeNode{noteNode("(listed as a separate root)")}
+				continue
 			}
-		}
-
-		if childIsDir {
-			real, err := filepath.EvalSymlinks(childPathFS)
-			if err == nil {
-				if seen[real] {
+			// 重复访问的目录不再展开，显示说明节点以区别于空目录
+			if id, ok := dirIdentity(node.fsPath); ok {
+				if first, dup := local.seen[id]; dup {
+					node.Children = []*treeNode{noteNode(loopMarker(node.logical, first))}
 					continue
 				}
-				seen[real] = true
+				local.seen[id] = node.logical
 			}
-			newPrefix := prefix + "│   "
-			if isLast {
-				newPrefix = prefix + "    "
-			}
-			writeTree(rootFS, rootLogical, childPathFS, childPathLogical, newPrefix, w, hardFilters, seen)
+		}
+		children, err := buildTree(ctx, fsys, rootLogical, node.fsPath, node.logical, hardFilters)
+		node.Children = children
+		if isPermissionDenied(err) {
+			node.Children = []*treeNode{noteNode("(permission denied)")}
 		}
 	}
-	return nil
+	return nodes, nil
 }
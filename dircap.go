@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// capFilesPerDir 每个目录最多保留 --max-files-per-dir 个文件的内容，按重要性评分 (入口文件、最近修改、小文件优先) 选择，
+// 其余文件在末尾的省略附录中按目录汇总
+func capFilesPerDir(candidates []candidateFile, limit int) []candidateFile {
+	groups := map[string][]int{}
+	var order []string
+	for i, c := range candidates {
+		dir := path.Dir(c.Rel)
+		if _, ok := groups[dir]; !ok {
+			order = append(order, dir)
+		}
+		groups[dir] = append(groups[dir], i)
+	}
+
+	drop := map[int]bool{}
+	for _, dir := range order {
+		indexes := groups[dir]
+		if len(indexes) <= limit {
+			continue
+		}
+		// 只用文件信息评分，不读取内容
+		files := make([]*fileContent, len(indexes))
+		byFile := map[*fileContent]int{}
+		for j, idx := range indexes {
+			fc := &fileContent{Path: candidates[idx].Path}
+			if info, err := os.Stat(fc.Path); err == nil {
+				fc.Size, fc.ModTime = info.Size(), info.ModTime()
+			}
+			files[j] = fc
+			byFile[fc] = idx
+		}
+		ranked := rankFiles(files, scoreFiles(files))
+		var size int64
+		for _, fc := range ranked[limit:] {
+			drop[byFile[fc]] = true
+			size += fc.Size
+			noteExcluded(fc.Path, filepath.ToSlash(fc.Path))
+			noteExclusion("超出每目录文件上限 (--max-files-per-dir)")
+			recordSkipped(fc.Path)
+		}
+		dropped := len(indexes) - limit
+		logf("[SKIP] 目录 %s 中有 %d 个文件，按 --max-files-per-dir 只输出 %d 个\n", dir, len(indexes), limit)
+		omitted = append(omitted, omittedFile{
+			DisplayPath: fmt.Sprintf("%s/ (%d files)", dir, dropped),
+			Tokens:      (size + 3) / 4,
+			Reason:      fmt.Sprintf("omitted (--max-files-per-dir %d)", limit),
+		})
+	}
+	if len(drop) == 0 {
+		return candidates
+	}
+
+	kept := make([]candidateFile, 0, len(candidates)-len(drop))
+	for i, c := range candidates {
+		if !drop[i] {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// limitFileCount 按重要性排名只保留前 limit 个文件，返回过滤后的原顺序列表与排名列表，
+// 其余文件记入省略附录
+func limitFileCount(files []*fileContent, ranked []*fileContent, limit int) ([]*fileContent, []*fileContent) {
+	if len(ranked) <= limit {
+		return files, ranked
+	}
+	keep := map[*fileContent]bool{}
+	for _, fc := range ranked[:limit] {
+		keep[fc] = true
+	}
+	var kept []*fileContent
+	for _, fc := range files {
+		if keep[fc] {
+			kept = append(kept, fc)
+			continue
+		}
+		logf("[SKIP] 超出 --max-files %d，不输出: %s\n", limit, fc.DisplayPath)
+		omitted = append(omitted, omittedFile{fc.DisplayPath, fc.Tokens, fmt.Sprintf("dropped (--max-files %d)", limit)})
+		noteExcluded(fc.Path, fc.DisplayPath)
+		noteExclusion("超出 --max-files")
+		recordSkipped(fc.Path)
+		releaseContent(fc)
+	}
+	return kept, ranked[:limit]
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// hasGlobMeta 参数中是否含有通配符
+func hasGlobMeta(arg string) bool {
+	return strings.ContainsAny(arg, "*?[")
+}
+
+// globDirs 展开目录模式，返回匹配到的目录 (排序)，以及是否所有匹配项都是目录
+func globDirs(pattern string) ([]string, bool) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, false
+	}
+	var dirs []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			dirs = append(dirs, match)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, len(dirs) == len(matches)
+}
+
+// isDirGlob 含通配符的位置参数是否应视为目录模式：带路径分隔符 (如 services/*/api) 且匹配到的全部是目录；
+// 否则仍按软过滤规则处理 (如 *.png、src/*.go)。只在没有给出其他目录时调用，见 parseCommandLine
+func isDirGlob(arg string) bool {
+	if !hasGlobMeta(arg) || !strings.ContainsAny(arg, "/"+string(filepath.Separator)) {
+		return false
+	}
+	dirs, onlyDirs := globDirs(arg)
+	return len(dirs) > 0 && onlyDirs
+}
+
+// expandDirGlobs 由程序自己展开目录参数中的通配符 (Windows 的 shell 不会展开)，每个匹配的目录作为一个根目录；
+// 按字面存在的目录 (名称中恰好含有 [ 等字符) 不展开
+func expandDirGlobs(dirs []string) ([]string, error) {
+	var result []string
+	for _, dir := range dirs {
+		if !hasGlobMeta(dir) {
+			result = append(result, dir)
+			continue
+		}
+		if _, err := os.Stat(dir); err == nil {
+			result = append(result, dir)
+			continue
+		}
+		matches, _ := globDirs(dir)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("目录模式没有匹配到任何目录: %s", dir)
+		}
+		logf("目录模式 %s 匹配 %d 个目录: %s\n", dir, len(matches), strings.Join(matches, ", "))
+		result = append(result, matches...)
+	}
+	return result, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestPositionalDirGlob 含通配符的位置参数与目录一起给出时仍是软过滤，单独给出时才展开为目录
+func TestPositionalDirGlob(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"vendor/a", "vendor/b", "services/x/api", "services/y/api"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(root)
+
+	tests := []struct {
+		args    []string
+		dirs    []string
+		filters []string
+	}{
+		{args: []string{".", "vendor/*"}, dirs: []string{"."}, filters: []string{"vendor/*"}},
+		{args: []string{"--dir=.", "vendor/*"}, dirs: []string{"."}, filters: []string{"vendor/*"}},
+		{args: []string{"services/*/api"}, dirs: []string{"services/*/api"}},
+		{args: []string{"services/*/api", "!services/x/api/keep"}, dirs: []string{"services/*/api"}, filters: []string{"!services/x/api/keep"}},
+		{args: []string{"*.go"}, filters: []string{"*.go"}},
+	}
+	for _, tt := range tests {
+		dirs, softFilters, _, _, _, _, _, err := parseCommandLine(tt.args)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if !slices.Equal([]string(dirs), tt.dirs) || !slices.Equal([]string(softFilters), tt.filters) {
+			t.Errorf("%v: dirs %q filters %q, want dirs %q filters %q", tt.args, dirs, softFilters, tt.dirs, tt.filters)
+		}
+	}
+}
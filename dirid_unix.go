@@ -0,0 +1,50 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// dirIdentity 返回目录的唯一标识 (设备号+inode)，可识别符号链接、bind mount 与目录硬链接等指向同一目录的多条路径
+func dirIdentity(path string) (string, bool) {
+	id, _, ok := fileIdentity(path)
+	return id, ok
+}
+
+// fileIdentity 返回文件的唯一标识 (设备号+inode) 与硬链接数
+func fileIdentity(path string) (string, uint64, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", 0, false
+	}
+	return fmt.Sprintf("%d:%d", st.Dev, st.Ino), uint64(st.Nlink), true
+}
+
+// dirDevice 返回路径所在文件系统的设备号
+func dirDevice(path string) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprint(st.Dev), true
+}
+
+// fileOwner 返回文件的属主 (uid:gid)
+func fileOwner(info os.FileInfo) string {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", st.Uid, st.Gid)
+}
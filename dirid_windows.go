@@ -0,0 +1,57 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// dirIdentity 返回目录的唯一标识 (卷序列号+文件 ID)；无法打开目录时使用解析符号链接/junction 后的真实路径
+func dirIdentity(path string) (string, bool) {
+	if id, _, ok := fileIdentity(path); ok {
+		return id, true
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false
+	}
+	return real, true
+}
+
+// fileIdentity 返回文件的唯一标识 (卷序列号+文件 ID) 与硬链接数
+func fileIdentity(path string) (string, uint64, bool) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", 0, false
+	}
+	// 不请求任何访问权限，只读取文件信息；FILE_FLAG_BACKUP_SEMANTICS 允许打开目录
+	h, err := windows.CreateFile(p, 0, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return "", 0, false
+	}
+	defer windows.CloseHandle(h)
+	var info windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &info); err != nil {
+		return "", 0, false
+	}
+	return fmt.Sprintf("%x:%x:%x", info.VolumeSerialNumber, info.FileIndexHigh, info.FileIndexLow), uint64(info.NumberOfLinks), true
+}
+
+// dirDevice 返回路径所在的卷 (盘符或 UNC 共享)；卷内挂载的文件夹无法识别
+func dirDevice(path string) (string, bool) {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false
+	}
+	return strings.ToLower(filepath.VolumeName(real)), true
+}
+
+// fileOwner Windows 下不提供 uid/gid
+func fileOwner(info os.FileInfo) string {
+	return ""
+}
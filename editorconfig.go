@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// editorConfigSection .editorconfig 中的一个节，只关心 charset
+type editorConfigSection struct {
+	pattern *regexp.Regexp
+	charset string
+}
+
+// editorConfigFile 解析后的 .editorconfig
+type editorConfigFile struct {
+	root     bool
+	sections []editorConfigSection
+}
+
+// editorConfigs 按目录缓存解析结果，nil 表示该目录没有 .editorconfig
+var editorConfigs = map[string]*editorConfigFile{}
+
+// editorconfigCharset 返回 .editorconfig 为文件声明的 charset (小写)，未声明时返回空。
+// 与 EditorConfig 规范一致：从文件所在目录向上查找，遇到 root = true 停止，越近的文件、越靠后的节优先
+func editorconfigCharset(path string) string {
+	if config.NoEditorconfig {
+		return ""
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	charset := ""
+	for dir := filepath.Dir(absPath); ; {
+		if ec := loadEditorConfig(dir); ec != nil {
+			rel, _ := filepath.Rel(dir, absPath)
+			rel = filepath.ToSlash(rel)
+			// 越靠近文件的配置优先，已确定时不再被上层覆盖
+			if charset == "" {
+				for _, s := range ec.sections {
+					if s.charset != "" && s.pattern.MatchString(rel) {
+						charset = s.charset
+					}
+				}
+			}
+			if ec.root {
+				break
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	if charset == "unset" {
+		return ""
+	}
+	return charset
+}
+
+// loadEditorConfig 读取并缓存目录中的 .editorconfig
+func loadEditorConfig(dir string) *editorConfigFile {
+	if ec, ok := editorConfigs[dir]; ok {
+		return ec
+	}
+	f, err := os.Open(filepath.Join(dir, ".editorconfig"))
+	if err != nil {
+		editorConfigs[dir] = nil
+		return nil
+	}
+	defer f.Close()
+
+	ec := &editorConfigFile{}
+	var current *editorConfigSection
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			re, err := editorConfigGlob(line[1 : len(line)-1])
+			if err != nil {
+				current = nil
+				continue
+			}
+			ec.sections = append(ec.sections, editorConfigSection{pattern: re})
+			current = &ec.sections[len(ec.sections)-1]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+		switch {
+		case current == nil && key == "root":
+			ec.root = value == "true"
+		case current != nil && key == "charset":
+			current.charset = value
+		}
+	}
+	editorConfigs[dir] = ec
+	return ec
+}
+
+// editorConfigGlob 将 EditorConfig 的节名转换为正则：
+// 不含 / 的模式匹配任意层级的文件名；* 不跨目录，** 跨目录，{a,b} 为多选
+func editorConfigGlob(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	if !strings.Contains(glob, "/") {
+		sb.WriteString("^(?:.*/)?")
+	} else {
+		sb.WriteString("^")
+		glob = strings.TrimPrefix(glob, "/")
+	}
+	depth := 0
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '{':
+			depth++
+			sb.WriteString("(?:")
+		case c == '}' && depth > 0:
+			depth--
+			sb.WriteString(")")
+		case c == ',' && depth > 0:
+			sb.WriteString("|")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// charsetEncodings .editorconfig 中 charset 取值对应的解码器
+var charsetEncodings = map[string]struct {
+	name string
+	enc  encoding.Encoding
+}{
+	"latin1":   {"ISO-8859-1", charmap.ISO8859_1},
+	"utf-16be": {"UTF-16BE", unicode.UTF16(unicode.BigEndian, unicode.UseBOM)},
+	"utf-16le": {"UTF-16LE", unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)},
+}
+
+// fallbackEncodings --fallback-encoding 支持的单字节编码，解码不会失败
+var fallbackEncodings = map[string]struct {
+	name string
+	enc  encoding.Encoding
+}{
+	"windows-1252": {"Windows-1252", charmap.Windows1252},
+	"latin1":       {"ISO-8859-1", charmap.ISO8859_1},
+}
+
+// isUTF16Charset 声明为 UTF-16 的文件含有大量 NUL 字节，不能按二进制检测
+func isUTF16Charset(charset string) bool {
+	return strings.HasPrefix(charset, "utf-16")
+}
+
+// decodeDeclared 按声明的 charset 解码；utf-8/utf-8-bom 与未知取值交给 convertToUTF8 自动检测
+func decodeDeclared(content []byte, charset string) ([]byte, string, error) {
+	decl, ok := charsetEncodings[charset]
+	if !ok {
+		return convertToUTF8(content)
+	}
+	decoded, err := decl.enc.NewDecoder().Bytes(content)
+	if err != nil || !utf8.Valid(decoded) {
+		return convertToUTF8(content)
+	}
+	// 声明为 latin1 但内容本身是合法的 UTF-8 (如纯 ASCII) 时不转换
+	if charset == "latin1" && utf8.Valid(content) {
+		return content, "UTF-8", nil
+	}
+	return bytes.TrimPrefix(decoded, []byte("\uFEFF")), fmt.Sprintf("%s (.editorconfig)", decl.name), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// observed 本次运行中实际触发的排除，用于 --emit-filter-file 生成可复用的过滤规则
+var observed = struct {
+	builtin   map[string]bool // 内置规则排除的名称或 *.ext
+	oversized map[string]bool // 超过大小上限的文件 (绝对路径)
+	generated map[string]bool // 检测到的生成代码 (绝对路径)
+}{map[string]bool{}, map[string]bool{}, map[string]bool{}}
+
+// generatedMarkers 文件开头出现这些文本 (小写比较) 时视为生成代码
+var generatedMarkers = []string{"code generated", "do not edit", "@generated", "auto-generated", "autogenerated", "automatically generated"}
+
+// noteBuiltinExclusion 记录被内置规则 (忽略目录、后缀、文件名等) 排除的名称
+func noteBuiltinExclusion(name string, isAssetExt bool) {
+	if config.EmitFilterFile == "" {
+		return
+	}
+	if ext := strings.ToLower(filepath.Ext(name)); isAssetExt && ext != "" {
+		name = "*" + ext
+	}
+	observed.builtin[name] = true
+}
+
+// noteOversized 记录超过大小上限的文件
+func noteOversized(path string) {
+	if config.EmitFilterFile != "" {
+		observed.oversized[path] = true
+	}
+}
+
+// noteGenerated 检查文件开头的生成代码标记
+func noteGenerated(path string, content []byte) {
+	if config.EmitFilterFile == "" {
+		return
+	}
+	head := content
+	for i, n := 0, 0; i < len(head); i++ {
+		if head[i] == '\n' {
+			if n++; n == 10 {
+				head = head[:i]
+				break
+			}
+		}
+	}
+	head = bytes.ToLower(head)
+	for _, marker := range generatedMarkers {
+		if bytes.Contains(head, []byte(marker)) {
+			observed.generated[path] = true
+			return
+		}
+	}
+}
+
+// relToRoots 将绝对路径转换为相对所在根目录的过滤规则
+func relToRoots(paths map[string]bool, dirs []string) []string {
+	var rules []string
+	for p := range paths {
+		rule := filepath.ToSlash(p)
+		for _, dir := range dirs {
+			absDir, err := filepath.Abs(dir)
+			if err != nil {
+				continue
+			}
+			if rel, err := filepath.Rel(absDir, p); err == nil && !strings.HasPrefix(rel, "..") {
+				rule = filepath.ToSlash(rel)
+				break
+			}
+		}
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+// writeFilterFile 将本次运行中生效的排除写成 .gitignore 风格的过滤文件，可通过 -c / -Fc 复用
+func writeFilterFile(path string, dirs []string, softFilters, hardFilters []string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# dir2txt 过滤规则建议，由 --emit-filter-file 根据 %s 的运行生成\n", time.Now().Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "# 用法: dir2txt -c %s (软过滤) 或 -Fc %s (硬过滤)；请按需删改\n", filepath.Base(path), filepath.Base(path))
+	section := func(title string, rules []string) {
+		if len(rules) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n# %s\n", title)
+		for _, rule := range rules {
+			b.WriteString(rule + "\n")
+		}
+	}
+
+	var matched []string
+	for _, rule := range append(append([]string{}, softFilters...), hardFilters...) {
+		if usedRules[rule] {
+			matched = append(matched, rule)
+		}
+	}
+	section("本次运行中命中的过滤规则", matched)
+
+	var builtin []string
+	for name := range observed.builtin {
+		builtin = append(builtin, name)
+	}
+	sort.Strings(builtin)
+	section("内置规则排除的目录与文件", builtin)
+	section(fmt.Sprintf("超过大小上限 (%s) 的文件", formatSize(config.MaxFileSize)), relToRoots(observed.oversized, dirs))
+	section("检测到的生成代码", relToRoots(observed.generated, dirs))
+
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// envAssignRe 匹配 dotenv 风格的赋值行：可选的 export 前缀、变量名、等号与值
+var envAssignRe = regexp.MustCompile(`^(\s*(?:export\s+)?[A-Za-z_][A-Za-z0-9_.-]*\s*=)(.*)$`)
+
+// isEnvFile 判断是否为 .env 风格的环境变量文件；.example/.sample/.template 等示例文件不含真实的值
+func isEnvFile(name string) bool {
+	lower := strings.ToLower(name)
+	if !(lower == ".env" || strings.HasPrefix(lower, ".env.") || strings.HasSuffix(lower, ".env")) {
+		return false
+	}
+	for _, suffix := range []string{".example", ".sample", ".template", ".dist"} {
+		if strings.HasSuffix(lower, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// maskEnvValues 保留变量名与注释，将非空的值替换为 ***
+func maskEnvValues(content []byte) []byte {
+	lines := bytes.Split(content, []byte("\n"))
+	for i, line := range lines {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 || trimmed[0] == '#' {
+			continue
+		}
+		m := envAssignRe.FindSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if value := bytes.TrimSpace(m[2]); len(value) > 0 {
+			lines[i] = append(append([]byte{}, m[1]...), "***"...)
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
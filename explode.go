@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// explodedFiles 记录 --explode 模式下已写出的文件 (相对输出目录的路径)，用于生成索引
+var explodedFiles []string
+
+// writeExplodedFile 将单个文件写入拆分输出目录，保持原有目录结构
+func writeExplodedFile(fc *fileContent) {
+	rel := sanitizeRelPath(config.Explode, fc.RelPath+config.ExplodeExt)
+	if rel != fc.RelPath+config.ExplodeExt {
+		renamedFiles = append(renamedFiles, renamedFile{fc.RelPath, rel})
+	}
+	target := filepath.Join(config.Explode, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		logf("[WARN] 无法创建目录: %s (%v)\n", filepath.Dir(target), err)
+		stats.Warnings++
+		return
+	}
+	f, err := os.Create(target)
+	if err != nil {
+		logf("[WARN] 无法写入文件: %s (%v)\n", target, err)
+		stats.Warnings++
+		return
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if config.ExplodeExt == ".txt" {
+		w.Write(fc.data())
+	} else {
+		if obsidianFlavor() {
+			writeFrontMatter(fc, w)
+		}
+		display := *fc
+		display.DisplayPath = fc.RelPath
+		renderFileSection(&display, newDocWriter(w, config.Format))
+	}
+	if err := w.Flush(); err != nil {
+		logf("[WARN] 无法写入文件: %s (%v)\n", target, err)
+		stats.Warnings++
+		return
+	}
+	explodedFiles = append(explodedFiles, rel)
+}
+
+// writeExplodeIndex 在索引文件中列出所有拆分出的文件
+func writeExplodeIndex(writer *docWriter) {
+	if len(explodedFiles) == 0 {
+		writer.WriteString("No files were written.\n\n")
+		return
+	}
+	for _, rel := range explodedFiles {
+		if obsidianFlavor() {
+			if link, ok := wikiLink(rel, sanitizeHeading(strings.TrimSuffix(rel, config.ExplodeExt))); ok {
+				writer.WriteString("- " + link + "\n")
+				continue
+			}
+		}
+		writer.WriteString("- " + writer.docLink(sanitizeHeading(rel), linkTarget(rel)) + "\n")
+	}
+	writer.WriteString("\n")
+	if len(renamedFiles) > 0 {
+		writer.WriteString(writer.subHeading("Renamed Files"))
+		writer.WriteString("File names that are invalid or too long on the target system were changed:\n\n")
+		var rows [][]string
+		for _, r := range renamedFiles {
+			rows = append(rows, []string{sanitizeHeading(r.Original), r.Written})
+		}
+		writer.writeTable([]string{"Original", "Written As"}, rows)
+	}
+}
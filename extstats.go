@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// extStat 单个扩展名的包含/跳过统计
+type extStat struct {
+	IncludedFiles int
+	IncludedBytes int64
+	SkippedFiles  int
+	SkippedBytes  int64
+}
+
+// extStats --ext-stats 按扩展名 (小写，无扩展名为 "(none)") 汇总的统计
+var extStats = map[string]*extStat{}
+
+// extStatFor 返回文件扩展名对应的统计项
+func extStatFor(path string) *extStat {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		ext = "(none)"
+	}
+	s, ok := extStats[ext]
+	if !ok {
+		s = &extStat{}
+		extStats[ext] = s
+	}
+	return s
+}
+
+// recordIncluded 记录写入输出的文件
+func recordIncluded(path string, size int64) {
+	if !config.ExtStats {
+		return
+	}
+	s := extStatFor(path)
+	s.IncludedFiles++
+	s.IncludedBytes += size
+}
+
+// recordSkipped 记录被过滤或跳过的文件
+func recordSkipped(path string) {
+	if !config.ExtStats {
+		return
+	}
+	s := extStatFor(path)
+	s.SkippedFiles++
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		s.SkippedBytes += info.Size()
+	}
+}
+
+// recordSkippedDir 记录整个被过滤目录中的文件；只在 --ext-stats 时才遍历
+func recordSkippedDir(dir string) {
+	if !config.ExtStats {
+		return
+	}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && isJunk(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			recordSkipped(path)
+		}
+		return nil
+	})
+}
+
+// printExtStats 打印按扩展名的包含/跳过统计，按跳过的字节数降序排列
+func printExtStats() {
+	if len(extStats) == 0 {
+		return
+	}
+	var exts []string
+	var skippedTotal int64
+	for ext, s := range extStats {
+		exts = append(exts, ext)
+		skippedTotal += s.SkippedBytes
+	}
+	sort.Slice(exts, func(i, j int) bool {
+		a, b := extStats[exts[i]], extStats[exts[j]]
+		if a.SkippedBytes != b.SkippedBytes {
+			return a.SkippedBytes > b.SkippedBytes
+		}
+		if a.IncludedBytes != b.IncludedBytes {
+			return a.IncludedBytes > b.IncludedBytes
+		}
+		return exts[i] < exts[j]
+	})
+	fmt.Println("按扩展名统计:")
+	fmt.Printf("  %-12s %8s %10s %8s %10s %8s\n", "扩展名", "包含", "大小", "跳过", "大小", "跳过占比")
+	for _, ext := range exts {
+		s := extStats[ext]
+		share := "-"
+		if skippedTotal > 0 && s.SkippedBytes > 0 {
+			share = fmt.Sprintf("%.1f%%", float64(s.SkippedBytes)*100/float64(skippedTotal))
+		}
+		fmt.Printf("  %-12s %8d %10s %8d %10s %8s\n", ext, s.IncludedFiles, formatSize(s.IncludedBytes), s.SkippedFiles, formatSize(s.SkippedBytes), share)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Decision 过滤规则集对一个路径的求值结果
+type Decision struct {
+	Matched bool   // 路径被排除 (最后一条命中的规则不是 ! 规则)
+	Rule    string // 最后一条命中的规则，没有规则命中时为空
+}
+
+// filterRule 一条过滤规则：过滤规则语法的模式 (glob) 或正则 (re)
+type filterRule struct {
+	source string // 规则的原始写法，用于说明求值过程与统计未使用的规则
+	negate bool   // ! 规则：命中时重新包含
+	glob   string
+	re     *regexp.Regexp
+}
+
+// match 规则是否命中以 / 分隔的相对路径 (不考虑取反)
+func (r filterRule) match(path string) bool {
+	if r.re != nil {
+		return r.re.MatchString(path)
+	}
+	matched, _ := matchRule(path, r.glob)
+	return matched
+}
+
+// FilterSet 按添加顺序求值的过滤规则集。规则：
+// - dir 或 dir/ : 目录前缀匹配，目录本身和其子孙均命中
+// - dir/*       : 目录下的内容命中，目录本身不命中（保留空目录）
+// - glob        : 尝试匹配全路径或文件名
+// - ! 前缀      : 取反（豁免）
+// 与 gitignore 相同，所有规则按顺序求值，最后一条命中的规则决定结果，
+// 因此 ! 规则可以重新包含之前被排除的路径；但目录一旦被排除便不会再遍历，
+// 其子项无法单独被重新包含 (应改用 dir/* 配合 !dir/keep)
+type FilterSet struct {
+	rules []filterRule
+}
+
+// newFilterSet 由命令行与配置文件中的规则列表创建规则集
+func newFilterSet(patterns []string) *FilterSet {
+	s := &FilterSet{}
+	for _, pattern := range patterns {
+		s.AddGlob(pattern)
+	}
+	return s
+}
+
+// Len 规则数量
+func (s *FilterSet) Len() int {
+	return len(s.rules)
+}
+
+// AddGlob 追加一条过滤规则语法的规则，! 前缀表示取反；空规则忽略
+func (s *FilterSet) AddGlob(pattern string) {
+	if pattern == "" {
+		return
+	}
+	s.rules = append(s.rules, filterRule{
+		source: pattern,
+		negate: strings.HasPrefix(pattern, "!"),
+		glob:   pattern,
+	})
+}
+
+// AddRegex 追加一条按正则匹配以 / 分隔的相对路径的规则 (未锚定，需要时自行加 ^ 与 $)
+func (s *FilterSet) AddRegex(expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("无效的正则规则 %q: %v", expr, err)
+	}
+	s.rules = append(s.rules, filterRule{source: expr, re: re})
+	return nil
+}
+
+// AddGitignoreFile 按 gitignore 语法逐行追加规则文件中的规则：不含 / 的模式匹配任意层级，
+// 以 / 开头或中间含 / 的模式相对根目录，** 跨目录，! 取反；# 注释与空行忽略。
+// 结尾的 / (只匹配目录) 按前缀处理，同名文件也会命中
+func (s *FilterSet) AddGitignoreFile(path string) error {
+	lines, err := loadPatternsFromFile(path)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		expr, negate, ok := gitignoreRegexp(line)
+		if !ok {
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("%s: 无效的规则 %q: %v", path, line, err)
+		}
+		s.rules = append(s.rules, filterRule{source: line, negate: negate, re: re})
+	}
+	return nil
+}
+
+// Negate 将最后添加的规则取反：命中时改为排除或重新包含
+func (s *FilterSet) Negate() {
+	if len(s.rules) == 0 {
+		return
+	}
+	r := &s.rules[len(s.rules)-1]
+	r.negate = !r.negate
+	if r.negate {
+		r.source = "!" + r.source
+	} else {
+		r.source = strings.TrimPrefix(r.source, "!")
+	}
+	if r.re == nil {
+		r.glob = r.source
+	}
+}
+
+// Match 求值规则集：路径以 / 或系统分隔符分隔，相对于根目录；空路径 (根目录自身) 不命中任何规则。
+// Match 不修改任何全局状态，规则使用统计由遍历中的调用方按 Decision.Rule 记录
+func (s *FilterSet) Match(path string) Decision {
+	if path == "" || s == nil {
+		return Decision{}
+	}
+	full := filepath.ToSlash(path)
+
+	var d Decision
+	for _, r := range s.rules {
+		if r.match(full) {
+			d = Decision{Matched: !r.negate, Rule: r.source}
+		}
+	}
+	return d
+}
+
+// gitignoreRegexp 将一行 gitignore 规则转换为匹配相对路径 (及其子孙) 的正则，ok 为 false 表示空行或注释
+func gitignoreRegexp(line string) (expr string, negate bool, ok bool) {
+	line = strings.TrimRight(line, " ")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", false, false
+	}
+	switch {
+	case strings.HasPrefix(line, "!"):
+		negate, line = true, line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+	line = strings.TrimSuffix(line, "/")
+	if line == "" {
+		return "", false, false
+	}
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var sb strings.Builder
+	if anchored {
+		sb.WriteString("^")
+	} else {
+		sb.WriteString("(^|/)")
+	}
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case strings.HasPrefix(line[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "/**") && i+3 == len(line):
+			sb.WriteString("/.*")
+			i += 2
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[' && strings.IndexByte(line[i:], ']') > 1:
+			class := line[i : i+strings.IndexByte(line[i:], ']')+1]
+			i += len(class) - 1
+			// 取反的字符类与 * ? 一样不跨越目录分隔符
+			if strings.HasPrefix(class, "[!") || strings.HasPrefix(class, "[^") {
+				class = "[^/" + class[2:]
+			}
+			sb.WriteString(class)
+		case c == '\\' && i+1 < len(line):
+			sb.WriteString(regexp.QuoteMeta(line[i+1 : i+2]))
+			i++
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("(/|$)")
+	return sb.String(), negate, true
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFilterSetMatch 最后一条命中的规则决定结果，! 规则重新包含之前被排除的路径
+func TestFilterSetMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []string
+		path  string
+		want  Decision
+	}{
+		{name: "无规则", rules: nil, path: "main.go", want: Decision{}},
+		{name: "根目录自身", rules: []string{"*"}, path: "", want: Decision{}},
+		{name: "文件名 glob", rules: []string{"*.go"}, path: "cmd/main.go", want: Decision{Matched: true, Rule: "*.go"}},
+		{name: "全路径 glob", rules: []string{"cmd/*.go"}, path: "cmd/main.go", want: Decision{Matched: true, Rule: "cmd/*.go"}},
+		{name: "未命中", rules: []string{"*.md"}, path: "main.go", want: Decision{}},
+		{name: "目录前缀", rules: []string{"vendor"}, path: "vendor/a/b.go", want: Decision{Matched: true, Rule: "vendor"}},
+		{name: "目录前缀不匹配同名前缀", rules: []string{"vendor"}, path: "vendored/b.go", want: Decision{}},
+		{name: "dir/* 不含目录本身", rules: []string{"build/*"}, path: "build", want: Decision{}},
+		{name: "dir/* 命中目录内容", rules: []string{"build/*"}, path: "build/out.bin", want: Decision{Matched: true, Rule: "build/*"}},
+		{name: "! 重新包含", rules: []string{"*.go", "!main.go"}, path: "main.go", want: Decision{Rule: "!main.go"}},
+		{name: "! 不影响其他路径", rules: []string{"*.go", "!main.go"}, path: "util.go", want: Decision{Matched: true, Rule: "*.go"}},
+		{name: "最后命中的规则生效", rules: []string{"!main.go", "*.go"}, path: "main.go", want: Decision{Matched: true, Rule: "*.go"}},
+		{name: "重新包含后再排除", rules: []string{"docs/*", "!docs/keep", "docs/keep/tmp"}, path: "docs/keep/tmp/x", want: Decision{Matched: true, Rule: "docs/keep/tmp"}},
+		{name: "重新包含的目录内容", rules: []string{"docs/*", "!docs/keep", "docs/keep/tmp"}, path: "docs/keep/a.md", want: Decision{Rule: "!docs/keep"}},
+		{name: "系统分隔符", rules: []string{"docs/*"}, path: filepath.Join("docs", "a.md"), want: Decision{Matched: true, Rule: "docs/*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newFilterSet(tt.rules).Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) with %q = %+v, want %+v", tt.path, tt.rules, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFilterSetRegexAndNegate 正则规则与 glob 规则按添加顺序一起求值，Negate 将最后一条规则取反
+func TestFilterSetRegexAndNegate(t *testing.T) {
+	s := &FilterSet{}
+	s.AddGlob("dist")
+	if err := s.AddRegex(`\.min\.js$`); err != nil {
+		t.Fatal(err)
+	}
+	s.AddGlob("dist/app.min.js")
+	s.Negate()
+
+	tests := []struct {
+		path string
+		want Decision
+	}{
+		{"web/a.min.js", Decision{Matched: true, Rule: `\.min\.js$`}},
+		{"web/a.js", Decision{}},
+		{"dist/b.js", Decision{Matched: true, Rule: "dist"}},
+		{"dist/app.min.js", Decision{Rule: "!dist/app.min.js"}},
+	}
+	for _, tt := range tests {
+		if got := s.Match(tt.path); got != tt.want {
+			t.Errorf("Match(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+	if err := s.AddRegex("("); err == nil {
+		t.Error("无效的正则没有返回错误")
+	}
+}
+
+// TestFilterSetGitignore 按 gitignore 语法加载规则文件
+func TestFilterSetGitignore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".gitignore")
+	content := "# 注释\n\nbuild/\n/root.txt\n*.tmp\n!keep.tmp\ndocs/**/*.png\n\\#hash\nlog?.txt\n[!a]z\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := &FilterSet{}
+	if err := s.AddGitignoreFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want Decision
+	}{
+		{"build", Decision{Matched: true, Rule: "build/"}},
+		{"src/build/out.o", Decision{Matched: true, Rule: "build/"}},
+		{"rebuild/x", Decision{}},
+		{"root.txt", Decision{Matched: true, Rule: "/root.txt"}},
+		{"sub/root.txt", Decision{}},
+		{"a/b/c.tmp", Decision{Matched: true, Rule: "*.tmp"}},
+		{"a/keep.tmp", Decision{Rule: "!keep.tmp"}},
+		{"docs/img.png", Decision{Matched: true, Rule: "docs/**/*.png"}},
+		{"docs/a/b/img.png", Decision{Matched: true, Rule: "docs/**/*.png"}},
+		{"src/docs/img.png", Decision{}},
+		{"#hash", Decision{Matched: true, Rule: `\#hash`}},
+		{"log1.txt", Decision{Matched: true, Rule: "log?.txt"}},
+		{"log10.txt", Decision{}},
+		{"bz", Decision{Matched: true, Rule: "[!a]z"}},
+		{"az", Decision{}},
+		{"x/z", Decision{}},
+	}
+	for _, tt := range tests {
+		if got := s.Match(tt.path); got != tt.want {
+			t.Errorf("Match(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFilterSetMatchNoUsage(t *testing.T) {
+	defer func(saved map[string]bool) { usedRules = saved }(usedRules)
+	usedRules = map[string]bool{}
+
+	newFilterSet([]string{"*.go"}).Match("main.go")
+	if len(usedRules) != 0 {
+		t.Errorf("Match recorded rule usage: %v", usedRules)
+	}
+}
@@ -0,0 +1,541 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// fixtureSpec gen-fixture 的规格文件 (YAML 的一个子集：顶层 key: value、[a, b] 行内列表与 entries 条目列表)
+type fixtureSpec struct {
+	Seed         uint64   // 随机种子，相同的规格与种子生成完全相同的目录树
+	Dirs         int      // 随机目录数
+	Files        int      // 随机文件数
+	Depth        int      // 随机目录的最大深度
+	SizeMin      int64    // 随机文件大小下限
+	SizeMax      int64    // 随机文件大小上限
+	Exts         []string // 随机文本文件的后缀
+	Encodings    []string // 随机文本文件的编码
+	Binary       float64  // 随机文件中二进制文件的比例 (0-1)
+	Deep         int      // 额外生成一条 Deep 层深的目录链
+	SymlinkLoops int      // 指向祖先目录的符号链接数 (目录环)
+	Entries      []fixtureEntry
+}
+
+// fixtureEntry 规格中显式列出的条目
+type fixtureEntry struct {
+	Path     string
+	Size     int64
+	Encoding string
+	Content  string // 指定时直接写入 (按 Encoding 编码)，忽略 Size
+	Symlink  string // 非空时创建指向该目标的符号链接
+	Binary   bool
+	Dir      bool
+}
+
+// fixtureEncodings 可生成的文本编码；与 convertToUTF8 能识别的编码一致
+var fixtureEncodings = map[string]encoding.Encoding{
+	"utf-8":     encoding.Nop,
+	"utf-8-bom": unicode.UTF8BOM,
+	"utf-16le":  unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf-16be":  unicode.UTF16(unicode.BigEndian, unicode.UseBOM),
+	"gbk":       simplifiedchinese.GBK,
+	"latin1":    charmap.ISO8859_1,
+}
+
+// fixtureWords 生成文本内容用的词；latin1 只使用前者，其余编码混入中文
+var fixtureWords = []string{"alpha", "beta", "gamma", "delta", "walk", "filter", "tree", "node", "value", "error", "return", "config", "café", "naïve", "größe"}
+var fixtureCJKWords = []string{"目录", "文件", "过滤", "编码", "测试"}
+
+const fixtureHelp = `用法: dir2txt gen-fixture --spec fixture.yaml [--seed N] <out>
+  按规格生成可复现的测试目录树 (大小、编码、符号链接环、深层嵌套)，用于基准测试与复现遍历/过滤问题
+  <out> 必须不存在或为空目录；相同的规格与种子在任何机器上生成相同的内容
+
+规格文件示例:
+  seed: 42                  # 随机种子 (默认 1)
+  dirs: 20                  # 随机目录数
+  files: 200                # 随机文件数
+  depth: 3                  # 随机目录的最大深度 (默认 3)
+  size: 1K-64K              # 随机文件大小 (近似值，按整行生成)，也可写单个值
+  exts: [go, py, md, txt]   # 随机文本文件的后缀
+  encodings: [utf-8, gbk]   # 随机文本文件的编码: utf-8 utf-8-bom utf-16le utf-16be gbk latin1
+  binary: 10%               # 随机文件中二进制文件 (.bin) 的比例
+  deep: 60                  # 额外生成 deep/level001/... 共 60 层的目录链
+  symlink-loops: 2          # 在随机目录中创建指向祖先目录的符号链接
+  entries:                  # 显式列出的条目
+    - path: logs/big.log
+      size: 5M
+    - path: legacy/readme.txt
+      encoding: gbk
+      content: "旧系统说明"
+    - path: assets/blob.bin
+      binary: true
+    - path: empty-dir
+      dir: true
+    - path: src/loop
+      symlink: ..`
+
+// runGenFixture 实现 dir2txt gen-fixture 子命令
+func runGenFixture(args []string) error {
+	var specPath, out string
+	var seed *uint64
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch {
+		case arg == "--help" || arg == "-h":
+			fmt.Println(fixtureHelp)
+			return nil
+		case name == "--spec" || name == "--seed":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return fmt.Errorf("%s 需要参数值", name)
+				}
+				i++
+				value = args[i]
+			}
+			if name == "--spec" {
+				specPath = value
+				continue
+			}
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("--seed 需要一个非负整数: %s", value)
+			}
+			seed = &n
+		case arg == "--":
+			if i+1 < len(args) {
+				out = args[i+1]
+			}
+			i = len(args)
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			return fmt.Errorf("未知参数: %s (dir2txt gen-fixture --help 查看用法)", arg)
+		default:
+			if out != "" {
+				return fmt.Errorf("只能指定一个输出目录")
+			}
+			out = arg
+		}
+	}
+	if specPath == "" || out == "" {
+		return fmt.Errorf("需要 --spec 与输出目录 (dir2txt gen-fixture --help 查看用法)")
+	}
+
+	content, err := os.ReadFile(specPath)
+	if err != nil {
+		return fmt.Errorf("无法读取规格文件: %v", err)
+	}
+	spec, err := parseFixtureSpec(content)
+	if err != nil {
+		return fmt.Errorf("%s:%v", specPath, err)
+	}
+	if seed != nil {
+		spec.Seed = *seed
+	}
+	if entries, err := os.ReadDir(out); err == nil && len(entries) > 0 {
+		return fmt.Errorf("输出目录不为空: %s", out)
+	}
+	return generateFixture(spec, out)
+}
+
+// parseFixtureSpec 解析规格文件，错误信息以行号开头
+func parseFixtureSpec(content []byte) (*fixtureSpec, error) {
+	spec := &fixtureSpec{Seed: 1, Depth: 3, SizeMin: 1024, SizeMax: 1024, Exts: []string{"txt"}, Encodings: []string{"utf-8"}}
+	inEntries := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		raw := stripYAMLComment(scanner.Text())
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" {
+			continue
+		}
+		indented := raw[0] == ' ' || raw[0] == '\t'
+		if !indented {
+			inEntries = false
+		}
+		if indented && inEntries {
+			if item, ok := strings.CutPrefix(trimmed, "-"); ok {
+				spec.Entries = append(spec.Entries, fixtureEntry{})
+				trimmed = strings.TrimSpace(item)
+				if trimmed == "" {
+					continue
+				}
+			}
+			if len(spec.Entries) == 0 {
+				return nil, fmt.Errorf("%d: entries 的条目需要以 - 开头", n)
+			}
+			key, value, ok := strings.Cut(trimmed, ":")
+			if !ok {
+				return nil, fmt.Errorf("%d: 需要 key: value: %s", n, trimmed)
+			}
+			if err := setFixtureEntryField(&spec.Entries[len(spec.Entries)-1], strings.TrimSpace(key), yamlScalar(value)); err != nil {
+				return nil, fmt.Errorf("%d: %v", n, err)
+			}
+			continue
+		}
+		if indented {
+			return nil, fmt.Errorf("%d: 意外的缩进: %s", n, trimmed)
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("%d: 需要 key: value: %s", n, trimmed)
+		}
+		key = strings.TrimSpace(key)
+		if key == "entries" {
+			inEntries = true
+			continue
+		}
+		if err := setFixtureSpecField(spec, key, strings.TrimSpace(value)); err != nil {
+			return nil, fmt.Errorf("%d: %v", n, err)
+		}
+	}
+	for _, e := range spec.Entries {
+		if e.Path == "" {
+			return nil, fmt.Errorf(" entries 中的条目缺少 path")
+		}
+		if filepath.IsAbs(e.Path) || strings.HasPrefix(filepath.Clean(filepath.FromSlash(e.Path)), "..") {
+			return nil, fmt.Errorf(" 条目路径必须位于输出目录之内: %s", e.Path)
+		}
+	}
+	return spec, scanner.Err()
+}
+
+// stripYAMLComment 去掉行首或空白之后的 # 注释 (引号中的 # 保留)
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// yamlScalar 去掉值两侧的空白与引号
+func yamlScalar(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// yamlList 解析 [a, b] 行内列表，也接受单个值
+func yamlList(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "["), "]")
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = yamlScalar(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func setFixtureSpecField(spec *fixtureSpec, key string, value string) error {
+	count := func(target *int) error {
+		n, err := strconv.Atoi(yamlScalar(value))
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s 需要一个非负整数: %s", key, value)
+		}
+		*target = n
+		return nil
+	}
+	switch key {
+	case "seed":
+		n, err := strconv.ParseUint(yamlScalar(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("seed 需要一个非负整数: %s", value)
+		}
+		spec.Seed = n
+	case "dirs":
+		return count(&spec.Dirs)
+	case "files":
+		return count(&spec.Files)
+	case "depth":
+		return count(&spec.Depth)
+	case "deep":
+		return count(&spec.Deep)
+	case "symlink-loops":
+		return count(&spec.SymlinkLoops)
+	case "size":
+		lo, hi, isRange := strings.Cut(yamlScalar(value), "-")
+		if !isRange {
+			hi = lo
+		}
+		minSize, err1 := parseSize(lo)
+		maxSize, err2 := parseSize(hi)
+		if err1 != nil || err2 != nil || minSize > maxSize {
+			return fmt.Errorf("size 需要大小或范围，如 4K 或 1K-64K: %s", value)
+		}
+		spec.SizeMin, spec.SizeMax = minSize, maxSize
+	case "exts":
+		spec.Exts = nil
+		for _, ext := range yamlList(value) {
+			spec.Exts = append(spec.Exts, strings.TrimPrefix(ext, "."))
+		}
+		if len(spec.Exts) == 0 {
+			return fmt.Errorf("exts 不能为空")
+		}
+	case "encodings":
+		spec.Encodings = yamlList(value)
+		if len(spec.Encodings) == 0 {
+			return fmt.Errorf("encodings 不能为空")
+		}
+		for _, enc := range spec.Encodings {
+			if _, ok := fixtureEncodings[enc]; !ok {
+				return fmt.Errorf("不支持的编码: %s", enc)
+			}
+		}
+	case "binary":
+		p, err := strconv.ParseFloat(strings.TrimSuffix(yamlScalar(value), "%"), 64)
+		if err != nil || p < 0 || p > 100 {
+			return fmt.Errorf("binary 需要一个 0-100 的百分比: %s", value)
+		}
+		spec.Binary = p / 100
+	default:
+		return fmt.Errorf("未知的设置: %s", key)
+	}
+	return nil
+}
+
+func setFixtureEntryField(e *fixtureEntry, key string, value string) error {
+	switch key {
+	case "path":
+		e.Path = value
+	case "size":
+		n, err := parseSize(value)
+		if err != nil {
+			return err
+		}
+		e.Size = n
+	case "encoding":
+		if _, ok := fixtureEncodings[value]; !ok {
+			return fmt.Errorf("不支持的编码: %s", value)
+		}
+		e.Encoding = value
+	case "content":
+		e.Content = value
+	case "symlink":
+		e.Symlink = value
+	case "binary", "dir":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s 需要 true 或 false: %s", key, value)
+		}
+		if key == "binary" {
+			e.Binary = b
+		} else {
+			e.Dir = b
+		}
+	default:
+		return fmt.Errorf("未知的条目字段: %s", key)
+	}
+	return nil
+}
+
+// generateFixture 按规格在 out 下生成目录树；所有随机选择都来自以 Seed 初始化的同一个生成器，顺序固定
+func generateFixture(spec *fixtureSpec, out string) error {
+	rng := rand.New(rand.NewPCG(spec.Seed, spec.Seed))
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return err
+	}
+	var dirCount, fileCount, linkCount int
+
+	// 随机目录：每个新目录挂在已有的、深度未达上限的目录之下
+	type dirInfo struct {
+		rel   string
+		depth int
+	}
+	dirs := []dirInfo{{rel: "", depth: 0}}
+	for i := 0; i < spec.Dirs; i++ {
+		var parents []dirInfo
+		for _, d := range dirs {
+			if d.depth < spec.Depth {
+				parents = append(parents, d)
+			}
+		}
+		if len(parents) == 0 {
+			break
+		}
+		parent := parents[rng.IntN(len(parents))]
+		rel := filepath.Join(parent.rel, fmt.Sprintf("dir%03d", i+1))
+		if err := os.Mkdir(filepath.Join(out, rel), 0o755); err != nil {
+			return err
+		}
+		dirs = append(dirs, dirInfo{rel: rel, depth: parent.depth + 1})
+		dirCount++
+	}
+
+	for i := 0; i < spec.Files; i++ {
+		dir := dirs[rng.IntN(len(dirs))].rel
+		size := spec.SizeMin + rng.Int64N(spec.SizeMax-spec.SizeMin+1)
+		var name string
+		var data []byte
+		if rng.Float64() < spec.Binary {
+			name = fmt.Sprintf("file%04d.bin", i+1)
+			data = fixtureBinary(rng, size)
+		} else {
+			ext := spec.Exts[rng.IntN(len(spec.Exts))]
+			enc := spec.Encodings[rng.IntN(len(spec.Encodings))]
+			name = fmt.Sprintf("file%04d.%s", i+1, ext)
+			data = fixtureText(rng, size, enc)
+		}
+		if err := os.WriteFile(filepath.Join(out, dir, name), data, 0o644); err != nil {
+			return err
+		}
+		fileCount++
+	}
+
+	if spec.Deep > 0 {
+		rel := "deep"
+		for i := 1; i <= spec.Deep; i++ {
+			rel = filepath.Join(rel, fmt.Sprintf("level%03d", i))
+		}
+		if err := os.MkdirAll(filepath.Join(out, rel), 0o755); err != nil {
+			return err
+		}
+		dirCount += spec.Deep + 1
+		if err := os.WriteFile(filepath.Join(out, rel, "bottom.txt"), fixtureText(rng, 256, "utf-8"), 0o644); err != nil {
+			return err
+		}
+		fileCount++
+	}
+
+	// 目录环：在随机目录中创建指向其某个祖先 (或根目录) 的相对符号链接
+	for i := 0; i < spec.SymlinkLoops; i++ {
+		d := dirs[rng.IntN(len(dirs))]
+		target := "."
+		if d.depth > 0 {
+			target = strings.TrimSuffix(strings.Repeat("../", 1+rng.IntN(d.depth)), "/")
+		}
+		if fixtureSymlink(target, filepath.Join(out, d.rel, fmt.Sprintf("loop%02d", i+1))) {
+			linkCount++
+		}
+	}
+
+	for _, e := range spec.Entries {
+		path := filepath.Join(out, filepath.FromSlash(e.Path))
+		if e.Dir {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+			dirCount++
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if e.Symlink != "" {
+			if fixtureSymlink(filepath.FromSlash(e.Symlink), path) {
+				linkCount++
+			}
+			continue
+		}
+		enc := e.Encoding
+		if enc == "" {
+			enc = "utf-8"
+		}
+		size := e.Size
+		if size == 0 {
+			size = spec.SizeMin
+		}
+		var data []byte
+		switch {
+		case e.Binary:
+			data = fixtureBinary(rng, size)
+		case e.Content != "":
+			encoded, err := fixtureEncodings[enc].NewEncoder().Bytes([]byte(e.Content + "\n"))
+			if err != nil {
+				return fmt.Errorf("%s 的内容无法以 %s 编码: %v", e.Path, enc, err)
+			}
+			data = encoded
+		default:
+			data = fixtureText(rng, size, enc)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+		fileCount++
+	}
+
+	fmt.Printf("已生成 %d 个目录、%d 个文件、%d 个符号链接: %s (seed %d)\n", dirCount, fileCount, linkCount, out, spec.Seed)
+	return nil
+}
+
+// fixtureSymlink 创建符号链接；系统不支持 (如未开启开发者模式的 Windows) 时只打印警告
+func fixtureSymlink(target string, path string) bool {
+	if err := os.Symlink(target, path); err != nil {
+		fmt.Printf("[WARN] 无法创建符号链接 %s -> %s: %v\n", path, target, err)
+		return false
+	}
+	return true
+}
+
+// fixtureBinary 生成含 NUL 字节的二进制内容
+func fixtureBinary(rng *rand.Rand, size int64) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		if i%7 == 0 {
+			continue
+		}
+		data[i] = byte(rng.IntN(256))
+	}
+	return data
+}
+
+// fixtureText 生成约 size 字节 (编码后) 的文本，按整行生成，不会截断多字节字符
+func fixtureText(rng *rand.Rand, size int64, enc string) []byte {
+	encoder := fixtureEncodings[enc].NewEncoder()
+	words := fixtureWords
+	if enc != "latin1" {
+		words = append(append([]string{}, fixtureWords...), fixtureCJKWords...)
+	}
+	var buf bytes.Buffer
+	for line := 1; int64(buf.Len()) < size; line++ {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%d:", line)
+		for n := 3 + rng.IntN(10); n > 0; n-- {
+			sb.WriteString(" " + words[rng.IntN(len(words))])
+		}
+		sb.WriteString("\n")
+		encoded, _ := encoder.Bytes([]byte(sb.String()))
+		// UTF-16 与带 BOM 的 UTF-8 只在文件开头写一次 BOM
+		if line > 1 {
+			encoded = bytes.TrimPrefix(encoded, fixtureBOM(enc))
+		}
+		buf.Write(encoded)
+	}
+	return buf.Bytes()
+}
+
+// fixtureBOM 编码器在每次编码开头写入的 BOM
+func fixtureBOM(enc string) []byte {
+	switch enc {
+	case "utf-8-bom":
+		return []byte{0xEF, 0xBB, 0xBF}
+	case "utf-16le":
+		return []byte{0xFF, 0xFE}
+	case "utf-16be":
+		return []byte{0xFE, 0xFF}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// flavors --flavor 支持的 Markdown 方言
+var flavors = map[string]bool{"github": true, "obsidian": true}
+
+// obsidianFlavor 是否按 Obsidian/Logseq 的习惯输出：wiki 链接、拆分文件带 YAML front matter
+func obsidianFlavor() bool {
+	return config.Flavor == "obsidian"
+}
+
+// wikiUnsafe Obsidian 的 [[链接]] 中不能出现的字符 (标题链接中的 : 也无法匹配)
+const wikiUnsafe = "#|^[]:"
+
+// wikiLink 生成 [[target|text]] 形式的链接；目标含有无法链接的字符时返回 false
+func wikiLink(target string, text string) (string, bool) {
+	if strings.ContainsAny(strings.TrimPrefix(target, "#"), wikiUnsafe) || strings.Contains(target, "%%") {
+		return "", false
+	}
+	text = strings.Map(func(r rune) rune {
+		if strings.ContainsRune("|[]", r) {
+			return '_'
+		}
+		return r
+	}, text)
+	return "[[" + target + "|" + text + "]]", true
+}
+
+// obsidianInlineRe 正文中会被 Obsidian 当作标签、高亮、注释或内部链接的写法
+var obsidianInlineRe = regexp.MustCompile(`#[^\s#]|==|%%|\[\[`)
+
+// obsidianText 转义正文 (摘要、提交信息等) 中会被 Obsidian 误解析的写法
+func obsidianText(s string) string {
+	if !obsidianFlavor() {
+		return s
+	}
+	return obsidianInlineRe.ReplaceAllStringFunc(s, func(m string) string {
+		return `\` + m
+	})
+}
+
+// writeFrontMatter 在拆分出的单个文件开头写入 YAML front matter，供知识库按属性检索
+func writeFrontMatter(fc *fileContent, w *bufio.Writer) {
+	lang := codeLang(fc.Path)
+	w.WriteString("---\n")
+	// JSON 字符串同时是合法的 YAML 双引号标量，无需处理特殊字符
+	fmt.Fprintf(w, "path: %s\n", yamlString(fc.RelPath))
+	fmt.Fprintf(w, "name: %s\n", yamlString(filepath.Base(fc.RelPath)))
+	fmt.Fprintf(w, "language: %s\n", yamlString(lang))
+	fmt.Fprintf(w, "size: %d\n", fc.Size)
+	if !fc.ModTime.IsZero() {
+		fmt.Fprintf(w, "modified: %s\n", fc.ModTime.Format("2006-01-02T15:04:05Z07:00"))
+	}
+	if fc.Summary != "" {
+		fmt.Fprintf(w, "summary: %s\n", yamlString(fc.Summary))
+	}
+	fmt.Fprintf(w, "tags: [dir2txt, %s]\n", yamlString(strings.ReplaceAll(lang, " ", "-")))
+	w.WriteString("---\n\n")
+}
+
+func yamlString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
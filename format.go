@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/width"
+)
+
+// markdownRenderer Markdown 输出 (默认格式)；--flavor obsidian 只作用于 Markdown
+type markdownRenderer struct{ documentSections }
+
+func (markdownRenderer) document() bool { return true }
+
+func (markdownRenderer) heading(level int, title string) string {
+	return strings.Repeat("#", level) + " " + title + "\n\n"
+}
+
+func (markdownRenderer) inlineCode(s string) string { return "`" + s + "`" }
+
+func (markdownRenderer) blockQuote(s string) string { return "> " + s + "\n\n" }
+
+func (markdownRenderer) strong(s string) string { return "**" + s + "**" }
+
+func (markdownRenderer) docLink(text string, target string) string {
+	return fmt.Sprintf("[%s](%s)", text, target)
+}
+
+// fileHeadingText Obsidian 的标题链接无法匹配冒号，--flavor obsidian 时标题只有路径
+func (markdownRenderer) fileHeadingText(displayPath string) string {
+	if obsidianFlavor() {
+		return sanitizeHeading(displayPath)
+	}
+	return "File: " + sanitizeHeading(displayPath)
+}
+
+func (r markdownRenderer) contentsHeading(level int) string { return r.heading(level, "File Contents") }
+
+func (markdownRenderer) codeBlock(writer *bufio.Writer, lang string, content []byte) {
+	writer.WriteString(fmt.Sprintf("```%s\n", lang))
+	writer.Write(content)
+
+	// 确保代码块如果没换行符结尾，手动补一个
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		writer.WriteString("\n")
+	}
+	writer.WriteString("```\n\n")
+}
+
+func (markdownRenderer) separator(writer *bufio.Writer) { writer.WriteString("---\n\n") }
+
+func (markdownRenderer) table(writer *bufio.Writer, header []string, rows [][]string) {
+	writer.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	writer.WriteString("|" + strings.Repeat("---|", len(header)) + "\n")
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.ReplaceAll(cell, "|", "\\|")
+		}
+		writer.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	writer.WriteString("\n")
+}
+
+// escapeLinkText 转义文件名中会被当作 Markdown 语法的字符
+func (markdownRenderer) escapeLinkText(s string) string {
+	var sb strings.Builder
+	for _, r := range sanitizeHeading(s) {
+		if strings.ContainsRune("\\`*_[]<>#|", r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// sectionLink --flavor obsidian 时为按标题文本的 wiki 链接，否则按 GitHub 锚点链接
+func (r markdownRenderer) sectionLink(text string, anchor string) string {
+	if obsidianFlavor() {
+		if link, ok := wikiLink("#"+anchor, text); ok {
+			return link
+		}
+		return r.escapeLinkText(text)
+	}
+	return "[" + r.escapeLinkText(text) + "](#" + anchor + ")"
+}
+
+func (r markdownRenderer) fileAnchors(candidates []candidateFile) map[string]string {
+	if obsidianFlavor() {
+		return headingTextAnchors(r, candidates)
+	}
+	return githubAnchors(r, candidates)
+}
+
+// rstRenderer reStructuredText 输出
+type rstRenderer struct{ documentSections }
+
+// rstUnderlines reStructuredText 各级标题的下划线字符，与 Python 文档的惯例一致
+var rstUnderlines = []byte{'=', '-', '~', '^', '"', '+'}
+
+func (rstRenderer) document() bool { return true }
+
+func (rstRenderer) heading(level int, title string) string {
+	title = rstEscape(title)
+	return title + "\n" + strings.Repeat(string(rstUnderlines[level-1]), displayWidth(title)) + "\n\n"
+}
+
+func (rstRenderer) inlineCode(s string) string { return "``" + s + "``" }
+
+// blockQuote reStructuredText 中为缩进段落
+func (rstRenderer) blockQuote(s string) string { return "   " + s + "\n\n" }
+
+func (rstRenderer) strong(s string) string { return "**" + s + "**" }
+
+func (rstRenderer) docLink(text string, target string) string {
+	return fmt.Sprintf("`%s <%s>`_", rstEscape(text), target)
+}
+
+func (rstRenderer) fileHeadingText(displayPath string) string {
+	return "File: " + sanitizeHeading(displayPath)
+}
+
+func (r rstRenderer) contentsHeading(level int) string { return r.heading(level, "File Contents") }
+
+func (rstRenderer) codeBlock(writer *bufio.Writer, lang string, content []byte) {
+	writer.WriteString(fmt.Sprintf(".. code-block:: %s\n\n", lang))
+	if len(bytes.TrimSpace(content)) == 0 {
+		// 内容为空的指令会被 Sphinx 报错
+		writer.WriteString("   (empty)\n\n")
+		return
+	}
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(string(content), "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			writer.WriteString("   ")
+		}
+		writer.WriteString(line)
+	}
+	writer.WriteString("\n\n")
+}
+
+// separator reStructuredText 的过渡线不能位于章节末尾，不输出
+func (rstRenderer) separator(writer *bufio.Writer) {}
+
+// table 以 list-table 指令输出，单元格中的 | 不需要转义
+func (rstRenderer) table(writer *bufio.Writer, header []string, rows [][]string) {
+	writer.WriteString(".. list-table::\n   :header-rows: 1\n\n")
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			prefix := "     -"
+			if i == 0 {
+				prefix = "   * -"
+			}
+			if cell == "" {
+				writer.WriteString(prefix + "\n")
+				continue
+			}
+			writer.WriteString(prefix + " " + cell + "\n")
+		}
+	}
+	writer.WriteString("\n")
+}
+
+// orgRenderer Org 输出
+type orgRenderer struct{ documentSections }
+
+func (orgRenderer) document() bool { return true }
+
+func (orgRenderer) heading(level int, title string) string {
+	return strings.Repeat("*", level) + " " + title + "\n\n"
+}
+
+func (orgRenderer) inlineCode(s string) string { return "=" + s + "=" }
+
+func (orgRenderer) blockQuote(s string) string {
+	return "#+BEGIN_QUOTE\n" + s + "\n#+END_QUOTE\n\n"
+}
+
+func (orgRenderer) strong(s string) string { return "*" + s + "*" }
+
+func (orgRenderer) docLink(text string, target string) string {
+	return fmt.Sprintf("[[file:%s][%s]]", target, text)
+}
+
+func (orgRenderer) fileHeadingText(displayPath string) string {
+	return "File: " + sanitizeHeading(displayPath)
+}
+
+func (r orgRenderer) contentsHeading(level int) string { return r.heading(level, "File Contents") }
+
+// orgLangs 代码块语言标记与 Emacs 模式名不一致的常见后缀 (Org 按 <lang>-mode 选择高亮)
+var orgLangs = map[string]string{
+	"py":   "python",
+	"rb":   "ruby",
+	"rs":   "rust",
+	"ts":   "typescript",
+	"yml":  "yaml",
+	"md":   "markdown",
+	"bash": "sh",
+	"zsh":  "sh",
+	"h":    "c",
+	"hpp":  "c++",
+	"cpp":  "c++",
+	"cc":   "c++",
+	"el":   "emacs-lisp",
+	"kt":   "kotlin",
+	"cs":   "csharp",
+}
+
+func (orgRenderer) codeBlock(writer *bufio.Writer, lang string, content []byte) {
+	if mode, ok := orgLangs[lang]; ok {
+		lang = mode
+	}
+	writer.WriteString(fmt.Sprintf("#+BEGIN_SRC %s\n", lang))
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		// 源码块中以 * 或 #+ 开头的行会被当作标题或块结束，按 Org 的约定加逗号转义
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(line, "*") || strings.HasPrefix(trimmed, "#+") || strings.HasPrefix(trimmed, ",*") || strings.HasPrefix(trimmed, ",#+") {
+			writer.WriteString(",")
+		}
+		writer.WriteString(line)
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		writer.WriteString("\n")
+	}
+	writer.WriteString("#+END_SRC\n\n")
+}
+
+// separator Org 以标题折叠即可区分章节，不输出
+func (orgRenderer) separator(writer *bufio.Writer) {}
+
+// table 单元格中的 | 以 \vert{} 转义，列宽由 Emacs 对齐
+func (orgRenderer) table(writer *bufio.Writer, header []string, rows [][]string) {
+	writer.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	writer.WriteString("|" + strings.Repeat("---+", len(header)-1) + "---|\n")
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.ReplaceAll(cell, "|", "\\vert{}")
+		}
+		writer.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	writer.WriteString("\n")
+}
+
+// escapeLinkText Org 没有反斜杠转义
+func (orgRenderer) escapeLinkText(s string) string { return sanitizeHeading(s) }
+
+func (orgRenderer) sectionLink(text string, anchor string) string {
+	if strings.ContainsAny(anchor+text, "[]") {
+		return text
+	}
+	return "[[*" + anchor + "][" + text + "]]"
+}
+
+// fileAnchors Org 按标题文本链接
+func (r orgRenderer) fileAnchors(candidates []candidateFile) map[string]string {
+	return headingTextAnchors(r, candidates)
+}
+
+// plainRenderer 纯文本输出：以 ===== 标题行分隔文件，内容不加标记
+type plainRenderer struct{ documentSections }
+
+func (plainRenderer) document() bool { return true }
+
+func (plainRenderer) heading(level int, title string) string {
+	return "===== " + title + " =====\n"
+}
+
+func (plainRenderer) inlineCode(s string) string { return s }
+
+func (plainRenderer) blockQuote(s string) string { return s + "\n\n" }
+
+func (plainRenderer) strong(s string) string { return s }
+
+func (plainRenderer) docLink(text string, target string) string { return target }
+
+// fileHeadingText plain 格式的分隔行只有路径
+func (plainRenderer) fileHeadingText(displayPath string) string {
+	return sanitizeHeading(displayPath)
+}
+
+// contentsHeading plain 格式中 "File Contents" 会被当作一个空文件的分隔行，省略
+func (plainRenderer) contentsHeading(level int) string { return "" }
+
+func (plainRenderer) codeBlock(writer *bufio.Writer, lang string, content []byte) {
+	writer.Write(content)
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		writer.WriteString("\n")
+	}
+	writer.WriteString("\n")
+}
+
+// separator plain 以 ===== 标题行分隔，不输出
+func (plainRenderer) separator(writer *bufio.Writer) {}
+
+// table 按显示宽度补齐各列，表头下方以 - 标出列宽
+func (plainRenderer) table(writer *bufio.Writer, header []string, rows [][]string) {
+	widths := make([]int, len(header))
+	all := append([][]string{header}, rows...)
+	for _, row := range all {
+		for i, cell := range row {
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+	writeRow := func(row []string) {
+		var sb strings.Builder
+		for i, cell := range row {
+			sb.WriteString(cell)
+			if i < len(row)-1 {
+				sb.WriteString(strings.Repeat(" ", widths[i]-displayWidth(cell)+2))
+			}
+		}
+		writer.WriteString(strings.TrimRight(sb.String(), " ") + "\n")
+	}
+	writeRow(header)
+	rule := make([]string, len(widths))
+	for i, w := range widths {
+		rule[i] = strings.Repeat("-", w)
+	}
+	writeRow(rule)
+	for _, row := range rows {
+		writeRow(row)
+	}
+	writer.WriteString("\n")
+}
+
+// displayWidth 文本在等宽字体下的显示宽度；docutils 要求标题下划线不短于标题，全角字符占两列
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		switch width.LookupRune(r).Kind() {
+		case width.EastAsianWide, width.EastAsianFullwidth:
+			n += 2
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// rstEscape 转义 reStructuredText 行内标记字符，避免文件名中的 * ` _ | 被解析为强调或引用
+func rstEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\*`_|", r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitFileHistory 返回最近 n 条修改过该文件的提交 (短哈希 + 标题)，不在 git 仓库中时返回 nil
+func gitFileHistory(filePath string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	output, err := runGit(filepath.Dir(filePath), "log", "--follow",
+		"-n", fmt.Sprint(n), "--format=%h %s", "--", filepath.Base(filePath))
+	if err != nil {
+		return nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// runGit 在指定目录执行 git 命令并返回标准输出
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	// 不从终端读取凭据，避免在 --ci 等非交互环境中挂起
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(output), nil
+}
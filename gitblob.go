@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gitBlobRepo 一个 git 仓库中工作区与索引内容一致的文件及其 blob OID
+type gitBlobRepo struct {
+	root  string
+	blobs map[string]string // 相对仓库根目录的路径 (正斜杠分隔) -> blob OID
+}
+
+// gitRepoOfDir 按目录缓存所属的仓库，nil 表示不在仓库中或 git 不可用
+var gitRepoOfDir = map[string]*gitBlobRepo{}
+
+// gitBlobOID 返回文件在 git 索引中的 blob OID；文件未被跟踪或工作区有未暂存的修改时返回 false。
+// checkout、rebase 等操作会改变修改时间但不改变内容，按 OID 作为缓存键可以继续命中
+func gitBlobOID(path string) (string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	repo := gitRepoFor(filepath.Dir(abs))
+	if repo == nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(repo.root, abs)
+	if err != nil {
+		return "", false
+	}
+	oid, ok := repo.blobs[filepath.ToSlash(rel)]
+	return oid, ok
+}
+
+// gitRepoFor 向上查找包含 .git 的目录 (子模块中的 .git 是文件) 并加载该仓库的索引
+func gitRepoFor(dir string) *gitBlobRepo {
+	if repo, ok := gitRepoOfDir[dir]; ok {
+		return repo
+	}
+	var repo *gitBlobRepo
+	if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+		repo = loadGitBlobs(dir)
+	} else if parent := filepath.Dir(dir); parent != dir {
+		repo = gitRepoFor(parent)
+	}
+	gitRepoOfDir[dir] = repo
+	return repo
+}
+
+// loadGitBlobs 读取索引中各文件的 blob OID，排除工作区与索引不一致的文件
+func loadGitBlobs(root string) *gitBlobRepo {
+	staged, err := runGit(root, "ls-files", "--stage", "-z")
+	if err != nil {
+		return nil
+	}
+	// git diff 会刷新索引中的文件状态，只有修改时间变化的文件不会被列出
+	modified, err := runGit(root, "diff", "--name-only", "-z", "--no-renames")
+	if err != nil {
+		return nil
+	}
+	dirty := map[string]bool{}
+	for _, p := range strings.Split(modified, "\x00") {
+		dirty[p] = true
+	}
+	repo := &gitBlobRepo{root: root, blobs: map[string]string{}}
+	for _, entry := range strings.Split(staged, "\x00") {
+		// 格式: <mode> <oid> <stage>\t<path>
+		meta, p, ok := strings.Cut(entry, "\t")
+		if !ok || dirty[p] {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 3 || fields[2] != "0" || fields[0] == "160000" {
+			// 跳过冲突中的文件与子模块
+			continue
+		}
+		repo.blobs[p] = fields[1]
+	}
+	return repo
+}
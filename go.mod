@@ -2,4 +2,8 @@ module dir2txt
 
 go 1.24.4
 
-require golang.org/x/text v0.31.0
+require (
+	github.com/klauspost/compress v1.18.0
+	golang.org/x/sys v0.38.0
+	golang.org/x/text v0.31.0
+)
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// goListPackage 对应 go list -json 输出中用到的字段
+type goListPackage struct {
+	ImportPath string
+	Dir        string
+	Standard   bool
+	Module     *struct {
+		Path string
+		Main bool
+	}
+	GoFiles    []string
+	CgoFiles   []string
+	CFiles     []string
+	HFiles     []string
+	SFiles     []string
+	EmbedFiles []string
+	Imports    []string
+}
+
+// runGoList 在 root 目录执行 go list -json 并解析输出的所有包
+func runGoList(root string, args ...string) ([]goListPackage, error) {
+	cmd := exec.Command("go", append([]string{"list", "-json"}, args...)...)
+	cmd.Dir = root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list 执行失败: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	var pkgs []goListPackage
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var pkg goListPackage
+		if err := dec.Decode(&pkg); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("无法解析 go list 输出: %v", err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+// inMainModule 判断包是否属于主模块 (排除标准库和第三方模块)
+func (pkg *goListPackage) inMainModule() bool {
+	return !pkg.Standard && pkg.Module != nil && pkg.Module.Main
+}
+
+// goPackageFiles 通过 go list 解析指定包的源文件绝对路径
+// withDeps 为 true 时同时包含主模块内的所有依赖包
+func goPackageFiles(root string, patterns []string, withDeps bool) (map[string]bool, error) {
+	var args []string
+	if withDeps {
+		args = append(args, "-deps")
+	}
+	pkgs, err := runGoList(root, append(args, patterns...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]bool{}
+	for _, pkg := range pkgs {
+		if !pkg.inMainModule() {
+			continue
+		}
+		groups := [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.CFiles, pkg.HFiles, pkg.SFiles, pkg.EmbedFiles}
+		for _, group := range groups {
+			for _, name := range group {
+				files[filepath.Join(pkg.Dir, name)] = true
+			}
+		}
+	}
+	return files, nil
+}
+
+// goGraphModule 一个根目录中主模块的包依赖图
+type goGraphModule struct {
+	Name  string
+	Lang  string // 代码块语言: mermaid 或 text
+	Graph string
+}
+
+// collectGoGraph 为包含 go.mod 的根目录生成主模块内的包依赖图
+// format 为 "mermaid" 时生成 Mermaid 流程图，否则生成 "a -> b" 形式的文本边
+func collectGoGraph(dirs []string, format string) []goGraphModule {
+	var modules []goGraphModule
+	for _, dir := range dirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(absDir, "go.mod")); err != nil {
+			continue
+		}
+		pkgs, err := runGoList(absDir, "./...")
+		if err != nil {
+			errorf("[WARN] 无法生成 %s 的 Go 依赖图: %v\n", dir, err)
+			continue
+		}
+
+		inModule := map[string]bool{}
+		for _, pkg := range pkgs {
+			if pkg.inMainModule() {
+				inModule[pkg.ImportPath] = true
+			}
+		}
+
+		var sb strings.Builder
+		lang := "text"
+		if format == "mermaid" {
+			lang = "mermaid"
+			sb.WriteString("graph LR\n")
+		}
+		for _, pkg := range pkgs {
+			if !inModule[pkg.ImportPath] {
+				continue
+			}
+			edges := 0
+			for _, imp := range pkg.Imports {
+				if !inModule[imp] {
+					continue
+				}
+				edges++
+				if format == "mermaid" {
+					sb.WriteString(fmt.Sprintf("    %s --> %s\n", mermaidID(pkg.ImportPath), mermaidID(imp)))
+				} else {
+					sb.WriteString(fmt.Sprintf("%s -> %s\n", pkg.ImportPath, imp))
+				}
+			}
+			// 没有模块内依赖的包也单独列出，避免在图中消失
+			if edges == 0 {
+				if format == "mermaid" {
+					sb.WriteString("    " + mermaidID(pkg.ImportPath) + "\n")
+				} else {
+					sb.WriteString(pkg.ImportPath + "\n")
+				}
+			}
+		}
+		modules = append(modules, goGraphModule{Name: filepath.Base(absDir), Lang: lang, Graph: sb.String()})
+	}
+	return modules
+}
+
+// writeGoGraph 输出各模块的包依赖图，没有 Go 模块时不输出章节
+func writeGoGraph(modules []goGraphModule, writer *docWriter) {
+	if len(modules) == 0 {
+		return
+	}
+	writer.WriteString(writer.docHeading("Go Package Graph"))
+	for _, m := range modules {
+		writer.WriteString(writer.subHeading("Module: " + m.Name))
+		writer.writeCodeBlock(m.Lang, []byte(m.Graph))
+	}
+	writer.writeSeparator()
+}
+
+// mermaidID 生成 Mermaid 节点声明，节点 ID 只保留字母数字，标签为完整导入路径
+func mermaidID(importPath string) string {
+	id := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, importPath)
+	return fmt.Sprintf("%s[\"%s\"]", id, importPath)
+}
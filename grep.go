@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// grepRegions 提取命中正则的行及其前后 context 行，返回拼接后的内容和行号区间描述
+// 不相邻的区间之间以 "..." 分隔
+func grepRegions(content []byte, re *regexp.Regexp, context int) ([]byte, string) {
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i + context
+		if end >= len(lines) {
+			end = len(lines) - 1
+		}
+		for j := start; j <= end; j++ {
+			keep[j] = true
+		}
+	}
+
+	var buf bytes.Buffer
+	var ranges []string
+	for i := 0; i < len(lines); i++ {
+		if !keep[i] {
+			continue
+		}
+		start := i
+		for i+1 < len(lines) && keep[i+1] {
+			i++
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("...\n")
+		}
+		for j := start; j <= i; j++ {
+			buf.WriteString(lines[j])
+			buf.WriteString("\n")
+		}
+		if start == i {
+			ranges = append(ranges, fmt.Sprintf("%d", start+1))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", start+1, i+1))
+		}
+	}
+	return buf.Bytes(), strings.Join(ranges, ", ")
+}
@@ -0,0 +1,27 @@
+package main
+
+import "os"
+
+// hardlinkFirst 硬链接文件的标识 -> 第一次输出时的逻辑路径
+var hardlinkFirst = map[string]string{}
+
+// hardlinkOf 被跳过的硬链接副本 (文件系统路径) -> 已输出的同一文件的逻辑路径，目录树中据此标注
+var hardlinkOf = map[string]string{}
+
+// checkHardlink 文件有多个硬链接且已经以其他路径输出时返回该路径；
+// 否则登记 rel 为该文件的首次出现。符号链接不在此处理
+func checkHardlink(path string, rel string, d os.DirEntry) (string, bool) {
+	if d.Type()&os.ModeSymlink != 0 {
+		return "", false
+	}
+	id, links, ok := fileIdentity(path)
+	if !ok || links < 2 {
+		return "", false
+	}
+	if first, dup := hardlinkFirst[id]; dup {
+		hardlinkOf[path] = first
+		return first, true
+	}
+	hardlinkFirst[id] = rel
+	return "", false
+}
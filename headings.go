@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+)
+
+// docHeading 文档级章节 (Project Structure、File Contents 等) 的标题，比文件章节高一级
+func (w *docWriter) docHeading(title string) string {
+	return w.heading(config.HeadingLevel-1, title)
+}
+
+// subHeading 文件章节及与其同级的条目标题，级别由 --heading-level 指定
+func (w *docWriter) subHeading(title string) string {
+	return w.heading(config.HeadingLevel, title)
+}
+
+// writeContentsHeading 文件章节之前的 "File Contents" 标题
+func (w *docWriter) writeContentsHeading() {
+	w.WriteString(w.contentsHeading(config.HeadingLevel - 1))
+}
+
+// fileSectionTitle 文件章节的标题；--numbered-headings 时以写入顺序编号，如 "12. src/util.go"
+func (w *docWriter) fileSectionTitle(displayPath string, number int) string {
+	if config.NumberedHeadings {
+		return fmt.Sprintf("%d. %s", number, sanitizeHeading(displayPath))
+	}
+	return w.fileHeadingText(displayPath)
+}
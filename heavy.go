@@ -0,0 +1,76 @@
+package main
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// heavyMinTokens 单个文件低于该 token 数时不提示，避免小项目中每个文件都超过比例
+const heavyMinTokens = 2000
+
+// writtenFile 已写入输出的文件及其估算 token 数，用于结束时检查占比过高的文件
+type writtenFile struct {
+	Path   string
+	Rel    string
+	Tokens int64
+}
+
+// writtenFiles 本次运行写入的全部文件
+var writtenFiles []writtenFile
+
+// recordWritten 记录写入的文件
+func recordWritten(fc *fileContent) {
+	if config.WarnShare <= 0 {
+		return
+	}
+	writtenFiles = append(writtenFiles, writtenFile{fc.Path, fc.RelPath, fc.Tokens})
+}
+
+// suggestFilter 为占比过高的文件给出过滤规则建议：压缩产物与数据文件按后缀，其它按路径
+func suggestFilter(rel string) string {
+	// 逻辑路径以根目录名开头，过滤规则相对于根目录
+	if _, rest, ok := strings.Cut(rel, "/"); ok {
+		rel = rest
+	}
+	name := path.Base(rel)
+	ext := strings.ToLower(path.Ext(name))
+	if strings.Contains(strings.ToLower(name), ".min.") {
+		return "*.min" + ext
+	}
+	switch ext {
+	case ".json", ".csv", ".tsv", ".map", ".svg", ".xml", ".snap", ".lock":
+		if dir := path.Dir(rel); dir != "." {
+			return dir + "/*" + ext
+		}
+		return "*" + ext
+	}
+	return rel
+}
+
+// warnHeavyFiles 单个文件占全部文件内容 token 数的比例超过 --warn-share 时给出提示；
+// 只是建议，不计入警告数 (不影响 --strict 的退出码)
+func warnHeavyFiles() {
+	if config.WarnShare <= 0 || len(writtenFiles) < 2 {
+		return
+	}
+	var total int64
+	for _, f := range writtenFiles {
+		total += f.Tokens
+	}
+	if total == 0 {
+		return
+	}
+	heavy := append([]writtenFile(nil), writtenFiles...)
+	sort.SliceStable(heavy, func(i, j int) bool {
+		return heavy[i].Tokens > heavy[j].Tokens
+	})
+	for _, f := range heavy {
+		share := float64(f.Tokens) * 100 / float64(total)
+		if share <= config.WarnShare || f.Tokens < heavyMinTokens {
+			break
+		}
+		logf("[WARN] %s 占全部文件内容的 %.0f%% (约 %d tokens)，如无必要可添加过滤: -f '%s'\n",
+			f.Path, share, f.Tokens, suggestFilter(f.Rel))
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// hookSummary 通过标准输入以 JSON 传给钩子命令的运行概况
+type hookSummary struct {
+	Stage          string   `json:"stage"` // pre 或 post
+	Output         string   `json:"output"`
+	Dirs           []string `json:"dirs"`
+	Files          int      `json:"files"`
+	Bytes          int64    `json:"bytes"`
+	Tokens         int64    `json:"tokens"`
+	Warnings       int      `json:"warnings"`
+	EncodingErrors int      `json:"encoding_errors"`
+	WalkErrors     int      `json:"walk_errors"`
+	ExitCode       int      `json:"exit_code"`
+}
+
+// newHookSummary 根据当前统计信息生成概况
+func newHookSummary(stage string, outPath string, dirs []string, code int) hookSummary {
+	s := hookSummary{
+		Stage:          stage,
+		Output:         outPath,
+		Dirs:           dirs,
+		Files:          stats.Included,
+		Warnings:       stats.Warnings,
+		EncodingErrors: stats.EncodingErrors,
+		WalkErrors:     stats.WalkErrors,
+		ExitCode:       code,
+	}
+	if output != nil {
+		s.Bytes, s.Tokens = output.bytes, output.Tokens()
+	}
+	return s
+}
+
+// runHook 通过 shell 执行 --pre-hook/--post-hook 命令：
+// 概况以 JSON 写入标准输入，同时设置 DIR2TXT_* 环境变量，命令的输出直接显示
+func runHook(command string, summary hookSummary) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"DIR2TXT_HOOK="+summary.Stage,
+		"DIR2TXT_OUTPUT="+summary.Output,
+		"DIR2TXT_FILES="+strconv.Itoa(summary.Files),
+		"DIR2TXT_BYTES="+strconv.FormatInt(summary.Bytes, 10),
+		"DIR2TXT_TOKENS="+strconv.FormatInt(summary.Tokens, 10),
+		"DIR2TXT_EXIT_CODE="+strconv.Itoa(summary.ExitCode),
+	)
+	logf("执行 %s-hook: %s\n", summary.Stage, command)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s-hook 执行失败: %v", summary.Stage, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+func init() {
+	RegisterRenderer("json", &jsonRenderer{})
+}
+
+// jsonRenderer --format json：整个输出为一个 JSON 数组，每个文件一个对象，便于脚本处理
+type jsonRenderer struct {
+	count int // 已写入的文件数，决定元素之间的逗号
+}
+
+// jsonFile --format json 中的一个文件
+type jsonFile struct {
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Size     int64  `json:"size"`
+	ModTime  string `json:"mod_time"`
+	Content  string `json:"content"`
+}
+
+func (*jsonRenderer) Ext() string { return ".json" }
+
+func (r *jsonRenderer) Begin(w io.Writer) error {
+	r.count = 0
+	_, err := io.WriteString(w, "[")
+	return err
+}
+
+func (r *jsonRenderer) WriteFile(w io.Writer, f File) error {
+	data, err := json.Marshal(jsonFile{
+		Path:     f.RelPath,
+		Language: f.Lang,
+		Size:     f.Size,
+		ModTime:  f.ModTime.UTC().Format(time.RFC3339),
+		Content:  string(f.Content),
+	})
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if r.count == 0 {
+		sep = "\n  "
+	}
+	r.count++
+	_, err = fmt.Fprintf(w, "%s%s", sep, data)
+	return err
+}
+
+func (r *jsonRenderer) End(w io.Writer) error {
+	end := "\n]\n"
+	if r.count == 0 {
+		end = "]\n"
+	}
+	_, err := io.WriteString(w, end)
+	return err
+}
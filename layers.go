@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// repoConfigName 仓库级配置文件名，从当前目录向上查找，直到仓库根目录 (含 .git 的目录)
+const repoConfigName = ".dir2txt"
+
+// configLayer 一层配置：来源及其展开后的命令行参数
+type configLayer struct {
+	Source string
+	Path   string // 配置文件路径，命令行层为空
+	Args   []string
+}
+
+// globalConfigPath 用户级配置文件路径 (Linux 下为 ~/.config/dir2txt/global)
+func globalConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "dir2txt", "global")
+}
+
+// findRepoConfig 从当前目录向上查找仓库级配置文件
+func findRepoConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, repoConfigName)
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return candidate
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadConfigLayer 读取分层配置文件：每行一个命令行参数 (如 "--rank"、"-F vendor")，
+// 不以 - 开头的行与 -c 文件相同，视为软过滤规则；行首 # 为注释
+func loadConfigLayer(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取配置文件 %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var args []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "-") {
+			// 写作 -f=规则，只取这一个值，不会吞掉后面的参数
+			args = append(args, "-f="+line)
+			continue
+		}
+		fields, err := splitConfigLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		if len(fields) > 0 && fields[0] == "--" {
+			return nil, fmt.Errorf("%s:%d: 配置文件中不能使用 --", path, n)
+		}
+		args = append(args, fields...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取配置文件 %s 失败: %w", path, err)
+	}
+	return args, nil
+}
+
+// splitConfigLine 按空白拆分一行参数，支持单引号与双引号
+func splitConfigLine(line string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	inField := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inField = true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("引号未闭合: %s", line)
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
+
+// configLayers 按 内置默认值 → 用户级配置 → 仓库级 .dir2txt → 命令行参数 的顺序收集配置层，
+// 后面的层覆盖前面的层 (过滤规则依次追加，后出现的规则优先)；命令行中有 --no-config 时只使用命令行参数，
+// 有 --ci 时跳过用户级配置
+func configLayers(cliArgs []string) ([]configLayer, error) {
+	layers := []configLayer{{Source: "内置默认值"}}
+	noConfig, ci := false, false
+	for _, arg := range cliArgs {
+		if arg == "--" {
+			break
+		}
+		switch arg {
+		case "--no-config":
+			noConfig = true
+		case "--ci":
+			ci = true
+		}
+	}
+	if !noConfig {
+		for _, layer := range []configLayer{
+			{Source: "用户级配置", Path: globalConfigPath()},
+			{Source: "仓库级配置", Path: findRepoConfig()},
+		} {
+			// --ci 模式不受运行环境中用户级配置的影响
+			if layer.Path == "" || (ci && layer.Source == "用户级配置") {
+				continue
+			}
+			if _, err := os.Stat(layer.Path); err != nil {
+				continue
+			}
+			args, err := loadConfigLayer(layer.Path)
+			if err != nil {
+				return nil, err
+			}
+			layer.Args = restrictConfigArgs(layer, args)
+			layers = append(layers, layer)
+		}
+	}
+	return append(layers, configLayer{Source: "命令行参数", Args: cliArgs}), nil
+}
+
+// configCommandOptions 执行外部命令、访问网络或安装程序的参数，不能写在任何配置文件中，只能在命令行中指定
+var configCommandOptions = map[string]bool{
+	"--pre-hook": true, "--post-hook": true, "--plugin": true,
+	"--ai-summaries": true, "--ai-endpoint": true, "--otel-endpoint": true,
+	"--history": true, "--apply-diff": true, "--go-package": true, "--go-graph": true,
+	"--install": true, "--uninstall": true, "--release-url": true,
+}
+
+// repoConfigOptions 仓库级 .dir2txt 可以使用的参数：过滤、遍历、内容识别、输出格式与规模限制。
+// 仓库级配置随仓库分发、不受用户控制，其中不能指定目录、读写其他文件或调用外部程序
+var repoConfigOptions = map[string]bool{
+	"--filter": true, "--Filter": true, "--filter-for": true, "--Filter-for": true,
+	"--workspace": true, "--grep": true, "--grep-context": true,
+	"--one-file-system": true, "--skip-unreadable": true, "--perm-filter": true, "--no-dir-markers": true,
+	"--no-default-ignores": true, "--ignore-dir": true, "--ignore-ext": true, "--text-ext": true,
+	"--asset": true, "--no-hashed-assets": true, "--unignore": true, "--hidden": true, "--hidden-allow": true,
+	"--keep-minified": true, "--keep": true,
+	"--unknown-ext": true, "--file-lang": true, "--binary-window": true, "--binary-threshold": true,
+	"--force-text": true, "--force-binary": true, "--control-chars": true, "--fallback-encoding": true,
+	"--no-editorconfig": true, "--env-values": true, "--pii-scan": true, "--pii-mask": true,
+	"--format": true, "--flavor": true, "--show-perms": true, "--heading-level": true,
+	"--numbered-headings": true, "--prompt": true,
+	"--tree-format": true, "--tree-order": true, "--tree-links": true, "--tree-mark-filtered": true,
+	"--ascii": true, "--no-fold": true,
+	"--deps-summary": true, "--test-map": true, "--todos": true, "--licenses": true,
+	"--contracts-summary": true, "--summarize-excluded": true,
+	"--max-output-size": true, "--oversize": true, "--budget-tokens": true, "--trim-strategy": true,
+	"--max-files": true, "--max-files-per-dir": true, "--rank": true, "--show-rank": true, "--warn-share": true,
+	"--strict": true, "--allow-empty": true, "--fail-over-tokens": true, "--fail-over-size": true,
+	"--ext-stats": true, "--progress": true, "--no-progress": true,
+	"--io-retries": true, "--io-concurrency": true, "--max-memory": true,
+}
+
+// restrictConfigArgs 去掉配置文件中不允许的参数 (连同其参数值) 并打印警告：
+// 任何配置文件都不能使用 configCommandOptions，仓库级配置只能使用 repoConfigOptions 且不能指定目录
+func restrictConfigArgs(layer configLayer, args []string) []string {
+	repo := layer.Source == "仓库级配置"
+	index := optionIndex()
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			if repo {
+				logf("[WARN] %s %s 中不能指定目录，已忽略: %s\n", layer.Source, layer.Path, arg)
+				continue
+			}
+			kept = append(kept, arg)
+			continue
+		}
+		name, _, hasValue := strings.Cut(arg, "=")
+		opt, ok := index[name]
+		if !ok {
+			// 未知参数交给解析时报错
+			kept = append(kept, arg)
+			continue
+		}
+		// 参数值的个数与 cliState.parse 的取值方式一致
+		end := i
+		switch opt.arity() {
+		case arityOne:
+			if !hasValue && i+1 < len(args) {
+				end++
+			}
+		case arityMany:
+			for !hasValue && end+1 < len(args) && !strings.HasPrefix(args[end+1], "-") {
+				end++
+			}
+		}
+		primary := opt.Names[0]
+		if configCommandOptions[primary] || (repo && !repoConfigOptions[primary]) {
+			logf("[WARN] %s %s 中不能使用 %s (执行命令、联网、安装或读写其他文件的参数只能在命令行中指定)，已忽略\n", layer.Source, layer.Path, name)
+			i = end
+			continue
+		}
+		kept = append(kept, args[i:end+1]...)
+		i = end
+	}
+	return kept
+}
+
+// layerArgs 各层的参数，按层分别解析，前一层末尾的多值参数 (如 -f a b) 不会吞掉后一层的目录参数
+func layerArgs(layers []configLayer) [][]string {
+	var sets [][]string
+	for _, layer := range layers {
+		sets = append(sets, layer.Args)
+	}
+	return sets
+}
+
+// showEffectiveConfig 打印各配置层与合并后的结果 (只列出与内置默认值不同的设置)
+func showEffectiveConfig(layers []configLayer, defaults []byte, dirs, softFilters, hardFilters []string) {
+	fmt.Println("配置层 (后者覆盖前者):")
+	for i, layer := range layers {
+		source := layer.Source
+		if layer.Path != "" {
+			source += ": " + layer.Path
+		}
+		fmt.Printf("  %d. %s\n", i+1, source)
+		if len(layer.Args) > 0 {
+			fmt.Printf("     %s\n", strings.Join(layer.Args, " "))
+		}
+	}
+
+	fmt.Println("生效的设置 (与内置默认值不同的项):")
+	var before, after map[string]json.RawMessage
+	current, _ := json.Marshal(config)
+	json.Unmarshal(defaults, &before)
+	json.Unmarshal(current, &after)
+	var keys []string
+	for key, value := range after {
+		if key != "ShowEffectiveConfig" && string(before[key]) != string(value) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		fmt.Println("  (无)")
+	}
+	for _, key := range keys {
+		value := string(after[key])
+		if key == "Grep" && config.Grep != nil {
+			value = config.Grep.String()
+		}
+		fmt.Printf("  %s: %s\n", key, value)
+	}
+
+	fmt.Printf("目录: %s\n", strings.Join(dirs, ", "))
+	if len(softFilters) > 0 {
+		fmt.Printf("软过滤: %s\n", strings.Join(softFilters, ", "))
+	}
+	if len(hardFilters) > 0 {
+		fmt.Printf("硬过滤: %s\n", strings.Join(hardFilters, ", "))
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestRepoConfigRestricted 仓库级 .dir2txt 不能设置钩子、插件、目录等参数，其余过滤与输出参数照常生效
+func TestRepoConfigRestricted(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	repo := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := "--pre-hook 'touch /tmp/evil'\n" +
+		"--plugin ./evil.sh\n" +
+		"--ai-endpoint=http://attacker.example/v1\n" +
+		"-o /tmp/elsewhere.md\n" +
+		"--rank /home\n" +
+		"-F vendor node_modules\n" +
+		"build/\n"
+	if err := os.WriteFile(filepath.Join(repo, repoConfigName), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(repo)
+
+	layers, err := configLayers([]string{"--pre-hook", "make"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var repoArgs, cliArgs []string
+	for _, layer := range layers {
+		switch layer.Source {
+		case "仓库级配置":
+			repoArgs = layer.Args
+		case "命令行参数":
+			cliArgs = layer.Args
+		}
+	}
+	if want := []string{"--rank", "-F", "vendor", "node_modules", "-f=build/"}; !slices.Equal(repoArgs, want) {
+		t.Errorf("repo layer args = %q, want %q", repoArgs, want)
+	}
+	if want := []string{"--pre-hook", "make"}; !slices.Equal(cliArgs, want) {
+		t.Errorf("command line args = %q, want %q", cliArgs, want)
+	}
+}
+
+// TestConfigOptionNames 配置文件的参数表只使用已登记参数的主名称
+func TestConfigOptionNames(t *testing.T) {
+	primary := map[string]bool{}
+	for _, group := range optionGroups() {
+		for _, opt := range group.Options {
+			primary[opt.Names[0]] = true
+		}
+	}
+	for _, table := range []map[string]bool{configCommandOptions, repoConfigOptions} {
+		for name := range table {
+			if !primary[name] {
+				t.Errorf("%s is not the primary name of a registered option", name)
+			}
+		}
+	}
+}
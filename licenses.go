@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// licenseFile 找到的许可证文件
+type licenseFile struct {
+	Path      string // 显示路径
+	License   string // 识别出的许可证 (SPDX 标识)，无法识别时为 Unknown
+	Copyright string // 第一条版权声明
+}
+
+// licenseFilePrefixes 许可证相关文件名的前缀 (大写比较)，如 LICENSE、LICENSE-MIT、COPYING.txt、NOTICE.md
+var licenseFilePrefixes = []string{"LICENSE", "LICENCE", "COPYING", "NOTICE", "UNLICENSE"}
+
+// licenseSignatures 按顺序匹配的许可证特征文本 (小写)，需全部出现
+var licenseSignatures = []struct {
+	spdx     string
+	keywords []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software"}},
+	{"BSL-1.0", []string{"boost software license"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+}
+
+var copyrightLineRe = regexp.MustCompile(`(?i)^\s*(copyright|\(c\)|©)\s`)
+
+// isLicenseFile 判断文件名是否为许可证/版权声明文件
+func isLicenseFile(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, prefix := range licenseFilePrefixes {
+		if upper == prefix {
+			return true
+		}
+		if rest, ok := strings.CutPrefix(upper, prefix); ok && strings.ContainsAny(rest[:1], ".-_") {
+			return true
+		}
+	}
+	return false
+}
+
+// identifyLicense 根据特征文本识别许可证
+func identifyLicense(content []byte) string {
+	text := strings.ToLower(strings.Join(strings.Fields(string(content)), " "))
+	for _, sig := range licenseSignatures {
+		matched := true
+		for _, kw := range sig.keywords {
+			if !strings.Contains(text, kw) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return sig.spdx
+		}
+	}
+	return "Unknown"
+}
+
+// firstCopyright 返回第一条版权声明行
+func firstCopyright(content []byte) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		if copyrightLineRe.MatchString(line) && !strings.Contains(strings.ToLower(line), "copyright notice") {
+			return strings.TrimSpace(line)
+		}
+	}
+	return ""
+}
+
+// collectLicenses 查找所有许可证文件；与内容输出不同，这里会进入被过滤的目录 (如 vendor、node_modules)，只跳过版本控制目录
+func collectLicenses(ctx context.Context, dirs []string) []licenseFile {
+	var files []licenseFile
+	for _, dir := range dirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		rootName := filepath.Base(absDir)
+		walkFollowSymlinks(ctx, absDir, func(logicalRel string, fullPath string, d os.DirEntry) error {
+			if d.IsDir() {
+				switch d.Name() {
+				case ".git", ".svn", ".hg":
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !isLicenseFile(d.Name()) {
+				return nil
+			}
+			content, err := os.ReadFile(fullPath)
+			if err != nil {
+				return nil
+			}
+			files = append(files, licenseFile{
+				Path:      rootName + "/" + filepath.ToSlash(logicalRel),
+				License:   identifyLicense(content),
+				Copyright: firstCopyright(content),
+			})
+			return nil
+		})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// writeLicensesSummary 输出许可证汇总：按许可证统计数量，并列出每个文件
+func writeLicensesSummary(files []licenseFile, writer *docWriter) {
+	writer.WriteString(writer.docHeading("Licenses"))
+	if len(files) == 0 {
+		writer.WriteString("No LICENSE, COPYING or NOTICE files found.\n\n")
+		writer.writeSeparator()
+		return
+	}
+
+	counts := map[string]int{}
+	var kinds []string
+	for _, f := range files {
+		if counts[f.License] == 0 {
+			kinds = append(kinds, f.License)
+		}
+		counts[f.License]++
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		if counts[kinds[i]] != counts[kinds[j]] {
+			return counts[kinds[i]] > counts[kinds[j]]
+		}
+		return kinds[i] < kinds[j]
+	})
+	var parts []string
+	for _, k := range kinds {
+		parts = append(parts, fmt.Sprintf("%s (%d)", k, counts[k]))
+	}
+	writer.WriteString("Summary: " + strings.Join(parts, ", ") + "\n\n")
+
+	var rows [][]string
+	for _, f := range files {
+		rows = append(rows, []string{f.Path, f.License, f.Copyright})
+	}
+	writer.writeTable([]string{"File", "License", "Copyright"}, rows)
+	writer.writeSeparator()
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	skipMarker = ".dir2txt-skip" // 目录中存在该文件时按硬过滤处理 (不出现在目录树中)
+	softMarker = ".dir2txt-soft" // 目录中存在该文件时按软过滤处理 (只在目录树中显示)
+)
+
+// dirMarker 返回目录中的过滤标记文件名，没有标记或 --no-dir-markers 时返回空
+func dirMarker(dir string) string {
+	if config.NoDirMarkers {
+		return ""
+	}
+	for _, marker := range []string{skipMarker, softMarker} {
+		if _, err := os.Lstat(filepath.Join(dir, marker)); err == nil {
+			return marker
+		}
+	}
+	return ""
+}
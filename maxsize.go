@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// errOutputTooLarge 预估的输出大小超过 --max-output-size
+var errOutputTooLarge = errors.New("预估输出大小超过 --max-output-size 限制")
+
+// outlineMode 预估大小超限且 --oversize outline 时，文件内容只输出声明行
+var outlineMode bool
+
+// projectedSize 预估的输出字节数，超限时在文档与日志中说明
+var projectedSize int64
+
+// outlineHeadLines 没有识别出声明行时保留的开头行数
+const outlineHeadLines = 10
+
+// projectOutputSize 在写入前预估文档大小：文件大小之和，加上每个文件的标题、代码块与目录树条目开销
+func projectOutputSize(candidates []candidateFile) int64 {
+	var total int64
+	for _, c := range candidates {
+		info, err := os.Stat(c.Path)
+		if err != nil {
+			continue
+		}
+		total += info.Size() + int64(2*len(c.Path)+len(c.Rel)) + 40
+	}
+	return total
+}
+
+// checkOutputSize 按 --max-output-size 检查预估大小：超限时中止，或在 --oversize outline 下切换为大纲模式
+func checkOutputSize(candidates []candidateFile) error {
+	projectedSize = projectOutputSize(candidates)
+	if projectedSize <= config.MaxOutputSize {
+		return nil
+	}
+	if config.Oversize != "outline" {
+		return fmt.Errorf("%w: 预估 %s，限制 %s (可使用 --oversize outline 只输出声明，或添加过滤规则)",
+			errOutputTooLarge, formatSize(projectedSize), formatSize(config.MaxOutputSize))
+	}
+	outlineMode = true
+	logf("[WARN] 预估输出 %s 超过 --max-output-size %s，切换为大纲模式 (只输出声明行)\n",
+		formatSize(projectedSize), formatSize(config.MaxOutputSize))
+	stats.Warnings++
+	return nil
+}
+
+// checkOutlineSize 大纲模式按实际写入的大小复查：大纲仍超过 --max-output-size 时中止，不留下超限的输出
+func checkOutlineSize(writer *docWriter) error {
+	if !outlineMode {
+		return nil
+	}
+	written := output.bytes + int64(writer.Buffered())
+	if written <= config.MaxOutputSize {
+		return nil
+	}
+	return fmt.Errorf("%w: 大纲模式的输出已达 %s，仍超过限制 %s (请添加过滤规则)",
+		errOutputTooLarge, formatSize(written), formatSize(config.MaxOutputSize))
+}
+
+// writeOutlineNotice 在文件内容之前说明当前为大纲模式
+func writeOutlineNotice(writer *docWriter) {
+	if !outlineMode {
+		return
+	}
+	writer.WriteString(writer.blockQuote(fmt.Sprintf("Note: the full document was projected at %s, over the %s limit; file contents below are outlines (declarations only).",
+		formatSize(projectedSize), formatSize(config.MaxOutputSize))))
+}
+
+// fileOutline 只保留函数、类型等声明行；没有声明的文件保留开头几行
+func fileOutline(content []byte) []byte {
+	lines := strings.Split(string(content), "\n")
+	var sb strings.Builder
+	for _, line := range lines {
+		if outlineDeclRe.MatchString(line) {
+			sb.WriteString(strings.TrimRight(line, " \t{") + "\n")
+		}
+	}
+	if sb.Len() == 0 {
+		if len(lines) > outlineHeadLines {
+			lines = append(lines[:outlineHeadLines], "...")
+		}
+		return []byte(strings.Join(lines, "\n"))
+	}
+	return []byte(strings.TrimSuffix(sb.String(), "\n"))
+}
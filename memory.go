@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// memoryBudget --max-memory 下已缓冲在内存中的文件内容字节数 (相当于按字节计数的信号量)
+var memoryBudget struct {
+	mu       sync.Mutex
+	used     int64
+	spillDir string // 溢出文件所在的临时目录，首次溢出时创建
+	spilled  int
+}
+
+// tryReserveMemory 尝试占用 n 字节的内存预算，超出 --max-memory 时返回 false
+func tryReserveMemory(n int64) bool {
+	if config.MaxMemory <= 0 {
+		return true
+	}
+	memoryBudget.mu.Lock()
+	defer memoryBudget.mu.Unlock()
+	if memoryBudget.used+n > config.MaxMemory {
+		return false
+	}
+	memoryBudget.used += n
+	return true
+}
+
+// releaseMemory 归还 n 字节的内存预算
+func releaseMemory(n int64) {
+	if config.MaxMemory <= 0 {
+		return
+	}
+	memoryBudget.mu.Lock()
+	memoryBudget.used -= n
+	memoryBudget.mu.Unlock()
+}
+
+// holdContent 在需要先读取全部文件的模式下保留文件内容：
+// 内存预算足够时留在内存中，否则写入临时文件，使用时再读回
+func holdContent(fc *fileContent) {
+	size := int64(len(fc.Content))
+	if tryReserveMemory(size) {
+		fc.Reserved = size
+		return
+	}
+	memoryBudget.mu.Lock()
+	defer memoryBudget.mu.Unlock()
+	if memoryBudget.spillDir == "" {
+		dir, err := os.MkdirTemp("", "dir2txt-spill-")
+		if err != nil {
+			logf("[WARN] 无法创建临时目录，内容保留在内存中: %v\n", err)
+			stats.Warnings++
+			return
+		}
+		memoryBudget.spillDir = dir
+	}
+	memoryBudget.spilled++
+	spill := filepath.Join(memoryBudget.spillDir, strconv.Itoa(memoryBudget.spilled))
+	if err := os.WriteFile(spill, fc.Content, 0o600); err != nil {
+		logf("[WARN] 无法写入临时文件，内容保留在内存中: %v\n", err)
+		stats.Warnings++
+		return
+	}
+	fc.Spill = spill
+	fc.Content = nil
+}
+
+// releaseContent 文件写入输出或被丢弃后归还内存预算、删除溢出文件；重复调用不会重复归还
+func releaseContent(fc *fileContent) {
+	if fc.Spill != "" {
+		os.Remove(fc.Spill)
+		fc.Spill = ""
+	}
+	releaseMemory(fc.Reserved)
+	fc.Reserved = 0
+}
+
+// data 返回文件内容，已溢出到临时文件时从磁盘读回
+func (fc *fileContent) data() []byte {
+	if fc.Spill == "" {
+		return fc.Content
+	}
+	content, err := os.ReadFile(fc.Spill)
+	if err != nil {
+		logf("[WARN] 无法读取临时文件: %s (%v)\n", fc.Spill, err)
+		stats.Warnings++
+		return nil
+	}
+	return content
+}
+
+// cleanupSpill 删除溢出用的临时目录
+func cleanupSpill() {
+	memoryBudget.mu.Lock()
+	defer memoryBudget.mu.Unlock()
+	if memoryBudget.spillDir != "" {
+		logf("内存预算不足，%d 个文件曾暂存到临时目录\n", memoryBudget.spilled)
+		os.RemoveAll(memoryBudget.spillDir)
+		memoryBudget.spillDir = ""
+	}
+}
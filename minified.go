@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// minifiableExts 会被压缩打包的前端资源后缀
+var minifiableExts = map[string]bool{".js": true, ".mjs": true, ".cjs": true, ".css": true}
+
+const (
+	minifiedMinSize    = 1024 // 小于该大小的文件不按行长判断
+	minifiedAvgLine    = 300  // 平均行长超过该值视为压缩代码
+	minifiedLongLine   = 500  // 超过该长度的行计入长行
+	minifiedLongRatio  = 0.5  // 长行字节占比超过该值视为压缩代码
+	minifiedMapAvgLine = 120  // 带 sourceMappingURL 注释时平均行长的阈值
+	minifiedSourceMap  = "# sourceMappingURL="
+)
+
+// isMinified 判断 JS/CSS 文件是否为压缩产物：文件名含 .min.，或平均行长、长行占比过高，
+// 或带有 sourcemap 注释且行长明显偏长。与生成代码检测 (文件头标记) 相互独立
+func isMinified(path string, content []byte) bool {
+	name := strings.ToLower(filepath.Base(path))
+	if !minifiableExts[filepath.Ext(name)] {
+		return false
+	}
+	if strings.Contains(name, ".min.") {
+		return true
+	}
+	if len(content) < minifiedMinSize {
+		return false
+	}
+
+	lines := bytes.Split(bytes.TrimRight(content, "\n"), []byte("\n"))
+	var longBytes int
+	for _, line := range lines {
+		if len(line) > minifiedLongLine {
+			longBytes += len(line)
+		}
+	}
+	avg := len(content) / len(lines)
+	if avg > minifiedAvgLine || float64(longBytes)/float64(len(content)) > minifiedLongRatio {
+		return true
+	}
+	return avg > minifiedMapAvgLine && bytes.Contains(content, []byte(minifiedSourceMap))
+}
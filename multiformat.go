@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// formatOutput --format 多次指定时，主输出之外的一份输出；与主输出共用同一次遍历，
+// 每个文件只读取、转码与处理一次，再按各自的格式写入
+type formatOutput struct {
+	Format     string
+	Path       string // 最终输出路径
+	partial    string // 写入中的临时文件
+	file       *os.File
+	compressor io.WriteCloser
+	writer     *docWriter
+}
+
+// extraOutputs 本次运行的其余输出格式，顺序与 --format 出现的顺序一致
+var extraOutputs []*formatOutput
+
+// extraOutputPath 其余格式的输出路径：与主输出同名，只替换格式后缀 (压缩后缀保持不变)
+func extraOutputPath(primary string, format string) string {
+	base, method := trimCompressExt(primary)
+	base = strings.TrimSuffix(base, filepath.Ext(base)) + outputExts[format]
+	if method != "" {
+		base = compressedPath(base, method)
+	}
+	return base
+}
+
+// planExtraOutputs 确定其余格式的输出路径，并在遍历时排除这些文件
+func planExtraOutputs(primary string) error {
+	for _, format := range config.ExtraFormats {
+		path := extraOutputPath(primary, format)
+		if path == primary {
+			return fmt.Errorf("--format %s 的输出路径与主输出相同: %s", format, path)
+		}
+		if config.NoOverwrite {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("输出文件已存在 (--no-overwrite): %s", path)
+			}
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			priorOutputs[abs] = true
+			priorOutputs[abs+".bak"] = true
+		}
+		extraOutputs = append(extraOutputs, &formatOutput{Format: format, Path: path, partial: partialPath(path)})
+	}
+	return nil
+}
+
+// openExtraOutputs 创建其余格式的临时输出文件
+func openExtraOutputs() error {
+	for _, o := range extraOutputs {
+		f, err := os.Create(o.partial)
+		if err != nil {
+			discardExtraOutputs()
+			return err
+		}
+		o.file = f
+		var sink io.Writer = f
+		if config.Compress != "" {
+			if o.compressor, err = newCompressor(f, config.Compress); err != nil {
+				discardExtraOutputs()
+				return err
+			}
+			sink = o.compressor
+		}
+		o.writer = newDocWriter(bufio.NewWriter(sink), o.Format)
+	}
+	return nil
+}
+
+// forEachFormat 先写入主输出 writer，再依次写入其余格式的输出，每份输出按各自的格式排版
+func forEachFormat(writer *docWriter, write func(w *docWriter)) {
+	write(writer)
+	for _, o := range extraOutputs {
+		write(o.writer)
+	}
+}
+
+// closeExtraOutputs 刷新并关闭其余格式的输出文件
+func closeExtraOutputs() {
+	for _, o := range extraOutputs {
+		if o.file == nil {
+			continue
+		}
+		o.writer.Flush()
+		if o.compressor != nil {
+			o.compressor.Close()
+		}
+		o.file.Close()
+		o.file = nil
+	}
+}
+
+// discardExtraOutputs 运行失败或中断时删除其余格式的临时文件
+func discardExtraOutputs() {
+	closeExtraOutputs()
+	for _, o := range extraOutputs {
+		os.Remove(o.partial)
+	}
+}
+
+// commitExtraOutputs 将其余格式的临时文件改名为最终输出并记录
+func commitExtraOutputs() error {
+	closeExtraOutputs()
+	for _, o := range extraOutputs {
+		if err := commitOutput(o.partial, o.Path); err != nil {
+			return err
+		}
+		if err := recordOutput(o.Path); err != nil {
+			logf("[WARN] 无法记录输出文件: %v\n", err)
+		}
+		logf("%s 格式已写入: %s\n", o.Format, o.Path)
+	}
+	return nil
+}
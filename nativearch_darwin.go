@@ -0,0 +1,17 @@
+//go:build darwin
+
+package main
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// nativeArch 返回系统原生架构；在 Rosetta 2 下运行的 amd64 程序得到 arm64
+func nativeArch() string {
+	if translated, err := unix.SysctlUint32("sysctl.proc_translated"); err == nil && translated == 1 {
+		return "arm64"
+	}
+	return runtime.GOARCH
+}
@@ -0,0 +1,27 @@
+//go:build !windows && !darwin
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// unameArches uname -m 的机器名与 GOARCH 的对应关系
+var unameArches = map[string]string{
+	"x86_64":  "amd64",
+	"amd64":   "amd64",
+	"aarch64": "arm64",
+	"arm64":   "arm64",
+	"i386":    "386",
+	"i686":    "386",
+	"armv7l":  "arm",
+	"armv6l":  "arm",
+	"riscv64": "riscv64",
+}
+
+// nativeArch 按内核报告的机器类型返回系统原生架构 (如 arm64 内核上运行的 32 位 arm 程序)，未知时返回空串
+func nativeArch() string {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return ""
+	}
+	return unameArches[unix.ByteSliceToString(uts.Machine[:])]
+}
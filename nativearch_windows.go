@@ -0,0 +1,26 @@
+//go:build windows
+
+package main
+
+import (
+	"debug/pe"
+
+	"golang.org/x/sys/windows"
+)
+
+// nativeArch 返回系统原生架构 (GOARCH 命名)；ARM64 Windows 上转译运行的 amd64 程序会得到 arm64，未知时返回空串
+func nativeArch() string {
+	var processMachine, nativeMachine uint16
+	if err := windows.IsWow64Process2(windows.CurrentProcess(), &processMachine, &nativeMachine); err != nil {
+		return ""
+	}
+	switch nativeMachine {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		return "amd64"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		return "arm64"
+	case pe.IMAGE_FILE_MACHINE_I386:
+		return "386"
+	}
+	return ""
+}
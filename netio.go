@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// ioRetryBase 第一次重试前的等待时间，之后每次翻倍
+const ioRetryBase = 200 * time.Millisecond
+
+// ioSlots 限制同时打开的文件与目录数 (--io-concurrency)，nil 表示不限制
+var ioSlots chan struct{}
+
+// initIOLimit 按 --io-concurrency 创建并发槽
+func initIOLimit() {
+	if config.IOConcurrency > 0 {
+		ioSlots = make(chan struct{}, config.IOConcurrency)
+	}
+}
+
+// withIORetry 执行一次文件系统操作；遇到瞬时错误时按指数退避重试 --io-retries 次
+func withIORetry(path string, op func() error) error {
+	delay := ioRetryBase
+	for attempt := 0; ; attempt++ {
+		if ioSlots != nil {
+			ioSlots <- struct{}{}
+		}
+		err := op()
+		if ioSlots != nil {
+			<-ioSlots
+		}
+		if err == nil || attempt >= config.IORetries || !isTransientIOError(err) {
+			return err
+		}
+		logf("[INFO] 暂时性 I/O 错误，%s 后重试 (%d/%d): %s (%v)\n", delay, attempt+1, config.IORetries, path, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// statFile os.Stat 的重试与限流版本
+func statFile(path string) (info os.FileInfo, err error) {
+	err = withIORetry(path, func() error {
+		info, err = os.Stat(path)
+		return err
+	})
+	return info, err
+}
+
+// readFile os.ReadFile 的重试与限流版本
+func readFile(path string) (content []byte, err error) {
+	err = withIORetry(path, func() error {
+		content, err = os.ReadFile(path)
+		return err
+	})
+	return content, err
+}
+
+// readDir os.ReadDir 的重试与限流版本
+func readDir(path string) (entries []os.DirEntry, err error) {
+	err = withIORetry(path, func() error {
+		entries, err = os.ReadDir(path)
+		return err
+	})
+	return entries, err
+}
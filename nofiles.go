@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+)
+
+// errNoFiles 过滤后没有任何文件内容可写入，不生成只有目录树的文档
+var errNoFiles = errors.New("no files included")
+
+// exclusionCounts 按原因统计未写入内容的文件数，用于没有文件被输出时的诊断
+var exclusionCounts = map[string]int{}
+
+// excludedDir 被整体跳过的目录；其中的文件数只在需要诊断时才统计
+type excludedDir struct {
+	Path   string
+	Reason string
+}
+
+var excludedDirs []excludedDir
+
+// noteExclusion 记录一个因 reason 未写入内容的文件
+func noteExclusion(reason string) {
+	exclusionCounts[reason]++
+}
+
+// noteExcludedDir 记录一个因 reason 被整体跳过的目录
+func noteExcludedDir(dir string, reason string) {
+	excludedDirs = append(excludedDirs, excludedDir{Path: dir, Reason: reason})
+}
+
+// printNoFilesDiagnostic 打印没有任何文件被写入时的原因分布，按排除的文件数从多到少列出
+func printNoFilesDiagnostic(limit int) {
+	counts := map[string]int{}
+	for reason, n := range exclusionCounts {
+		counts[reason] += n
+	}
+	for _, d := range excludedDirs {
+		filepath.WalkDir(d.Path, func(path string, entry fs.DirEntry, err error) error {
+			if err == nil && entry.Type().IsRegular() {
+				counts[d.Reason]++
+			}
+			return nil
+		})
+	}
+	total := 0
+	reasons := make([]string, 0, len(counts))
+	for reason, n := range counts {
+		total += n
+		reasons = append(reasons, reason)
+	}
+	if total == 0 {
+		errorf("[ERROR] 没有任何文件内容被写入输出: 输入目录中没有找到文件\n")
+		return
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if counts[reasons[i]] != counts[reasons[j]] {
+			return counts[reasons[i]] > counts[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	errorf("[ERROR] 没有任何文件内容被写入输出，共 %d 个文件被排除:\n", total)
+	for i, reason := range reasons {
+		if i == limit {
+			errorf("  ... 另有 %d 种原因\n", len(reasons)-limit)
+			break
+		}
+		errorf("  %3.0f%% (%d) %s\n", float64(counts[reason])*100/float64(total), counts[reason], reason)
+	}
+	errorf("提示: 运行日志中的 [SKIP] 行列出了每个文件的跳过原因；使用 --allow-empty 仍然输出只有目录树的文档\n")
+}
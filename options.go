@@ -0,0 +1,418 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// cliOption 一个命令行参数的定义；解析、帮助、help-full 与 man 页面均由此生成，新增参数时只需在 optionGroups 中登记
+type cliOption struct {
+	Names []string     // 参数名，第一个为主名称，其余为别名
+	Arg   string       // 值的占位符 (如 FILE)，同时决定取值方式：空为开关；以 [ 开头为可选值 (需写作 --x=V)；以 ... 结尾可接多个值
+	Key   string       // --show-effective-config 中对应的设置名，空表示不对应单一设置
+	Set   optionSetter // 校验参数值并写入 config 或解析状态
+	Usage string       // 说明，可包含多行
+}
+
+// optionGroup 帮助中的一组参数
+type optionGroup struct {
+	Title   string
+	Options []cliOption
+}
+
+// optionGroups 全部命令行参数，按功能分组
+func optionGroups() []optionGroup {
+	return []optionGroup{
+		{"输入与过滤", []cliOption{
+			{Names: []string{"--dir", "-d"}, Arg: "DIR...", Set: addDirs, Usage: "指定要扫描的目录，可重复；也可用位置参数追加目录。支持通配符 (如 'services/*/api')，由程序展开为多个目录；位置参数为文件时直接输出该文件，不遍历目录"},
+			{Names: []string{"--filter", "-f", "-filter"}, Arg: "PATTERN...", Set: addFields(func(s *cliState) *multiValue { return &s.softFilters }), Usage: "软过滤：仅跳过文件内容输出，目录和树仍显示；支持 * ? [] 与 ! 反向"},
+			{Names: []string{"--Filter", "-F", "-Filter"}, Arg: "PATTERN...", Set: addFields(func(s *cliState) *multiValue { return &s.hardFilters }), Usage: "硬过滤：目录树和文件内容都不显示；支持 * ? [] 与 ! 反向"},
+			{Names: []string{"--filter-for"}, Arg: "DIR PATTERN...", Key: "RootSoft", Set: setRootFilters(config.RootSoft), Usage: "只对指定根目录生效的软过滤"},
+			{Names: []string{"--Filter-for"}, Arg: "DIR PATTERN...", Key: "RootHard", Set: setRootFilters(config.RootHard), Usage: "只对指定根目录生效的硬过滤"},
+			{Names: []string{"--config", "-c"}, Arg: "FILE", Set: loadFilterFile(false), Usage: "指定配置文件路径 (默认作为软过滤); 行首 # 视为注释"},
+			{Names: []string{"-fc"}, Arg: "FILE", Set: loadFilterFile(false), Usage: "指定配置文件路径 (强制作为软过滤); 行首 # 视为注释"},
+			{Names: []string{"-Fc"}, Arg: "FILE", Set: loadFilterFile(true), Usage: "指定配置文件路径 (强制作为硬过滤); 行首 # 视为注释"},
+			{Names: []string{"--no-config"}, Set: func(s *cliState, name string, values []string) error { return nil }, Usage: "不读取用户级与仓库级配置文件"},
+			{Names: []string{"--show-effective-config"}, Key: "ShowEffectiveConfig", Set: setTrue(&config.ShowEffectiveConfig), Usage: "打印各配置层及合并后生效的设置，然后退出"},
+			{Names: []string{"--workspace", "-w"}, Arg: "NAME...", Key: "Workspaces", Set: appendValues(&config.Workspaces), Usage: "只扫描 monorepo 中指定的工作区包 (按第一个目录中的 pnpm-workspace.yaml、package.json workspaces、lerna.json、go.work、Cargo.toml [workspace] 解析路径)"},
+			{Names: []string{"--go-package"}, Arg: "PKG", Key: "GoPackages", Set: appendValues(&config.GoPackages), Usage: "只输出指定 Go 包的源文件 (通过 go list 解析，以第一个目录为模块根)，可重复"},
+			{Names: []string{"--with-deps"}, Key: "WithDeps", Set: setTrue(&config.WithDeps), Usage: "配合 --go-package，同时输出其在主模块内的依赖包"},
+			{Names: []string{"--apply-diff"}, Arg: "PATCH", Key: "ApplyDiff", Set: setString(&config.ApplyDiff), Usage: "读取统一 diff 补丁，只输出补丁本身及其涉及文件的补丁后内容 (以第一个目录为根)"},
+			{Names: []string{"--grep"}, Arg: "REGEX", Key: "Grep", Set: setGrep, Usage: "只包含内容命中该正则表达式的文件"},
+			{Names: []string{"--grep-context"}, Arg: "N", Key: "GrepContext", Set: setInt(&config.GrepContext, 0, math.MaxInt, "一个非负整数"), Usage: "配合 --grep，只输出命中行及其前后 N 行，而不是整个文件"},
+		}},
+		{"遍历与忽略规则", []cliOption{
+			{Names: []string{"--one-file-system", "-x"}, Key: "OneFileSystem", Set: setTrue(&config.OneFileSystem), Usage: "不跨越挂载点 (网络盘、容器 overlay、FUSE 等)，目录树中只显示挂载点本身"},
+			{Names: []string{"--skip-unreadable"}, Key: "SkipUnreadable", Set: setTrue(&config.SkipUnreadable), Usage: "无读取权限的文件/目录静默跳过 (不计为警告或遍历失败)，并在目录树中标注 (permission denied)"},
+			{Names: []string{"--perm-filter"}, Arg: "KINDS", Key: "PermFilter", Set: setPermFilter, Usage: "逗号分隔的 world-writable、setuid、setgid，命中的文件只在目录树中显示，不输出内容"},
+			{Names: []string{"--no-dir-markers"}, Key: "NoDirMarkers", Set: setTrue(&config.NoDirMarkers), Usage: "忽略目录标记文件 (默认: 含 .dir2txt-skip 的目录按硬过滤处理，含 .dir2txt-soft 的目录只在树中显示)"},
+			{Names: []string{"--no-default-ignores"}, Set: func(s *cliState, name string, values []string) error { s.noDefaultIgnores = true; return nil }, Usage: "清空内置的忽略目录、忽略后缀和忽略文件名列表"},
+			{Names: []string{"--ignore-dir"}, Arg: "NAME...", Key: "IgnoredDirs", Set: addFields(func(s *cliState) *multiValue { return &s.ignoreDirs }), Usage: "追加要忽略的目录名，可重复"},
+			{Names: []string{"--ignore-ext"}, Arg: "EXT...", Key: "IgnoredExts", Set: addFields(func(s *cliState) *multiValue { return &s.ignoreExts }), Usage: "追加只在树中显示、不读取内容的后缀 (如 .foo)，可重复"},
+			{Names: []string{"--text-ext"}, Arg: "EXT...", Key: "TextExts", Set: addFields(func(s *cliState) *multiValue { return &s.textExts }), Usage: "追加强制视为文本的后缀 (如 .bar)，可重复"},
+			{Names: []string{"--asset"}, Arg: "NAME...", Key: "AssetFiles", Set: addFields(func(s *cliState) *multiValue { return &s.assets }), Usage: "追加只在树中显示、不读取内容的文件名 (支持通配符，如 '*.gen.js')，可重复；默认已包含锁文件、*.map、*.bundle.js、*.chunk.js"},
+			{Names: []string{"--no-hashed-assets"}, Key: "HashedAssets", Set: func(s *cliState, name string, values []string) error { config.HashedAssets = false; return nil }, Usage: "输出带内容哈希的打包产物 (如 app.3f9c2a.js，默认只在树中显示)"},
+			{Names: []string{"--unignore"}, Arg: "NAME...", Set: addFields(func(s *cliState) *multiValue { return &s.unignore }), Usage: "从所有内置忽略列表中移除指定名称或后缀 (如 vendor、.github、.png)，可重复"},
+			{Names: []string{"--hidden"}, Arg: "POLICY", Key: "HiddenPolicy", Set: setString(&config.HiddenPolicy), Usage: "隐藏文件策略: include 完整输出; tree-only 只在树中显示; exclude 完全忽略 (默认)"},
+			{Names: []string{"--hidden-allow"}, Arg: "NAME...", Key: "HiddenAllow", Set: addKeys(config.HiddenAllow), Usage: "追加始终输出的隐藏文件名 (支持通配符)，默认已包含 .env .gitignore .github .dockerignore 等"},
+			{Names: []string{"--keep-minified"}, Key: "KeepMinified", Set: setTrue(&config.KeepMinified), Usage: "输出压缩后的 JS/CSS (默认按文件名 .min.、平均行长、长行占比与 sourcemap 注释识别，只在树中显示)"},
+			{Names: []string{"--keep"}, Arg: "NAME...", Key: "KeepFiles", Set: addKeys(config.KeepFiles), Usage: "指定文件名 (支持通配符) 不受内置忽略规则影响，如 --keep go.sum，可重复"},
+		}},
+		{"内容识别与处理", []cliOption{
+			{Names: []string{"--unknown-ext"}, Arg: "M", Key: "UnknownExt", Set: setChoice(&config.UnknownExt, "include", "skip"), Usage: "不在 --text-ext 白名单中的后缀: include 通过文本检测即输出 (默认); skip 只在树中显示。Dockerfile、Makefile 等常见文件名始终输出"},
+			{Names: []string{"--file-lang"}, Arg: "N=L", Key: "FileLangs", Set: setFileLang, Usage: "将无后缀文件名 N 视为文本并以语言 L 标记代码块 (如 Tiltfile=python)，可重复；内置 Dockerfile、Makefile、Jenkinsfile、CMakeLists.txt 等"},
+			{Names: []string{"--binary-window"}, Arg: "S", Key: "BinaryWindow", Set: setBinaryWindow, Usage: "二进制检测读取的开头字节数 (默认 8K)"},
+			{Names: []string{"--binary-threshold"}, Arg: "P", Key: "BinaryThreshold", Set: setPercent(&config.BinaryThreshold), Usage: "检测窗口内含 NUL，或不可打印字节超过 P% 时视为二进制 (默认 30)；无 BOM 的 UTF-16 文本会自动识别"},
+			{Names: []string{"--force-text"}, Arg: "P", Key: "ForceText", Set: appendValues(&config.ForceText), Usage: "路径匹配 P (过滤规则语法) 的文件始终按文本读取，可重复"},
+			{Names: []string{"--force-binary"}, Arg: "P", Key: "ForceBinary", Set: appendValues(&config.ForceBinary), Usage: "路径匹配 P 的文件始终视为二进制 (只在树中显示)，可重复"},
+			{Names: []string{"--control-chars"}, Arg: "M", Key: "ControlChars", Set: setChoice(&config.ControlChars, "strip", "escape", "keep"), Usage: "ANSI 转义序列与控制字符 (常见于日志文件): strip 去除 (默认); escape 转义为 \\x1b 等可见形式; keep 原样保留"},
+			{Names: []string{"--fallback-encoding"}, Arg: "E", Key: "FallbackEncoding", Set: setFallbackEncoding, Usage: "既非 UTF-8 也非 GBK 的文件按 E (windows-1252 或 latin1) 尽力转换，而不是跳过"},
+			{Names: []string{"--no-editorconfig"}, Key: "NoEditorconfig", Set: setTrue(&config.NoEditorconfig), Usage: "不读取 .editorconfig 中的 charset 声明 (默认按声明的 latin1/utf-16be/utf-16le 解码，而不是自动检测)"},
+			{Names: []string{"--env-values"}, Arg: "M", Key: "EnvValues", Set: setChoice(&config.EnvValues, "keep", "mask", "drop"), Usage: ".env 文件中值的处理: mask (默认，KEY=***)、keep (原样输出) 或 drop (不输出内容)；.env.example 等示例文件不受影响"},
+			{Names: []string{"--pii-scan"}, Key: "PIIScan", Set: setTrue(&config.PIIScan), Usage: "扫描输出内容中的邮箱、电话号码与身份证号/SSN，结束时列出命中位置 (计为警告，配合 --strict 可阻止输出)"},
+			{Names: []string{"--pii-mask"}, Key: "PIIMask", Set: func(s *cliState, name string, values []string) error {
+				config.PIIScan, config.PIIMask = true, true
+				return nil
+			}, Usage: "扫描并在输出中将命中内容替换为 [EMAIL]、[PHONE]、[ID] (隐含 --pii-scan)"},
+			{Names: []string{"--plugin"}, Arg: "CMD", Key: "Plugins", Set: appendValues(&config.Plugins), Usage: "注册外部处理器：CMD --describe 输出 {\"name\",\"patterns\"}；也可写作 \"*.ipynb=CMD\" 直接指定匹配的文件名\n匹配的文件内容从标准输入传给 CMD (DIR2TXT_FILE 为文件路径)，其标准输出作为 Markdown 写入"},
+		}},
+		{"输出", []cliOption{
+			{Names: []string{"--out", "-o"}, Arg: "PATH", Set: func(s *cliState, name string, values []string) error { s.out = values[0]; return nil }, Usage: "指定输出文件路径或输出目录"},
+			{Names: []string{"--format"}, Arg: "F", Key: "Format", Set: setFormat, Usage: "输出格式: md (默认); rst reStructuredText (code-block 指令与标题下划线，可直接放入 Sphinx 文档); org Org-mode (* 标题与 #+BEGIN_SRC 源码块，便于在 Emacs 中折叠浏览); plain 以 \"===== 路径 =====\" 分隔的原始内容，不含任何 Markdown; rag-jsonl 每行一个按函数/类边界切分的 JSON 分块，便于 RAG 入库; json 所有文件组成的 JSON 数组 (path、language、size、mod_time、content)。可重复指定 (如 --format md --format rag-jsonl)，一次扫描同时写出多种格式，其余格式的文件与主输出同名、只替换后缀"},
+			{Names: []string{"--flavor"}, Arg: "F", Key: "Flavor", Set: setChoice(&config.Flavor, choicesOf(flavors)...), Usage: "Markdown 方言: github (默认); obsidian 使用 [[wiki 链接]]、--explode 的每个文件带 YAML front matter，并转义会被误解析为标签/高亮的写法 (兼容 Logseq)"},
+			{Names: []string{"--explode"}, Arg: "DIR", Key: "Explode", Set: setString(&config.Explode), Usage: "每个源文件单独写出一个文件 (保持目录结构)，并在 DIR/index.md 中输出目录树与文件索引"},
+			{Names: []string{"--explode-ext"}, Arg: "EXT", Key: "ExplodeExt", Set: setExplodeExt, Usage: "拆分文件的后缀: .md (默认，带代码块) 或 .txt (仅原始内容)"},
+			{Names: []string{"--compress"}, Arg: "gzip|zstd", Key: "Compress", Set: setChoice(&config.Compress, choicesOf(compressExts)...), Usage: "流式压缩输出文件，文件名追加 .gz/.zst (--out 以 .gz/.zst 结尾时自动启用)"},
+			{Names: []string{"--archive"}, Arg: "zip", Key: "Archive", Set: setChoice(&config.Archive, "zip"), Usage: "完成后将输出文件 (或 --explode 的整个目录) 打包为同名 .zip，便于分享"},
+			{Names: []string{"--resume"}, Key: "Resume", Set: setTrue(&config.Resume), Usage: "从上次崩溃或中断 (Ctrl-C) 的位置继续，跳过已完整写入的文件 (参数需与上次一致)"},
+			{Names: []string{"--snapshot"}, Arg: "FILE", Key: "Snapshot", Set: setString(&config.Snapshot), Usage: "将本次通过过滤规则的文件 (大小、修改时间与 SHA-256) 记录为一次快照，写入快照库 FILE (单个 gob 文件，每组根目录保留最近 20 次)"},
+			{Names: []string{"--since-last"}, Key: "SinceLast", Set: setTrue(&config.SinceLast), Usage: "配合 --snapshot，只输出自上次快照以来新增或修改的文件，删除的文件列在文末；不依赖 git。dir2txt changes 等同于加上此参数"},
+			{Names: []string{"--backup"}, Key: "Backup", Set: setTrue(&config.Backup), Usage: "覆盖已有输出前将其保留为 .bak"},
+			{Names: []string{"--no-overwrite"}, Key: "NoOverwrite", Set: setTrue(&config.NoOverwrite), Usage: "输出文件已存在时报错退出，不覆盖"},
+			{Names: []string{"--versioned"}, Key: "Versioned", Set: setTrue(&config.Versioned), Usage: "输出文件已存在时依次写入 name_context.2.md、.3.md 等新文件"},
+			{Names: []string{"--show-perms"}, Key: "ShowPerms", Set: setTrue(&config.ShowPerms), Usage: "在每个文件标题下输出权限与属主 (uid:gid)"},
+			{Names: []string{"--history"}, Arg: "N", Key: "History", Set: setInt(&config.History, 0, math.MaxInt, "一个非负整数"), Usage: "在每个文件标题下附带最近 N 条修改该文件的提交 (来自 git log --follow)"},
+			{Names: []string{"--heading-level"}, Arg: "N", Key: "HeadingLevel", Set: setInt(&config.HeadingLevel, 2, 6, " 2 到 6 之间的整数"), Usage: "文件章节使用 N 级标题 (2-6，默认 2)，Project Structure 等章节为 N-1 级，便于嵌入更大的文档"},
+			{Names: []string{"--numbered-headings"}, Key: "NumberedHeadings", Set: setTrue(&config.NumberedHeadings), Usage: "文件章节标题按写入顺序编号，如 \"## 12. src/util.go\""},
+			{Names: []string{"--preamble"}, Arg: "F", Key: "Preamble", Set: setPromptOption, Usage: "将文件 F 的内容作为说明写在目录树之前，使输出可直接作为完整提示词粘贴"},
+			{Names: []string{"--postamble"}, Arg: "F", Key: "Postamble", Set: setPromptOption, Usage: "将文件 F 的内容作为说明写在全部文件内容之后"},
+			{Names: []string{"--prompt"}, Arg: "TEXT", Key: "Preamble", Set: setPromptOption, Usage: "直接指定写在目录树之前的说明 (可与 --preamble 同时使用，追加在其后)"},
+		}},
+		{"目录树", []cliOption{
+			{Names: []string{"--tree-format"}, Arg: "F", Key: "TreeFormat", Set: setChoice(&config.TreeFormat, choicesOf(treeFormats)...), Usage: "目录树格式: ascii 框线字符 (默认); indent 两空格缩进; paths 排序后的完整路径列表; json 嵌套对象"},
+			{Names: []string{"--tree-order"}, Arg: "O", Key: "TreeOrder", Set: setChoice(&config.TreeOrder, choicesOf(treeOrders)...), Usage: "目录树排列: dirs-first 目录在前 (默认); mixed 按名称混排; files-first 文件在前"},
+			{Names: []string{"--tree-links"}, Key: "TreeLinks", Set: setTrue(&config.TreeLinks), Usage: "目录树输出为 Markdown 嵌套列表，文件名链接到对应的内容章节 (渲染后可点击跳转)"},
+			{Names: []string{"--tree-mark-filtered"}, Key: "TreeMarkFiltered", Set: setTrue(&config.TreeMarkFiltered), Usage: "硬过滤 (-F) 命中的项仍显示在目录树中并标注 [filtered]，但不展开其内容"},
+			{Names: []string{"--ascii"}, Key: "ASCIIGlyphs", Set: setTrue(&config.ASCIIGlyphs), Usage: "目录树使用 |-- 与 `-- 代替 Unicode 框线字符"},
+			{Names: []string{"--no-fold"}, Key: "NoFold", Set: setTrue(&config.NoFold), Usage: fmt.Sprintf("在目录树中不折叠过长的文件或目录列表，始终全部显示 (默认同级超过 %d 个时折叠)", maxDisplayFiles)},
+		}},
+		{"附加章节", []cliOption{
+			{Names: []string{"--go-graph"}, Arg: "[=text|mermaid]", Key: "GoGraph", Set: setGoGraph, Usage: "在目录树之后输出 Go 模块内的包依赖图 (默认 text)"},
+			{Names: []string{"--deps-summary"}, Key: "DepsSummary", Set: setTrue(&config.DepsSummary), Usage: "输出依赖汇总表 (解析 go.mod、package.json、requirements.txt、Cargo.toml、pom.xml)"},
+			{Names: []string{"--test-map"}, Key: "TestMap", Set: setTrue(&config.TestMap), Usage: "在内容之前列出源文件对应的测试文件 (Go/Python/JS/TS 命名约定)，并标出没有测试的文件"},
+			{Names: []string{"--todos"}, Key: "Todos", Set: setTrue(&config.Todos), Usage: "在内容之前汇总输出文件中的 TODO/FIXME/HACK/XXX 注释 (文件、行号、内容)"},
+			{Names: []string{"--sarif"}, Arg: "FILE", Key: "SARIF", Set: setString(&config.SARIF), Usage: "将 --pii-scan 与 --todos 的发现导出为 SARIF 2.1.0 文件，供安全与代码质量平台导入"},
+			{Names: []string{"--licenses"}, Key: "Licenses", Set: setTrue(&config.Licenses), Usage: "汇总 LICENSE/COPYING/NOTICE 文件 (包括 vendor 等被过滤的目录) 的许可证类型与版权声明"},
+			{Names: []string{"--contracts-summary"}, Key: "ContractsSummary", Set: setTrue(&config.ContractsSummary), Usage: "输出 .proto、GraphQL schema、OpenAPI/Swagger 契约文件的 service/message/endpoint 摘要"},
+			{Names: []string{"--ai-summaries"}, Key: "AISummaries", Set: setTrue(&config.AISummaries), Usage: "调用 OpenAI 兼容接口为每个文件生成两句话摘要，写在文件标题下 (按内容哈希缓存)"},
+			{Names: []string{"--ai-endpoint"}, Arg: "URL", Key: "AIEndpoint", Set: setString(&config.AIEndpoint), Usage: "配合 --ai-summaries，接口地址 (如 https://api.openai.com/v1)；API key 取自 DIR2TXT_AI_KEY 或 OPENAI_API_KEY"},
+			{Names: []string{"--ai-model"}, Arg: "M", Key: "AIModel", Set: setString(&config.AIModel), Usage: fmt.Sprintf("配合 --ai-summaries，使用的模型 (默认 %s)", defaultAIModel)},
+			{Names: []string{"--summarize-excluded"}, Key: "SummarizeExcluded", Set: setTrue(&config.SummarizeExcluded), Usage: "为因预算或数量限制未输出的文件在附录中各写一行摘要 (路径、大小、首个文档注释或首个非空行)"},
+		}},
+		{"规模与排序", []cliOption{
+			{Names: []string{"--plan"}, Arg: "[=N]", Key: "Plan", Set: setPlan, Usage: "只遍历元数据并打印将输出的最大的 N 个文件与目录 (默认 20，按字节与估算 token 数)，不生成输出"},
+			{Names: []string{"--max-output-size"}, Arg: "S", Key: "MaxOutputSize", Set: setSize(&config.MaxOutputSize), Usage: fmt.Sprintf("写入前预估文档大小，超过 S (如 10M) 时不生成输出并以退出码 %d 结束", exitOverBudget)},
+			{Names: []string{"--oversize"}, Arg: "M", Key: "Oversize", Set: setChoice(&config.Oversize, "abort", "outline"), Usage: "配合 --max-output-size: abort 中止 (默认); outline 改为只输出每个文件的声明行 (大纲仍超限时同样中止)"},
+			{Names: []string{"--budget-tokens"}, Arg: "N", Key: "BudgetTokens", Set: setPositiveInt64(&config.BudgetTokens), Usage: "文档超过 N tokens 时按策略自动丢弃或截断低优先级文件，并在末尾列出"},
+			{Names: []string{"--trim-strategy"}, Arg: "S", Key: "TrimStrategy", Set: setChoice(&config.TrimStrategy, "size", "importance", "oldest"), Usage: "裁剪策略: size 先丢弃最大的文件 (默认); importance 先丢弃最不重要的; oldest 先丢弃最久未修改的"},
+			{Names: []string{"--max-files"}, Arg: "N", Key: "MaxFiles", Set: setInt(&config.MaxFiles, 1, math.MaxInt, "一个正整数"), Usage: "最多输出 N 个文件的内容，按重要性排名选择，其余在末尾附录中列出"},
+			{Names: []string{"--max-files-per-dir"}, Arg: "N", Key: "MaxFilesPerDir", Set: setInt(&config.MaxFilesPerDir, 1, math.MaxInt, "一个正整数"), Usage: "每个目录最多输出 N 个文件的内容 (按入口文件、最近修改、小文件优先选择)，其余在末尾附录中列出"},
+			{Names: []string{"--rank"}, Key: "Rank", Set: setTrue(&config.Rank), Usage: "按重要性 (入口文件、README、最近修改、被导入次数、小文件) 排列文件内容"},
+			{Names: []string{"--show-rank"}, Key: "ShowRank", Set: setTrue(&config.ShowRank), Usage: "在控制台打印文件重要性排名及各项得分"},
+			{Names: []string{"--warn-share"}, Arg: "P", Key: "WarnShare", Set: setPercent(&config.WarnShare), Usage: "单个文件占全部文件内容估算 token 数超过 P% 时提示并建议过滤规则 (默认 25，0 表示关闭；小于 2000 tokens 的文件不提示)"},
+		}},
+		{"CI 与诊断", []cliOption{
+			{Names: []string{"--ci"}, Key: "CI", Set: setTrue(&config.CI), Usage: fmt.Sprintf("非交互模式: 禁止安装/卸载，不读取用户级配置，日志只输出 JSON 格式的警告/错误，默认输出文件名固定为 %s.md，结束时打印一行 JSON 结果", ciOutputName)},
+			{Names: []string{"--strict"}, Key: "Strict", Set: setTrue(&config.Strict), Usage: "将单个文件的警告 (编码无法识别、读取失败等) 视为失败"},
+			{Names: []string{"--allow-empty"}, Key: "AllowEmpty", Set: setTrue(&config.AllowEmpty), Usage: fmt.Sprintf("过滤后没有任何文件内容时仍然输出只有目录树的文档并返回 0 (默认打印排除原因并以 %d 退出)", exitNoFiles)},
+			{Names: []string{"--fail-over-tokens"}, Arg: "N", Key: "FailOverTokens", Set: setPositiveInt64(&config.FailOverTokens), Usage: fmt.Sprintf("输出估算 token 数超过 N 时以退出码 %d 结束", exitOverBudget)},
+			{Names: []string{"--fail-over-size"}, Arg: "S", Key: "FailOverSize", Set: setSize(&config.FailOverSize), Usage: fmt.Sprintf("输出大小超过 S (如 10M) 时以退出码 %d 结束", exitOverBudget)},
+			{Names: []string{"--emit-filter-file"}, Arg: "F", Key: "EmitFilterFile", Set: setString(&config.EmitFilterFile), Usage: "运行结束后将实际生效的排除 (命中的规则、内置规则、超大文件、生成代码) 写成可用 -c/-Fc 复用的过滤文件"},
+			{Names: []string{"--ext-stats"}, Key: "ExtStats", Set: setTrue(&config.ExtStats), Usage: "结束时按扩展名统计包含与跳过的文件数和大小 (含被过滤的目录)，便于调整过滤规则"},
+			{Names: []string{"--bench"}, Key: "Bench", Set: setTrue(&config.Bench), Usage: "结束时打印各阶段 (目录树、遍历、读取、转码、写入) 的耗时与吞吐量"},
+			{Names: []string{"--profile"}, Arg: "FILE", Key: "ProfileFile", Set: setString(&config.ProfileFile), Usage: "将 CPU profile 写入 FILE (go tool pprof 分析)"},
+			{Names: []string{"--trace"}, Arg: "FILE", Key: "TraceFile", Set: setString(&config.TraceFile), Usage: "将执行 trace 写入 FILE (go tool trace 分析)"},
+			{Names: []string{"--otel-endpoint"}, Arg: "URL", Key: "OTelEndpoint", Set: setURL(&config.OTelEndpoint), Usage: "结束时以 OTLP/HTTP JSON 向 collector (如 http://localhost:4318) 推送指标与 trace：\n扫描/写入/跳过的文件数、输出字节数、各阶段耗时；推送失败只打印警告，不影响退出码"},
+			{Names: []string{"--progress"}, Key: "Progress", Set: func(s *cliState, name string, values []string) error { config.Progress = "always"; return nil }, Usage: "标准错误重定向时也显示进度 (每 10 秒一行)；默认只在终端中显示单行刷新的速度与预计剩余时间"},
+			{Names: []string{"--no-progress"}, Key: "Progress", Set: func(s *cliState, name string, values []string) error { config.Progress = "never"; return nil }, Usage: "不显示进度状态行"},
+		}},
+		{"运行环境", []cliOption{
+			{Names: []string{"--cache"}, Key: "CacheDir", Set: setDefaultCacheDir, Usage: "启用跨运行的缓存 (~/.cache/dir2txt)，按路径+内容版本复用已转换的内容 (git 仓库中与索引一致的文件使用 blob OID，checkout 改变修改时间也能命中；其他文件使用修改时间+大小)，未变化的文件不再重新读取"},
+			{Names: []string{"--cache-dir"}, Arg: "DIR", Key: "CacheDir", Set: setString(&config.CacheDir), Usage: "使用指定的缓存目录 (隐含 --cache)"},
+			{Names: []string{"--io-retries"}, Arg: "N", Key: "IORetries", Set: setInt(&config.IORetries, 0, math.MaxInt, "一个非负整数"), Usage: "遇到暂时性 I/O 错误 (NFS/SMB 超时、文件被占用等) 时按指数退避重试的次数 (默认 3，0 表示不重试)"},
+			{Names: []string{"--io-concurrency"}, Arg: "N", Key: "IOConcurrency", Set: setInt(&config.IOConcurrency, 1, math.MaxInt, "一个正整数"), Usage: "同时打开的文件与目录数上限，避免压垮网络文件服务器 (默认不限制)"},
+			{Names: []string{"--max-memory"}, Arg: "SIZE", Key: "MaxMemory", Set: setSize(&config.MaxMemory), Usage: "需要先读取全部文件时 (--budget-tokens、--rank) 内存中缓冲内容的上限，超出部分暂存到临时文件"},
+			{Names: []string{"--pre-hook"}, Arg: "CMD", Key: "PreHook", Set: setString(&config.PreHook), Usage: "生成前通过 shell 执行 CMD，失败时中止"},
+			{Names: []string{"--post-hook"}, Arg: "CMD", Key: "PostHook", Set: setString(&config.PostHook), Usage: "生成后执行 CMD；两者均通过 DIR2TXT_OUTPUT 等环境变量及标准输入中的 JSON 获取输出路径与概况"},
+		}},
+		{"安装", []cliOption{
+			{Names: []string{"--install"}, Set: func(s *cliState, name string, values []string) error { s.install = true; return nil }, Usage: "安装程序到系统 (Linux: /usr/local/bin; Windows: Program Files 并添加 PATH)"},
+			{Names: []string{"--uninstall"}, Set: func(s *cliState, name string, values []string) error { s.uninstall = true; return nil }, Usage: "从系统中卸载程序"},
+			{Names: []string{"--user"}, Key: "UserInstall", Set: setTrue(&config.UserInstall), Usage: "配合 --install/--uninstall，安装到用户目录且无需 root/管理员 (Linux: ~/.local/bin 并写入 shell 启动文件; Windows: %LOCALAPPDATA%\\Programs 并添加用户 PATH)，卸载时清理添加的 PATH"},
+			{Names: []string{"--static"}, Key: "InstallStatic", Set: setTrue(&config.InstallStatic), Usage: "配合 --install，确认程序为静态链接后再安装，供 Alpine 等 musl 容器使用"},
+			{Names: []string{"--release-url"}, Arg: "URL", Key: "ReleaseURL", Set: setHTTPSURL(&config.ReleaseURL), Usage: "配合 --install，检测到程序在转译层下运行 (如 ARM64 Windows 上的 amd64 版本) 时下载原生版本安装；\nURL 须为 https://，其中的 {os}、{arch}、{ext} 与 {name} (如 dir2txt_windows_arm64.exe，与 build.sh 产物同名) 会被替换；\n安装前按同一位置的 <URL>.sha256 校验文件核对 SHA-256"},
+		}},
+		{"其他", []cliOption{
+			{Names: []string{"--help", "-h"}, Set: func(s *cliState, name string, values []string) error { s.help = true; return nil }, Usage: "显示此帮助"},
+		}},
+	}
+}
+
+// subcommandDocs 子命令及其说明
+var subcommandDocs = [][2]string{
+	{"compare", "对比两个 git 引用，只输出有差异的文件 (dir2txt compare --help 查看详情)"},
+	{"test-filter", "打印指定路径的过滤规则求值过程与结果 (dir2txt test-filter <path> -f ... -F ...)"},
+	{"install-shell", "安装 shell 函数 ctx，在 git 仓库根目录运行 dir2txt 并复制结果到剪贴板 (dir2txt install-shell -h 查看用法)"},
+	{"changes", "只输出自上次快照以来变化的文件，等同于 --since-last (dir2txt changes --snapshot snapshots.db)"},
+	{"daemon", "在后台按计划 (--every 15m) 或文件变化 (--watch) 重新生成上下文文件；daemon stop/status 停止或查看状态 (dir2txt daemon --help 查看用法)"},
+	{"gen-fixture", "按规格文件生成可复现的测试目录树，用于基准测试与复现问题 (dir2txt gen-fixture --help 查看规格格式)"},
+	{"help-full", "打印包含全部参数、配置文件、过滤规则语法、环境变量与退出码的完整帮助"},
+	{"man", "输出 man 页面 (roff 格式)，如 dir2txt man > dir2txt.1"},
+}
+
+// helpUsageLine 用法行
+const helpUsageLine = "dir2txt [--dir <path> ...] [--filter <pattern> ...] [dir|filter ...]"
+
+// helpExamples 帮助中的示例
+var helpExamples = []string{
+	"dir2txt --dir . ../other --filter '*.png *.jpg' '!keep.png'",
+	"dir2txt --filter '*.png' --filter '!keep.png' src test",
+	"dir2txt -F 'dist/**' -f '*.png' src",
+	"dir2txt --dir backend --filter-for backend 'migrations/*' --dir frontend --filter-for frontend 'public/*'",
+}
+
+// helpNotes 简要帮助中参数表之后的说明
+func helpNotes() []string {
+	return []string{
+		fmt.Sprintf("分层配置: 依次读取 %s 与仓库中的 %s (每行一个参数，不以 - 开头的行视为软过滤)，命令行参数最后生效", globalConfigPath(), repoConfigName),
+		"Pattern 语法: ? 单字符 (test?.log); * 任意串 (*.go); [] 字符范围 (file[0-9].txt); 前缀 ! 取反 (!important.txt)",
+		"规则顺序: 与 gitignore 相同，后出现的规则覆盖先前的规则，! 规则可重新包含先前被排除的路径",
+		"位置参数: 未被 --dir 消耗的参数：若含 * ? [] 或以 ! 开头视为软过滤，其它视为目录；没有其他目录参数时，带路径分隔符且只匹配到目录的模式 (如 'services/*/api') 展开为多个目录",
+		"以 - 开头的值: 写作 --dir=-x、--filter=-x，或 --dir -- -x (紧跟在参数后的 -- 之后均为该参数的值)；单独的 -- 之后均为位置参数",
+	}
+}
+
+// exitCodeDocs 退出码说明
+func exitCodeDocs() [][2]string {
+	return [][2]string{
+		{fmt.Sprint(exitOK), "成功"},
+		{fmt.Sprint(exitError), "参数或致命错误"},
+		{fmt.Sprint(exitNoFiles), "没有文件内容被写入"},
+		{fmt.Sprint(exitOverBudget), "超过 token/大小限制"},
+		{fmt.Sprint(exitEncoding), "--strict 下存在编码无法识别的文件"},
+		{fmt.Sprint(exitPartialWalk), "部分目录遍历失败"},
+		{fmt.Sprint(exitWarnings), "--strict 下存在其他警告"},
+	}
+}
+
+// envDocs 读取或提供给外部命令的环境变量
+var envDocs = [][2]string{
+	{"DIR2TXT_AI_KEY, OPENAI_API_KEY", "--ai-summaries 使用的 API key"},
+	{"DIR2TXT_HOOK", "传给 --pre-hook/--post-hook：pre 或 post"},
+	{"DIR2TXT_OUTPUT", "传给钩子：输出文件路径"},
+	{"DIR2TXT_FILES, DIR2TXT_BYTES, DIR2TXT_TOKENS", "传给 --post-hook：写入的文件数、字节数与估算 token 数"},
+	{"DIR2TXT_EXIT_CODE", "传给 --post-hook：本次运行的退出码"},
+	{"DIR2TXT_FILE", "传给 --plugin 处理器：当前文件路径"},
+}
+
+// patternDocs help-full 与 man 中过滤规则的完整语义
+var patternDocs = []string{
+	"dir 或 dir/: 目录前缀匹配，目录本身及其全部子孙均命中",
+	"dir/*: 只命中目录下的内容，目录本身保留在树中 (可配合 !dir/keep 重新包含单个文件)",
+	"其他规则按 glob 匹配相对路径或文件名: ? 单字符; * 不跨越 / 的任意串; [] 字符范围，[^...] 取反",
+	"前缀 ! 取反 (豁免)；Windows 风格的 \\ 分隔符会被转换为 /",
+	"所有规则按出现顺序求值，最后一条命中的规则决定结果；目录一旦被排除便不会再遍历，其子项无法单独重新包含",
+	"-f/--filter 为软过滤 (只跳过内容)，-F/--Filter 为硬过滤 (树中也不显示)；--filter-for/--Filter-for 只作用于指定根目录",
+	"dir2txt test-filter <path> ... 可打印每条规则对指定路径的求值过程",
+}
+
+// optionLabel 帮助中参数的显示形式，如 "--dir/-d DIR..."、"--plan[=N]"
+func optionLabel(opt cliOption) string {
+	label := strings.Join(opt.Names, "/")
+	switch {
+	case opt.Arg == "":
+	case strings.HasPrefix(opt.Arg, "["):
+		label += opt.Arg
+	default:
+		label += " " + opt.Arg
+	}
+	return label
+}
+
+// writeOptionLines 输出一个参数的帮助行，多行说明的后续行缩进对齐
+func writeOptionLines(w io.Writer, opt cliOption) {
+	lines := strings.Split(opt.Usage, "\n")
+	fmt.Fprintf(w, "  %-13s %s\n", optionLabel(opt), lines[0])
+	for _, line := range lines[1:] {
+		fmt.Fprintf(w, "      %s\n", line)
+	}
+}
+
+// printUsage 简要帮助 (--help)
+func printUsage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "dir2txt %s\n", version)
+	fmt.Fprintf(w, "用法: %s\n", helpUsageLine)
+	fmt.Fprintf(w, "示例:\n")
+	for _, example := range helpExamples {
+		fmt.Fprintf(w, "  %s\n", example)
+	}
+	fmt.Fprintf(w, "子命令:\n")
+	for _, sub := range subcommandDocs {
+		fmt.Fprintf(w, "  %-13s %s\n", sub[0], sub[1])
+	}
+	for _, group := range optionGroups() {
+		fmt.Fprintf(w, "%s:\n", group.Title)
+		for _, opt := range group.Options {
+			writeOptionLines(w, opt)
+		}
+	}
+	fmt.Fprintf(w, "说明:\n")
+	for _, note := range helpNotes() {
+		fmt.Fprintf(w, "  %s\n", note)
+	}
+	fmt.Fprintf(w, "退出码:\n")
+	for _, code := range exitCodeDocs() {
+		fmt.Fprintf(w, "  %s %s\n", code[0], code[1])
+	}
+}
+
+// configKeyDocs 设置名到设置它的参数的对应关系，按设置名排序
+func configKeyDocs() [][2]string {
+	keys := map[string][]string{}
+	for _, group := range optionGroups() {
+		for _, opt := range group.Options {
+			if opt.Key != "" {
+				keys[opt.Key] = append(keys[opt.Key], opt.Names[0])
+			}
+		}
+	}
+	var docs [][2]string
+	for key, names := range keys {
+		docs = append(docs, [2]string{key, strings.Join(names, ", ")})
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i][0] < docs[j][0] })
+	return docs
+}
+
+// printFullHelp dir2txt help-full：完整帮助
+func printFullHelp(w io.Writer) {
+	fmt.Fprintf(w, "dir2txt %s\n\n", version)
+	fmt.Fprintf(w, "用法:\n  %s\n  dir2txt <子命令> [参数...]\n\n", helpUsageLine)
+	fmt.Fprintf(w, "示例:\n")
+	for _, example := range helpExamples {
+		fmt.Fprintf(w, "  %s\n", example)
+	}
+	fmt.Fprintf(w, "\n子命令:\n")
+	for _, sub := range subcommandDocs {
+		fmt.Fprintf(w, "  %-13s %s\n", sub[0], sub[1])
+	}
+	for _, group := range optionGroups() {
+		fmt.Fprintf(w, "\n%s:\n", group.Title)
+		for _, opt := range group.Options {
+			writeOptionLines(w, opt)
+		}
+	}
+
+	fmt.Fprintf(w, "\n位置参数:\n  未被 --dir 消耗的参数：若含 * ? [] 或以 ! 开头视为软过滤，其它视为目录；-- 之后的参数均按位置参数处理\n")
+	fmt.Fprintf(w, "  没有其他目录参数时，带路径分隔符且只匹配到目录的模式 (如 'services/*/api') 视为目录模式，由程序展开，每个匹配的目录作为一个根目录；\n")
+	fmt.Fprintf(w, "  给出了目录时 (如 dir2txt . 'vendor/*') 仍为软过滤，此时目录模式请写作 --dir 'services/*/api'\n")
+	fmt.Fprintf(w, "  以 - 开头的目录或规则: --dir=-x、--filter=-x，或 --dir -- -x、--filter -- -x (紧跟在多值参数后的 -- 之后的参数全部作为该参数的值)\n")
+	fmt.Fprintf(w, "\n过滤规则:\n")
+	for _, line := range patternDocs {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+
+	fmt.Fprintf(w, "\n配置文件:\n")
+	fmt.Fprintf(w, "  按 内置默认值 → 用户级配置 (%s) → 仓库级配置 (从当前目录向上查找 %s，直到仓库根目录) → 命令行参数 的顺序合并，后者覆盖前者\n", globalConfigPath(), repoConfigName)
+	fmt.Fprintf(w, "  每行一个参数 (如 --rank、-F vendor，支持单双引号)，不以 - 开头的行视为软过滤规则，行首 # 为注释；过滤规则在各层间依次追加\n")
+	fmt.Fprintf(w, "  配置文件中不能使用执行命令、联网或安装的参数 (--pre-hook、--post-hook、--plugin、--ai-summaries、--install 等)；\n")
+	fmt.Fprintf(w, "  仓库级配置只能使用过滤、遍历、内容识别、输出格式与规模限制类参数，不能指定目录或读写其他文件；不允许的参数会被忽略并打印 [WARN]\n")
+	fmt.Fprintf(w, "  --no-config 只使用命令行参数；--ci 跳过用户级配置；--show-effective-config 打印各层及下列设置中与默认值不同的项\n")
+	fmt.Fprintf(w, "  设置名与对应参数:\n")
+	for _, key := range configKeyDocs() {
+		fmt.Fprintf(w, "    %-20s %s\n", key[0], key[1])
+	}
+
+	fmt.Fprintf(w, "\n环境变量:\n")
+	for _, env := range envDocs {
+		fmt.Fprintf(w, "  %s\n      %s\n", env[0], env[1])
+	}
+	fmt.Fprintf(w, "\n退出码:\n")
+	for _, code := range exitCodeDocs() {
+		fmt.Fprintf(w, "  %s %s\n", code[0], code[1])
+	}
+}
+
+// roffEscape 转义 roff 正文：反斜杠与 - 需要转义，行首的 . 与 ' 会被当作请求
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// printManPage dir2txt man：输出 roff 格式的 man 页面
+func printManPage(w io.Writer) {
+	fmt.Fprintf(w, ".TH DIR2TXT 1 \"\" \"dir2txt %s\" \"User Commands\"\n", version)
+	fmt.Fprintf(w, ".SH NAME\ndir2txt \\- 将目录树与源文件内容合并为一个文档\n")
+	fmt.Fprintf(w, ".SH SYNOPSIS\n.B dir2txt\n%s\n.br\n.B dir2txt\n.I subcommand\n[参数...]\n", roffEscape(strings.TrimPrefix(helpUsageLine, "dir2txt ")))
+	fmt.Fprintf(w, ".SH DESCRIPTION\n扫描一个或多个目录，按过滤规则输出目录树与文本文件内容，便于提供给大语言模型或代码审阅。\n")
+	fmt.Fprintf(w, ".SH SUBCOMMANDS\n")
+	for _, sub := range subcommandDocs {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", sub[0], roffEscape(sub[1]))
+	}
+	fmt.Fprintf(w, ".SH OPTIONS\n")
+	for _, group := range optionGroups() {
+		fmt.Fprintf(w, ".SS %s\n", group.Title)
+		for _, opt := range group.Options {
+			fmt.Fprintf(w, ".TP\n.B %s\n", roffEscape(optionLabel(opt)))
+			for _, line := range strings.Split(opt.Usage, "\n") {
+				fmt.Fprintf(w, "%s\n", roffEscape(line))
+			}
+		}
+	}
+	fmt.Fprintf(w, ".SH PATTERNS\n")
+	for _, line := range patternDocs {
+		fmt.Fprintf(w, ".IP \\(bu 2\n%s\n", roffEscape(line))
+	}
+	fmt.Fprintf(w, ".SH CONFIGURATION\n")
+	fmt.Fprintf(w, "%s\n", roffEscape(fmt.Sprintf("按 内置默认值、用户级配置 (%s)、仓库级 %s、命令行参数 的顺序合并，后者覆盖前者。每行一个参数，不以 - 开头的行视为软过滤规则，行首 # 为注释。配置文件中不能使用执行命令、联网或安装的参数；仓库级配置只能使用过滤、遍历、内容识别、输出格式与规模限制类参数。", globalConfigPath(), repoConfigName)))
+	fmt.Fprintf(w, ".PP\n--show-effective-config 中的设置名与对应参数:\n")
+	for _, key := range configKeyDocs() {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", key[0], roffEscape(key[1]))
+	}
+	fmt.Fprintf(w, ".SH ENVIRONMENT\n")
+	for _, env := range envDocs {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", roffEscape(env[0]), roffEscape(env[1]))
+	}
+	fmt.Fprintf(w, ".SH EXIT STATUS\n")
+	for _, code := range exitCodeDocs() {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", code[0], roffEscape(code[1]))
+	}
+}
+
+// runHelpCommand 处理 help-full 与 man 子命令
+func runHelpCommand(name string) {
+	if name == "man" {
+		printManPage(os.Stdout)
+		return
+	}
+	printFullHelp(os.Stdout)
+}
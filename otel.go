@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// otelTimeout 每次向 collector 推送的超时时间，导出失败不影响运行结果
+const otelTimeout = 5 * time.Second
+
+// otelStage 一个阶段在本次运行中的起止时间 (多次调用时为首次开始到累计耗时)
+type otelStage struct {
+	Start time.Time
+	Total time.Duration
+	Calls int
+}
+
+// telemetry --otel-endpoint 启用时收集的运行数据
+var telemetry struct {
+	start  time.Time
+	stages map[string]*otelStage
+}
+
+// initTelemetry 开始计时，并通过 onStage 钩子记录各阶段耗时
+func initTelemetry() {
+	if config.OTelEndpoint == "" {
+		return
+	}
+	telemetry.start = time.Now()
+	telemetry.stages = map[string]*otelStage{}
+	onStage = func(stage string, elapsed time.Duration) {
+		s, ok := telemetry.stages[stage]
+		if !ok {
+			s = &otelStage{Start: time.Now().Add(-elapsed)}
+			telemetry.stages[stage] = s
+		}
+		s.Total += elapsed
+		s.Calls++
+	}
+}
+
+// OTLP/HTTP JSON 编码 (opentelemetry-proto v1) 中用到的结构
+type otlpAttr struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttr `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpAttr `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsInt             *string    `json:"asInt,omitempty"`
+	AsDouble          *float64   `json:"asDouble,omitempty"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality"` // 1 = DELTA：每次运行独立上报
+	IsMonotonic            bool            `json:"isMonotonic"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpMetric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Unit        string     `json:"unit"`
+	Sum         *otlpSum   `json:"sum,omitempty"`
+	Gauge       *otlpGauge `json:"gauge,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"` // 1 = INTERNAL
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []otlpAttr `json:"attributes,omitempty"`
+	Status            otlpStatus `json:"status"`
+}
+
+type otlpStatus struct {
+	Code int `json:"code"` // 1 = OK, 2 = ERROR
+}
+
+func otlpString(key string, value string) otlpAttr {
+	return otlpAttr{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+func otlpInt(key string, value int64) otlpAttr {
+	s := strconv.FormatInt(value, 10)
+	return otlpAttr{Key: key, Value: otlpAnyValue{IntValue: &s}}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otlpID 生成 n 字节的随机 trace/span ID (十六进制)
+func otlpID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// exportTelemetry 将本次运行的指标与 trace 推送到 --otel-endpoint，code 为运行的退出码
+func exportTelemetry(code int) {
+	if config.OTelEndpoint == "" {
+		return
+	}
+	end := time.Now()
+	start, now := unixNano(telemetry.start), unixNano(end)
+	counter := func(name string, desc string, unit string, value int64, attrs ...otlpAttr) otlpMetric {
+		v := strconv.FormatInt(value, 10)
+		return otlpMetric{Name: name, Description: desc, Unit: unit, Sum: &otlpSum{
+			DataPoints:             []otlpDataPoint{{Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: now, AsInt: &v}},
+			AggregationTemporality: 1,
+			IsMonotonic:            true,
+		}}
+	}
+
+	skipped := int64(0)
+	reasons := make([]string, 0, len(exclusionCounts))
+	for reason, n := range exclusionCounts {
+		skipped += int64(n)
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	var skipPoints []otlpDataPoint
+	for _, reason := range reasons {
+		v := strconv.Itoa(exclusionCounts[reason])
+		skipPoints = append(skipPoints, otlpDataPoint{Attributes: []otlpAttr{otlpString("reason", reason)}, StartTimeUnixNano: start, TimeUnixNano: now, AsInt: &v})
+	}
+
+	var bytesWritten, tokens int64
+	if output != nil {
+		bytesWritten, tokens = output.bytes, output.Tokens()
+	}
+	metrics := []otlpMetric{
+		counter("dir2txt.files.scanned", "Files considered for the output", "{file}", int64(stats.Included)+skipped),
+		counter("dir2txt.files.included", "Files whose content was written", "{file}", int64(stats.Included)),
+		counter("dir2txt.output.bytes", "Bytes written to the output", "By", bytesWritten),
+		counter("dir2txt.output.tokens", "Estimated tokens written to the output", "{token}", tokens),
+		counter("dir2txt.warnings", "Warnings and encoding errors", "{warning}", int64(stats.Warnings+stats.EncodingErrors)),
+		counter("dir2txt.walk.errors", "Directories that could not be walked", "{error}", int64(stats.WalkErrors)),
+		counter("dir2txt.dirs.skipped", "Directories skipped as a whole by filters", "{dir}", int64(len(excludedDirs))),
+	}
+	if len(skipPoints) > 0 {
+		metrics = append(metrics, otlpMetric{Name: "dir2txt.files.skipped", Description: "Files skipped, by reason", Unit: "{file}", Sum: &otlpSum{
+			DataPoints: skipPoints, AggregationTemporality: 1, IsMonotonic: true,
+		}})
+	}
+	duration := end.Sub(telemetry.start).Seconds()
+	durationPoints := []otlpDataPoint{{Attributes: []otlpAttr{otlpString("stage", "total")}, StartTimeUnixNano: start, TimeUnixNano: now, AsDouble: &duration}}
+	for _, stage := range benchStages {
+		if s, ok := telemetry.stages[stage]; ok {
+			secs := s.Total.Seconds()
+			durationPoints = append(durationPoints, otlpDataPoint{Attributes: []otlpAttr{otlpString("stage", stage)}, StartTimeUnixNano: start, TimeUnixNano: now, AsDouble: &secs})
+		}
+	}
+	metrics = append(metrics, otlpMetric{Name: "dir2txt.stage.duration", Description: "Time spent per stage", Unit: "s", Gauge: &otlpGauge{DataPoints: durationPoints}})
+
+	resource := otlpResource{Attributes: []otlpAttr{
+		otlpString("service.name", "dir2txt"),
+		otlpString("dir2txt.format", config.Format),
+	}}
+	scope := otlpScope{Name: "dir2txt"}
+
+	// 根 span 覆盖整次运行，各阶段为子 span；按文件重复执行的阶段合并为一个 span，耗时为累计值
+	traceID, rootID := otlpID(16), otlpID(8)
+	status := otlpStatus{Code: 1}
+	if code != exitOK {
+		status.Code = 2
+	}
+	spans := []otlpSpan{{
+		TraceID: traceID, SpanID: rootID, Name: "dir2txt run", Kind: 1,
+		StartTimeUnixNano: start, EndTimeUnixNano: now,
+		Attributes: []otlpAttr{otlpInt("dir2txt.exit_code", int64(code)), otlpInt("dir2txt.files.included", int64(stats.Included))},
+		Status:     status,
+	}}
+	for _, stage := range benchStages {
+		s, ok := telemetry.stages[stage]
+		if !ok {
+			continue
+		}
+		spans = append(spans, otlpSpan{
+			TraceID: traceID, SpanID: otlpID(8), ParentSpanID: rootID, Name: stage, Kind: 1,
+			StartTimeUnixNano: unixNano(s.Start), EndTimeUnixNano: unixNano(s.Start.Add(s.Total)),
+			Attributes: []otlpAttr{otlpInt("dir2txt.stage.calls", int64(s.Calls))},
+			Status:     otlpStatus{Code: 1},
+		})
+	}
+
+	payloads := []struct {
+		path string
+		body any
+	}{
+		{"/v1/metrics", map[string]any{"resourceMetrics": []any{map[string]any{
+			"resource": resource, "scopeMetrics": []any{map[string]any{"scope": scope, "metrics": metrics}},
+		}}}},
+		{"/v1/traces", map[string]any{"resourceSpans": []any{map[string]any{
+			"resource": resource, "scopeSpans": []any{map[string]any{"scope": scope, "spans": spans}},
+		}}}},
+	}
+	client := &http.Client{Timeout: otelTimeout}
+	for _, p := range payloads {
+		if err := postOTLP(client, strings.TrimRight(config.OTelEndpoint, "/")+p.path, p.body); err != nil {
+			errorf("[WARN] 无法推送遥测数据: %v\n", err)
+		}
+	}
+}
+
+// postOTLP 以 OTLP/HTTP JSON 推送一份数据
+func postOTLP(client *http.Client, url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s 返回 %s", url, resp.Status)
+	}
+	return nil
+}
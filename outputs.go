@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// outputsStateFile 输出目录中记录历次生成的输出文件名的状态文件
+const outputsStateFile = ".dir2txt-outputs"
+
+// priorOutputs 之前的运行在输出目录中生成的文件 (绝对路径)，遍历与目录树中都会排除
+var priorOutputs = map[string]bool{}
+
+// loadPriorOutputs 读取输出目录中的状态文件，只保留仍然存在的输出
+func loadPriorOutputs(outDir string) []string {
+	data, err := os.ReadFile(filepath.Join(outDir, outputsStateFile))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, name := range strings.Split(string(data), "\n") {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.ContainsAny(name, `/\`) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// excludePriorOutputs 将之前在同一输出目录生成的文件加入排除列表，避免连续运行时互相读取对方的输出
+func excludePriorOutputs(outPath string) {
+	outDir := filepath.Dir(outPath)
+	for _, name := range loadPriorOutputs(outDir) {
+		abs, err := filepath.Abs(filepath.Join(outDir, name))
+		if err != nil {
+			continue
+		}
+		priorOutputs[abs] = true
+		priorOutputs[abs+".bak"] = true
+	}
+}
+
+// isPriorOutput 判断路径是否为之前生成的输出或状态文件本身
+func isPriorOutput(path string) bool {
+	return priorOutputs[path] || filepath.Base(path) == outputsStateFile
+}
+
+// recordOutput 在状态文件中记录本次生成的输出文件名
+func recordOutput(outPath string) error {
+	outDir := filepath.Dir(outPath)
+	names := loadPriorOutputs(outDir)
+	name := filepath.Base(outPath)
+	for _, n := range names {
+		if n == name {
+			return nil
+		}
+	}
+	names = append(names, name)
+	return os.WriteFile(filepath.Join(outDir, outputsStateFile), []byte(strings.Join(names, "\n")+"\n"), 0o644)
+}
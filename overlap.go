@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path/filepath"
+)
+
+// nestedRoots 位于其他根目录之中的根目录 (绝对路径)。外层根目录遍历时跳过它们，
+// 其中的文件只在较短的逻辑路径 (内层根目录) 下输出一次
+var nestedRoots = map[string]bool{}
+
+// dedupeRoots 去除重复的根目录，并记录相互包含的根目录
+func dedupeRoots(dirs []string) []string {
+	var result []string
+	var absDirs []string
+	seen := map[string]string{}
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			result = append(result, dir)
+			continue
+		}
+		if real, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = real
+		}
+		if first, dup := seen[abs]; dup {
+			logf("[WARN] 目录 %s 与 %s 相同，只输出一次\n", dir, first)
+			continue
+		}
+		seen[abs] = dir
+		result = append(result, dir)
+		absDirs = append(absDirs, abs)
+	}
+
+	for i, inner := range absDirs {
+		for j, outer := range absDirs {
+			if i != j && hasPathPrefix(inner, outer) {
+				logf("[WARN] 目录 %s 位于 %s 之中，其中的文件只在 %s 下输出一次\n", seen[inner], seen[outer], seen[inner])
+				nestedRoots[inner] = true
+				// 遍历时使用未解析符号链接的路径，两种形式都记录
+				if abs, err := filepath.Abs(seen[inner]); err == nil {
+					nestedRoots[abs] = true
+				}
+				break
+			}
+		}
+	}
+	return result
+}
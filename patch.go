@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// patchFile 记录补丁中涉及的一个文件
+type patchFile struct {
+	Path    string // 补丁应用后的路径 (删除时为原路径)
+	Old     string // 重命名或复制前的路径，应用补丁前需要存在
+	Deleted bool
+}
+
+// parsePatchFiles 从统一 diff 中提取涉及的文件，保持首次出现的顺序
+// git 格式的补丁按 diff --git 头与 rename/copy 头识别，没有 ---/+++ 行的纯重命名也会记录
+func parsePatchFiles(patch string) []patchFile {
+	var files []patchFile
+	index := map[string]int{}
+	add := func(pf patchFile) {
+		if pf.Path == "" {
+			return
+		}
+		if pf.Old == pf.Path {
+			pf.Old = ""
+		}
+		if i, ok := index[pf.Path]; ok {
+			files[i] = pf
+			return
+		}
+		index[pf.Path] = len(files)
+		files = append(files, pf)
+	}
+
+	var cur *patchFile // 当前 diff --git 段落中的文件
+	flush := func() {
+		if cur != nil {
+			add(*cur)
+		}
+		cur = nil
+	}
+	oldPath := ""
+	for _, line := range strings.Split(patch, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			old, path := gitDiffPaths(strings.TrimPrefix(line, "diff --git "))
+			cur = &patchFile{Path: path, Old: old}
+		case cur != nil && (strings.HasPrefix(line, "rename from ") || strings.HasPrefix(line, "copy from ")):
+			_, from, _ := strings.Cut(line, " from ")
+			cur.Old = unquotePatchPath(from)
+		case cur != nil && (strings.HasPrefix(line, "rename to ") || strings.HasPrefix(line, "copy to ")):
+			_, to, _ := strings.Cut(line, " to ")
+			cur.Path = unquotePatchPath(to)
+		case cur != nil && strings.HasPrefix(line, "deleted file mode"):
+			cur.Deleted = true
+		case strings.HasPrefix(line, "--- "):
+			oldPath = patchPath(strings.TrimPrefix(line, "--- "))
+		case strings.HasPrefix(line, "+++ "):
+			newPath := patchPath(strings.TrimPrefix(line, "+++ "))
+			if cur != nil {
+				if newPath == "" {
+					cur.Deleted, cur.Old = true, ""
+				} else {
+					cur.Path = newPath
+				}
+				continue
+			}
+			if newPath == "" {
+				add(patchFile{Path: oldPath, Deleted: true})
+			} else {
+				add(patchFile{Path: newPath})
+			}
+		}
+	}
+	flush()
+	return files
+}
+
+// gitDiffPaths 解析 diff --git 头中的 a/旧路径 与 b/新路径；路径含空格且未加引号时，优先取两侧相同的拆分方式
+func gitDiffPaths(s string) (string, string) {
+	if strings.HasPrefix(s, "\"") {
+		if first, err := strconv.QuotedPrefix(s); err == nil {
+			return patchPath(first), patchPath(strings.TrimSpace(s[len(first):]))
+		}
+	}
+	if i := strings.LastIndex(s, " \""); i >= 0 {
+		return patchPath(s[:i]), patchPath(s[i+1:])
+	}
+	split := -1
+	for i := 0; i+3 <= len(s); i++ {
+		if !strings.HasPrefix(s[i:], " b/") {
+			continue
+		}
+		if split < 0 {
+			split = i
+		}
+		if s[2:i] == s[i+3:] {
+			split = i
+			break
+		}
+	}
+	if split < 0 {
+		return "", ""
+	}
+	return patchPath(s[:split]), patchPath(s[split+1:])
+}
+
+// patchPath 将 diff 头中的路径规范化，/dev/null 返回空串
+func patchPath(s string) string {
+	if i := strings.Index(s, "\t"); i >= 0 {
+		s = s[:i]
+	}
+	s = unquotePatchPath(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
+
+// unquotePatchPath 还原 git 对含特殊字符的路径加的引号与转义 (如 "\344\270\255.go")
+func unquotePatchPath(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "\"") {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+		return strings.Trim(s, "\"")
+	}
+	return s
+}
+
+// postPatchContents 在临时目录中应用补丁，返回补丁后的文件目录
+// 如果补丁已经应用到 root 中，直接返回 root
+func postPatchContents(root string, patchPath string, files []patchFile) (string, func(), error) {
+	noop := func() {}
+
+	tmpDir, err := os.MkdirTemp("", "dir2txt-patch-")
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	// 重命名与复制的源文件也要复制，git apply 需要读取
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+		if f.Old != "" {
+			paths = append(paths, f.Old)
+		}
+	}
+	for _, p := range paths {
+		src := filepath.Join(root, filepath.FromSlash(p))
+		content, err := os.ReadFile(src)
+		if err != nil {
+			continue
+		}
+		dst := filepath.Join(tmpDir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			cleanup()
+			return "", noop, err
+		}
+		if err := os.WriteFile(dst, content, 0o644); err != nil {
+			cleanup()
+			return "", noop, err
+		}
+	}
+
+	apply := exec.Command("git", "apply", "--whitespace=nowarn", patchPath)
+	apply.Dir = tmpDir
+	output, err := apply.CombinedOutput()
+	if err == nil {
+		return tmpDir, cleanup, nil
+	}
+	cleanup()
+
+	reverse := exec.Command("git", "apply", "--reverse", "--check", patchPath)
+	reverse.Dir = root
+	if reverse.Run() == nil {
+		logf("[INFO] 补丁已应用于工作区，直接读取当前文件内容\n")
+		return root, noop, nil
+	}
+	return "", noop, fmt.Errorf("无法应用补丁: %s", strings.TrimSpace(string(output)))
+}
+
+// processPatch 输出补丁本身以及补丁涉及文件的补丁后内容
+func processPatch(root string, patchFilePath string, writer *docWriter) error {
+	absPatch, err := filepath.Abs(patchFilePath)
+	if err != nil {
+		return err
+	}
+	patch, err := os.ReadFile(absPatch)
+	if err != nil {
+		return fmt.Errorf("无法读取补丁文件 %s: %w", patchFilePath, err)
+	}
+	files := parsePatchFiles(string(patch))
+	if len(files) == 0 {
+		return fmt.Errorf("补丁文件 %s 中没有找到任何文件变化", patchFilePath)
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	contentRoot, cleanup, err := postPatchContents(absRoot, absPatch, files)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	writer.WriteString(writer.docHeading("Patch: " + filepath.Base(patchFilePath)))
+	var list strings.Builder
+	for _, f := range files {
+		if f.Deleted {
+			list.WriteString(fmt.Sprintf("%s (deleted)\n", f.Path))
+		} else if f.Old != "" {
+			list.WriteString(fmt.Sprintf("%s -> %s\n", f.Old, f.Path))
+		} else {
+			list.WriteString(f.Path + "\n")
+		}
+	}
+	writer.writeCodeBlock("text", []byte(list.String()))
+	writeFence(writer, "diff", patch)
+
+	writer.writeContentsHeading()
+	for _, f := range files {
+		if f.Deleted {
+			continue
+		}
+		fullPath := filepath.Join(contentRoot, filepath.FromSlash(f.Path))
+		if isAsset(filepath.Base(f.Path)) {
+			continue
+		}
+		if err := processFileAs(fullPath, f.Path, writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
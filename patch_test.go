@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// renamePatch git format-patch 生成的补丁：a.go 重命名为 b.go 并修改，k.txt 纯重命名为 k2.txt
+const renamePatch = `From 1aca6fee6b346e889ed48ad5b8d60a8ab77fcf38 Mon Sep 17 00:00:00 2001
+From: a <a@b>
+Subject: [PATCH] r
+
+---
+ a.go => b.go    | 2 +-
+ k.txt => k2.txt | 0
+ 2 files changed, 1 insertion(+), 1 deletion(-)
+
+diff --git a/a.go b/b.go
+similarity index 78%
+rename from a.go
+rename to b.go
+index 06d7e47..6975fe7 100644
+--- a/a.go
++++ b/b.go
+@@ -1,3 +1,3 @@
+ package a
+ 
+-func A() {}
++func B() {}
+diff --git a/k.txt b/k2.txt
+similarity index 100%
+rename from k.txt
+rename to k2.txt
+diff --git "a/\344\270\255.go" "b/\344\270\255.go"
+deleted file mode 100644
+index 06d7e47..0000000
+--- "a/\344\270\255.go"
++++ /dev/null
+@@ -1 +0,0 @@
+-package a
+-- 
+2.39.5
+`
+
+// TestParsePatchFiles 重命名、纯重命名与带引号的删除都按 git 头识别
+func TestParsePatchFiles(t *testing.T) {
+	got := parsePatchFiles(renamePatch)
+	want := []patchFile{
+		{Path: "b.go", Old: "a.go"},
+		{Path: "k2.txt", Old: "k.txt"},
+		{Path: "中.go", Deleted: true},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("parsePatchFiles = %+v, want %+v", got, want)
+	}
+}
+
+// TestPostPatchContentsRename 重命名并修改的文件在临时目录中应用补丁后得到新路径的内容
+func TestPostPatchContentsRename(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"a.go":  "package a\n\nfunc A() {}\n",
+		"k.txt": "keep\n",
+		"中.go":  "package a\n",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	patchPath := filepath.Join(t.TempDir(), "r.patch")
+	if err := os.WriteFile(patchPath, []byte(renamePatch), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, cleanup, err := postPatchContents(root, patchPath, parsePatchFiles(renamePatch))
+	if err != nil {
+		t.Fatalf("postPatchContents: %v", err)
+	}
+	defer cleanup()
+	for name, want := range map[string]string{"b.go": "package a\n\nfunc B() {}\n", "k2.txt": "keep\n"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q (%v), want %q", name, got, err, want)
+		}
+	}
+}
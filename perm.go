@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// permFilterKinds --perm-filter 支持的条件
+var permFilterKinds = map[string]os.FileMode{
+	"world-writable": 0o002,
+	"setuid":         os.ModeSetuid,
+	"setgid":         os.ModeSetgid,
+}
+
+// permExcluded 判断文件是否命中 --perm-filter，返回命中的条件
+func permExcluded(info os.FileInfo) (string, bool) {
+	for _, kind := range config.PermFilter {
+		mask := permFilterKinds[kind]
+		if kind == "world-writable" {
+			if info.Mode().Perm()&mask != 0 {
+				return kind, true
+			}
+		} else if info.Mode()&mask != 0 {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// parsePermFilter 解析逗号分隔的权限过滤条件
+func parsePermFilter(value string) ([]string, error) {
+	var kinds []string
+	for _, kind := range strings.Split(value, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		if _, ok := permFilterKinds[kind]; !ok {
+			return nil, fmt.Errorf("--perm-filter 只支持 world-writable、setuid、setgid: %s", kind)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// formatPerms 返回权限与属主说明，如 "-rwxr-xr-x 1000:1000"
+func formatPerms(info os.FileInfo) string {
+	perms := info.Mode().String()
+	if owner := fileOwner(info); owner != "" {
+		perms += " " + owner
+	}
+	return perms
+}
+
+// isPermissionDenied 判断错误是否为权限不足
+func isPermissionDenied(err error) bool {
+	return errors.Is(err, fs.ErrPermission)
+}
+
+// isReadable 尝试打开文件，判断当前用户是否有读取权限
+func isReadable(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return !isPermissionDenied(err)
+	}
+	f.Close()
+	return true
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// piiPattern 一类个人信息的匹配规则
+type piiPattern struct {
+	Kind  string
+	Re    *regexp.Regexp
+	Valid func(match string) bool // 进一步校验，减少误报；为 nil 时只按正则判断
+	Mask  string                  // --pii-mask 时的替换文本
+}
+
+// piiPatterns 检测的个人信息类型：邮箱、电话号码、身份证号/SSN
+var piiPatterns = []piiPattern{
+	{
+		Kind:  "email",
+		Re:    regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+		Valid: func(m string) bool { return !isExampleEmail(m) },
+		Mask:  "[EMAIL]",
+	},
+	{
+		Kind: "phone",
+		// 中国大陆手机号、带国家码的国际号码、北美 (555) 123-4567 / 555-123-4567 格式
+		Re:   regexp.MustCompile(`\b1[3-9]\d{9}\b|\+\d{1,3}[ -]?\d{1,4}(?:[ -]\d{2,4}){2,4}\b|\(\d{3}\) ?\d{3}-\d{4}\b|\b\d{3}-\d{3}-\d{4}\b`),
+		Mask: "[PHONE]",
+	},
+	{
+		Kind:  "national-id",
+		Re:    regexp.MustCompile(`\b\d{17}[\dXx]\b`),
+		Valid: validChineseID,
+		Mask:  "[ID]",
+	},
+	{
+		Kind:  "national-id",
+		Re:    regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		Valid: validSSN,
+		Mask:  "[ID]",
+	},
+}
+
+// piiHit 一处个人信息命中
+type piiHit struct {
+	DisplayPath string
+	Line        int
+	Kind        string
+	Sample      string // 部分遮盖后的命中内容
+}
+
+// piiHits 本次运行的全部命中，结束时在概况中列出
+var piiHits []piiHit
+
+// isExampleEmail 文档中常见的示例邮箱不视为个人信息
+func isExampleEmail(email string) bool {
+	_, domain, _ := strings.Cut(strings.ToLower(email), "@")
+	for _, example := range []string{"example.com", "example.org", "example.net", "localhost"} {
+		if domain == example || strings.HasSuffix(domain, "."+example) {
+			return true
+		}
+	}
+	return strings.HasPrefix(strings.ToLower(email), "noreply@") || strings.Contains(strings.ToLower(email), "users.noreply.")
+}
+
+// validChineseID 校验 18 位居民身份证号的校验码
+func validChineseID(id string) bool {
+	weights := []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	checks := "10X98765432"
+	sum := 0
+	for i, w := range weights {
+		sum += int(id[i]-'0') * w
+	}
+	return strings.ToUpper(id[17:]) == string(checks[sum%11])
+}
+
+// validSSN 排除不会分配的美国社会安全号 (000、666、9xx 开头，组号或序号全零)
+func validSSN(ssn string) bool {
+	area, group, serial := ssn[:3], ssn[4:6], ssn[7:]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// redactSample 只保留命中内容的首尾字符，避免在概况中再次泄露
+func redactSample(s string) string {
+	if len(s) <= 4 {
+		return "***"
+	}
+	return s[:2] + strings.Repeat("*", len(s)-4) + s[len(s)-2:]
+}
+
+// scanPII 记录内容中的个人信息命中，--pii-mask 时返回遮盖后的内容
+func scanPII(displayPath string, content []byte) []byte {
+	lines := bytes.Split(content, []byte("\n"))
+	found := false
+	for i, line := range lines {
+		for _, p := range piiPatterns {
+			line = p.Re.ReplaceAllFunc(line, func(m []byte) []byte {
+				if p.Valid != nil && !p.Valid(string(m)) {
+					return m
+				}
+				found = true
+				piiHits = append(piiHits, piiHit{displayPath, i + 1, p.Kind, redactSample(string(m))})
+				if config.PIIMask {
+					return []byte(p.Mask)
+				}
+				return m
+			})
+		}
+		lines[i] = line
+	}
+	if found {
+		// 计为警告，--strict 下可阻止带有个人信息的输出
+		stats.Warnings++
+	}
+	if !config.PIIMask {
+		return content
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// applyPIIScan 在文件选定输出后扫描个人信息，--pii-mask 时替换为遮盖后的内容
+// 被 --max-files 或预算丢弃的文件不扫描，不计入命中
+func applyPIIScan(fc *fileContent) {
+	if !config.PIIScan {
+		return
+	}
+	masked := scanPII(fc.DisplayPath, fc.data())
+	if config.PIIMask {
+		releaseContent(fc)
+		fc.Content = masked
+		fc.Tokens = estimateTokens(masked)
+	}
+}
+
+// printPIISummary 打印个人信息扫描结果
+func printPIISummary() {
+	if len(piiHits) == 0 {
+		fmt.Println("PII 扫描: 未发现邮箱、电话号码或身份证号")
+		return
+	}
+	action := "未遮盖，可使用 --pii-mask 遮盖"
+	if config.PIIMask {
+		action = "已在输出中遮盖"
+	}
+	fmt.Printf("PII 扫描: 发现 %d 处可能的个人信息 (%s):\n", len(piiHits), action)
+	for _, h := range piiHits {
+		fmt.Printf("  %s:%d  %-12s %s\n", h.DisplayPath, h.Line, h.Kind, h.Sample)
+	}
+}
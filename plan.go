@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+)
+
+// errPlanOnly --plan 只打印预估报告，不生成输出
+var errPlanOnly = errors.New("plan only")
+
+// planSampleSize 估算 token 时每个文件读取的开头字节数
+const planSampleSize = 4096
+
+// planEntry --plan 报告中的一行
+type planEntry struct {
+	Rel    string
+	Bytes  int64
+	Tokens int64
+}
+
+// planTokens 按文件开头的采样推算整个文件的 token 数，不读取完整内容
+func planTokens(filePath string, size int64) int64 {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return (size + 3) / 4
+	}
+	defer f.Close()
+	buf := make([]byte, planSampleSize)
+	n, _ := io.ReadFull(f, buf)
+	if n == 0 {
+		return 0
+	}
+	return estimateTokens(buf[:n]) * size / int64(n)
+}
+
+// printPlan 打印将要输出的文件中体积与 token 数最大的前 N 项，以及按目录的汇总
+func printPlan(candidates []candidateFile, limit int) {
+	var entries []planEntry
+	dirs := map[string]*planEntry{}
+	var totalBytes, totalTokens int64
+	for _, c := range candidates {
+		info, err := os.Stat(c.Path)
+		if err != nil {
+			continue
+		}
+		e := planEntry{Rel: c.Rel, Bytes: info.Size(), Tokens: planTokens(c.Path, info.Size())}
+		entries = append(entries, e)
+		totalBytes += e.Bytes
+		totalTokens += e.Tokens
+		dir := path.Dir(c.Rel)
+		if dirs[dir] == nil {
+			dirs[dir] = &planEntry{Rel: dir + "/"}
+		}
+		dirs[dir].Bytes += e.Bytes
+		dirs[dir].Tokens += e.Tokens
+	}
+
+	fmt.Printf("预估输出: %d 个文件, %s, 约 %d tokens (不含目录树与标题)\n", len(entries), formatSize(totalBytes), totalTokens)
+	if config.BudgetTokens > 0 && totalTokens > config.BudgetTokens {
+		fmt.Printf("超过 --budget-tokens %d 约 %d tokens\n", config.BudgetTokens, totalTokens-config.BudgetTokens)
+	}
+	if config.MaxOutputSize > 0 && totalBytes > config.MaxOutputSize {
+		fmt.Printf("超过 --max-output-size %s\n", formatSize(config.MaxOutputSize))
+	}
+
+	var dirEntries []planEntry
+	for _, d := range dirs {
+		dirEntries = append(dirEntries, *d)
+	}
+	printPlanTable("最大的文件", entries, totalTokens, limit)
+	printPlanTable("最大的目录 (仅直接包含的文件)", dirEntries, totalTokens, limit)
+}
+
+// printPlanTable 按 token 数降序打印前 limit 项及其占比
+func printPlanTable(title string, entries []planEntry, totalTokens int64, limit int) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Tokens != entries[j].Tokens {
+			return entries[i].Tokens > entries[j].Tokens
+		}
+		return entries[i].Rel < entries[j].Rel
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	fmt.Printf("\n%s:\n", title)
+	fmt.Printf("  %10s  %10s  %6s  %s\n", "bytes", "tokens", "share", "path")
+	for _, e := range entries {
+		share := 0.0
+		if totalTokens > 0 {
+			share = float64(e.Tokens) * 100 / float64(totalTokens)
+		}
+		fmt.Printf("  %10s  %10d  %5.1f%%  %s\n", formatSize(e.Bytes), e.Tokens, share, e.Rel)
+	}
+}
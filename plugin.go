@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// fileProcessor 自定义文件处理器：匹配文件 → 转换内容 → 以 Markdown 输出
+type fileProcessor interface {
+	Name() string
+	Match(path string) bool
+	Process(path string, content []byte) ([]byte, error)
+}
+
+// processors 已注册的处理器，按注册顺序匹配，第一个匹配的生效
+var processors []fileProcessor
+
+// matchProcessor 返回第一个匹配该文件的处理器
+func matchProcessor(path string) fileProcessor {
+	for _, p := range processors {
+		if p.Match(path) {
+			return p
+		}
+	}
+	return nil
+}
+
+// execProcessor 基于外部命令的插件：文件内容写入标准输入，标准输出为渲染后的 Markdown
+type execProcessor struct {
+	command  string
+	name     string
+	patterns []string // 按文件名匹配的 glob，如 *.ipynb
+}
+
+// pluginDescription 插件以 --describe 参数运行时输出的 JSON
+type pluginDescription struct {
+	Name     string   `json:"name"`
+	Patterns []string `json:"patterns"`
+}
+
+// newExecProcessor 解析 --plugin 参数：
+// "PATTERNS=CMD" 直接指定匹配的文件名 (逗号分隔)，否则运行 "CMD --describe" 询问插件
+func newExecProcessor(spec string) (*execProcessor, error) {
+	if patterns, command, ok := strings.Cut(spec, "="); ok && !strings.ContainsAny(patterns, `/\`) {
+		p := &execProcessor{command: command, name: filepath.Base(command)}
+		for _, pattern := range strings.Split(patterns, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				p.patterns = append(p.patterns, pattern)
+			}
+		}
+		return p, nil
+	}
+
+	out, err := exec.Command(spec, "--describe").Output()
+	if err != nil {
+		return nil, fmt.Errorf("无法获取插件信息 (%s --describe): %v", spec, err)
+	}
+	var desc pluginDescription
+	if err := json.Unmarshal(out, &desc); err != nil {
+		return nil, fmt.Errorf("插件 %s 的 --describe 输出不是有效的 JSON: %v", spec, err)
+	}
+	if len(desc.Patterns) == 0 {
+		return nil, fmt.Errorf("插件 %s 没有声明匹配的文件 (patterns)", spec)
+	}
+	if desc.Name == "" {
+		desc.Name = filepath.Base(spec)
+	}
+	return &execProcessor{command: spec, name: desc.Name, patterns: desc.Patterns}, nil
+}
+
+func (p *execProcessor) Name() string { return p.name }
+
+func (p *execProcessor) Match(file string) bool {
+	name := filepath.Base(file)
+	for _, pattern := range p.patterns {
+		if m, _ := path.Match(pattern, name); m || pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *execProcessor) Process(file string, content []byte) ([]byte, error) {
+	cmd := exec.Command(p.command)
+	cmd.Stdin = bytes.NewReader(content)
+	cmd.Env = append(os.Environ(), "DIR2TXT_FILE="+file)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("插件 %s 处理失败: %v %s", p.name, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
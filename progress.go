@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// 状态行在终端中的刷新间隔；非终端 (--progress 强制开启时) 改为定期打印一行
+const (
+	progressTTYInterval   = 200 * time.Millisecond
+	progressPlainInterval = 10 * time.Second
+)
+
+// progressMeter 在标准错误上显示扫描与生成的速度和预计剩余时间
+type progressMeter struct {
+	mu      sync.Mutex
+	enabled bool
+	tty     bool
+	shown   bool // 终端中当前显示着状态行
+	logTTY  bool // 运行日志 (标准输出) 也写到终端，打印前需要清除状态行
+
+	phase   string // "scan" 或 "read"
+	start   time.Time
+	last    time.Time
+	entries int   // 扫描阶段已遍历的条目数
+	total   int   // 生成阶段的候选文件总数 (扫描阶段得到的预计数)
+	done    int   // 已处理的候选文件数
+	bytes   int64 // 已读取的字节数
+}
+
+var progress progressMeter
+
+// isTerminal 判断文件是否连接到终端 (字符设备，但不是重定向到的空设备)
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+// initProgress 默认只在标准错误是终端时显示状态行；--progress 在重定向时也定期打印，--no-progress 关闭
+func initProgress() {
+	progress.tty = isTerminal(os.Stderr)
+	progress.logTTY = progress.tty && isTerminal(os.Stdout)
+	switch {
+	case config.CI || config.Progress == "never":
+		progress.enabled = false
+	case config.Progress == "always":
+		progress.enabled = true
+	default:
+		progress.enabled = progress.tty
+	}
+}
+
+// startScan 开始遍历阶段
+func (p *progressMeter) startScan() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.phase, p.start, p.last, p.entries = "scan", time.Now(), time.Time{}, 0
+}
+
+// scanned 遍历到一个条目
+func (p *progressMeter) scanned() {
+	if !p.enabled {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries++
+	p.maybeRender()
+}
+
+// startRead 开始读取与写入文件内容，total 为遍历得到的候选文件数
+func (p *progressMeter) startRead(total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.phase, p.start, p.last = "read", time.Now(), time.Time{}
+	p.total, p.done, p.bytes = total, 0, 0
+}
+
+// fileDone 处理完一个候选文件 (无论是否写入)，size 为读取的字节数
+func (p *progressMeter) fileDone(size int64) {
+	if !p.enabled {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.bytes += size
+	p.maybeRender()
+}
+
+// maybeRender 按刷新间隔重绘状态行，调用方需持有锁
+func (p *progressMeter) maybeRender() {
+	interval := progressPlainInterval
+	if p.tty {
+		interval = progressTTYInterval
+	}
+	now := time.Now()
+	if now.Sub(p.last) < interval {
+		return
+	}
+	if now.Sub(p.start) < interval {
+		// 很快就能完成的运行不显示状态行
+		return
+	}
+	p.last = now
+	line := p.status(now.Sub(p.start))
+	if p.tty {
+		fmt.Fprintf(os.Stderr, "\r\033[K%s", line)
+		p.shown = true
+	} else {
+		fmt.Fprintln(os.Stderr, line)
+	}
+}
+
+// status 生成状态行文本
+func (p *progressMeter) status(elapsed time.Duration) string {
+	secs := elapsed.Seconds()
+	if secs <= 0 {
+		secs = 1e-3
+	}
+	if p.phase == "scan" {
+		return fmt.Sprintf("扫描中: %d 个条目, %.0f 条/s, 已用 %s", p.entries, float64(p.entries)/secs, formatElapsed(elapsed))
+	}
+	eta := "--"
+	if p.done > 0 && p.total >= p.done {
+		remaining := time.Duration(float64(elapsed) / float64(p.done) * float64(p.total-p.done))
+		eta = formatElapsed(remaining)
+	}
+	percent := 0.0
+	if p.total > 0 {
+		percent = float64(p.done) * 100 / float64(p.total)
+	}
+	return fmt.Sprintf("[%d/%d] %.1f%%, %.0f 文件/s, %.1f MB/s, 预计剩余 %s",
+		p.done, p.total, percent, float64(p.done)/secs, float64(p.bytes)/secs/(1024*1024), eta)
+}
+
+// clear 清除终端中的状态行；打印日志前与结束时调用，避免日志与状态行混在同一行
+func (p *progressMeter) clear() {
+	if !p.enabled || !p.tty {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.shown {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		p.shown = false
+		// 日志打印后的下一次更新立即重绘，使状态行始终位于日志下方
+		p.last = time.Time{}
+	}
+}
+
+// clearForLog 标准输出也是终端时，打印运行日志前清除状态行
+func (p *progressMeter) clearForLog() {
+	if p.logTTY {
+		p.clear()
+	}
+}
+
+// formatElapsed 将时长格式化为 h:mm:ss 或 m:ss
+func formatElapsed(d time.Duration) string {
+	s := int(d.Round(time.Second).Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readPromptFile 读取 --preamble / --postamble 指定的文件
+func readPromptFile(flagName, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s 无法读取文件 %s: %v", flagName, path, err)
+	}
+	return string(data), nil
+}
+
+// setPrompt 处理 --preamble、--postamble 与 --prompt 参数
+func setPrompt(flagName, value string) error {
+	if flagName == "--prompt" {
+		config.Preamble = appendPrompt(config.Preamble, value)
+		return nil
+	}
+	text, err := readPromptFile(flagName, value)
+	if err != nil {
+		return err
+	}
+	if flagName == "--preamble" {
+		config.Preamble = appendPrompt(config.Preamble, text)
+	} else {
+		config.Postamble = appendPrompt(config.Postamble, text)
+	}
+	return nil
+}
+
+// appendPrompt 将文本追加到已有的提示词之后，两段之间空一行
+func appendPrompt(existing, text string) string {
+	text = strings.TrimSpace(text)
+	if existing == "" {
+		return text
+	}
+	return existing + "\n\n" + text
+}
+
+// writePreamble 在目录树之前写入用户提供的说明
+func writePreamble(writer *docWriter) {
+	if config.Preamble == "" {
+		return
+	}
+	writer.WriteString(config.Preamble + "\n\n")
+	writer.writeSeparator()
+}
+
+// writePostamble 在全部文件内容之后写入用户提供的说明
+func writePostamble(writer *docWriter) {
+	if config.Postamble == "" {
+		return
+	}
+	writer.WriteString(config.Postamble + "\n")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// ragChunkLines 单个 RAG 分块的目标最大行数
+const ragChunkLines = 80
+
+// ragChunk 对应 --format rag-jsonl 输出的一行
+type ragChunk struct {
+	Path       string `json:"path"`
+	ChunkIndex int    `json:"chunk_index"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	Language   string `json:"language"`
+	Text       string `json:"text"`
+}
+
+// boundaryRe 匹配常见语言中顶层函数/类/类型定义的起始行
+var boundaryRe = regexp.MustCompile(`^(func|def|async def|class|function|async function|export|public|private|protected|internal|static|abstract|fn|pub|impl|struct|enum|interface|type|trait|module|object|namespace|template|CREATE|create)\b`)
+
+// decoratorRe 匹配定义之前应当归属同一分块的注释与注解行
+var decoratorRe = regexp.MustCompile(`^(//|#|/\*|\*|@|\[|--)`)
+
+// chunkBoundaries 返回各分块的起始行下标 (从 0 开始)
+// 在顶层定义处切分，并把紧邻定义之前的注释/注解一并归入该定义
+func chunkBoundaries(lines []string) []int {
+	bounds := []int{0}
+	for i := 1; i < len(lines); i++ {
+		if !boundaryRe.MatchString(lines[i]) {
+			continue
+		}
+		start := i
+		for start-1 > bounds[len(bounds)-1] && decoratorRe.MatchString(lines[start-1]) {
+			start--
+		}
+		if start > bounds[len(bounds)-1] {
+			bounds = append(bounds, start)
+		}
+	}
+	return bounds
+}
+
+// chunkLines 将内容按定义边界切分为若干 [start, end) 行区间：
+// 相邻的小段合并到不超过 ragChunkLines 行，超长的段优先在空行处拆分
+func chunkLines(lines []string) [][2]int {
+	bounds := append(chunkBoundaries(lines), len(lines))
+
+	var chunks [][2]int
+	start := 0
+	for k := 1; k < len(bounds); k++ {
+		segStart, segEnd := bounds[k-1], bounds[k]
+		if segEnd-start <= ragChunkLines {
+			continue
+		}
+		// 当前累积的分块加上这一段会超长，先输出已累积的部分
+		if segStart > start {
+			chunks = append(chunks, [2]int{start, segStart})
+			start = segStart
+		}
+		// 单个段本身超长时继续拆分
+		for segEnd-start > ragChunkLines {
+			cut := start + ragChunkLines
+			for j := cut; j > start+ragChunkLines/2; j-- {
+				if strings.TrimSpace(lines[j-1]) == "" {
+					cut = j
+					break
+				}
+			}
+			chunks = append(chunks, [2]int{start, cut})
+			start = cut
+		}
+	}
+	if start < len(lines) {
+		chunks = append(chunks, [2]int{start, len(lines)})
+	}
+	return chunks
+}
+
+// writeRAGChunks 将文件按分块写为 JSON Lines
+func writeRAGChunks(fc *fileContent, writer *docWriter) {
+	lines := strings.Split(strings.TrimSuffix(string(fc.data()), "\n"), "\n")
+	enc := json.NewEncoder(writer)
+	enc.SetEscapeHTML(false)
+	for i, c := range chunkLines(lines) {
+		enc.Encode(ragChunk{
+			Path:       fc.DisplayPath,
+			ChunkIndex: i,
+			StartLine:  c[0] + 1,
+			EndLine:    c[1],
+			Language:   codeLang(fc.Path),
+			Text:       strings.Join(lines[c[0]:c[1]], "\n"),
+		})
+	}
+}
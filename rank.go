@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fileScore 记录文件的重要性分数及各项构成，便于打印排名
+type fileScore struct {
+	Total      float64
+	Entrypoint float64 // 入口文件 / README / 契约文件 / 清单文件
+	Recency    float64 // 最近修改
+	References float64 // 被其他文件导入的次数
+	Size       float64 // 小文件加分
+	Test       float64 // 测试文件减分
+}
+
+// importLineRe 匹配常见语言的导入语句行
+var importLineRe = regexp.MustCompile(`^\s*(import|from|require|#include|use|using)\b|require\(|import\(`)
+
+// scoreFiles 计算每个文件的重要性分数
+func scoreFiles(files []*fileContent) map[*fileContent]*fileScore {
+	scores := map[*fileContent]*fileScore{}
+
+	var newest time.Time
+	for _, fc := range files {
+		if fc.ModTime.After(newest) {
+			newest = fc.ModTime
+		}
+	}
+
+	// 一次遍历建立 名称 -> 导入该名称的文件数 的索引，避免逐个文件两两匹配
+	words := make([]map[string]bool, len(files))
+	importers := map[string]int{}
+	for i, fc := range files {
+		words[i] = importWords(importText(fc.data()))
+		for word := range words[i] {
+			importers[word]++
+		}
+	}
+
+	for i, fc := range files {
+		s := &fileScore{}
+		name := strings.ToLower(filepath.Base(fc.Path))
+		stem := strings.TrimSuffix(name, filepath.Ext(name))
+
+		// 1. 入口文件、README 与清单文件
+		switch {
+		case strings.HasPrefix(name, "readme"):
+			s.Entrypoint = 50
+		case stem == "main" || stem == "index" || stem == "app" || stem == "server" || name == "__main__.py":
+			s.Entrypoint = 30
+		case contractKind(name) != "":
+			// 契约文件定义了服务接口，重要性高于其大小所体现的
+			s.Entrypoint = 40
+		case manifestParsers[filepath.Base(fc.Path)].parse != nil:
+			s.Entrypoint = 20
+		}
+
+		// 2. 最近修改：与最新的文件相比，一周内满分，此后按天衰减，90 天后不再加分
+		if !newest.IsZero() {
+			age := newest.Sub(fc.ModTime).Hours() / 24
+			switch {
+			case age <= 7:
+				s.Recency = 20
+			case age < 90:
+				s.Recency = 20 * (90 - age) / 83
+			}
+		}
+
+		// 3. 被其他文件导入：按文件名 (Go 按目录名，即包名) 在导入语句中出现的文件数计分，不计文件自身
+		key := stem
+		if strings.HasSuffix(name, ".go") {
+			key = filepath.Base(filepath.Dir(fc.Path))
+		}
+		refs := 0
+		if len(key) >= 3 {
+			refs = importers[key]
+			if words[i][key] {
+				refs--
+			}
+		}
+		s.References = float64(refs) * 5
+		if s.References > 30 {
+			s.References = 30
+		}
+
+		// 4. 小文件加分：64K 以内线性递减
+		const smallLimit = 64 * 1024
+		if fc.Size < smallLimit {
+			s.Size = 20 * (1 - float64(fc.Size)/smallLimit)
+		}
+
+		// 5. 测试文件降低优先级
+		if strings.Contains(name, "_test.") || strings.Contains(name, ".test.") || strings.Contains(name, ".spec.") || strings.HasPrefix(name, "test_") {
+			s.Test = -10
+		}
+
+		s.Total = s.Entrypoint + s.Recency + s.References + s.Size + s.Test
+		scores[fc] = s
+	}
+	return scores
+}
+
+// importText 提取文件中的导入语句，包括 Go 的 import ( ... ) 块
+func importText(content []byte) string {
+	var sb strings.Builder
+	inBlock := false
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inBlock:
+			if trimmed == ")" {
+				inBlock = false
+				continue
+			}
+			sb.WriteString(trimmed + "\n")
+		case trimmed == "import (":
+			inBlock = true
+		case importLineRe.MatchString(line):
+			sb.WriteString(trimmed + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// importWordRe 导入语句中的名称：单词，以及带连字符的包名 (如 my-lib)
+var importWordRe = regexp.MustCompile(`[\w-]+`)
+
+// importWords 返回导入语句中出现的名称集合；带连字符的名称同时记录各个部分
+func importWords(text string) map[string]bool {
+	words := map[string]bool{}
+	for _, word := range importWordRe.FindAllString(text, -1) {
+		words[word] = true
+		if strings.Contains(word, "-") {
+			for _, part := range strings.Split(word, "-") {
+				if part != "" {
+					words[part] = true
+				}
+			}
+		}
+	}
+	return words
+}
+
+// rankFiles 按重要性从高到低排序 (分数相同时保持原有顺序)
+func rankFiles(files []*fileContent, scores map[*fileContent]*fileScore) []*fileContent {
+	ordered := append([]*fileContent{}, files...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return scores[ordered[i]].Total > scores[ordered[j]].Total
+	})
+	return ordered
+}
+
+// printRanking 在控制台打印文件重要性排名
+func printRanking(ordered []*fileContent, scores map[*fileContent]*fileScore) {
+	fmt.Println("文件重要性排名:")
+	// 中文表头每个字符占两列，宽度按 4 个字符对齐到 6 列
+	fmt.Printf("  %4s  %4s  %4s  %4s  %4s  %4s  %4s  %s\n", "#", "总分", "入口", "最近", "引用", "大小", "测试", "文件")
+	for i, fc := range ordered {
+		s := scores[fc]
+		fmt.Printf("  %4d  %6.1f  %6.1f  %6.1f  %6.1f  %6.1f  %6.1f  %s\n",
+			i+1, s.Total, s.Entrypoint, s.Recency, s.References, s.Size, s.Test, fc.DisplayPath)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+// TestScoreFilesReferences 按导入该文件 (Go 为包目录) 的其他文件数计分，不计文件自身
+func TestScoreFilesReferences(t *testing.T) {
+	file := func(path, content string) *fileContent {
+		return &fileContent{Path: path, DisplayPath: path, Content: []byte(content)}
+	}
+	util := file("src/utils.py", "import os\n")
+	lib := file("web/my-lib.js", "const utils = require('./utils')\n")
+	app := file("web/app.js", "import lib from './my-lib'\nimport { x } from './utils'\n")
+	store := file("store/store.go", "package store\n\nimport (\n\t\"example.com/m/store/cache\"\n)\n")
+	api := file("api/api.go", "package api\n\nimport (\n\t\"fmt\"\n\t\"example.com/m/store\"\n)\n")
+	cmd := file("cmd/main.go", "package main\n\nimport \"example.com/m/store\"\n")
+	files := []*fileContent{util, lib, app, store, api, cmd}
+
+	scores := scoreFiles(files)
+	tests := []struct {
+		fc   *fileContent
+		want float64
+	}{
+		{util, 10},  // my-lib.js 与 app.js
+		{lib, 5},    // app.js
+		{app, 0},    // 无人导入
+		{store, 10}, // api.go 与 main.go，不计 store.go 自身导入的 store/cache
+		{api, 0},
+	}
+	for _, tt := range tests {
+		if got := scores[tt.fc].References; got != tt.want {
+			t.Errorf("%s: References = %v, want %v", tt.fc.Path, got, tt.want)
+		}
+	}
+}
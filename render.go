@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"io"
+)
+
+// renderer 一种输出格式的写入方式；processDirs 依次调用 Begin、WriteTree、对每个文件调用 WriteFile，最后调用 End。
+// 各格式的标题、行内代码、代码块等排版由各自的类型实现 (见 format.go)
+type renderer interface {
+	Begin(w *docWriter)
+	WriteTree(header *projectHeader, w *docWriter)
+	WriteFile(fc *fileContent, w *docWriter)
+	End(w *docWriter)
+
+	// document 是否为输出目录树与文件章节的文档格式 (rag-jsonl 只输出分块)
+	document() bool
+	// heading 生成 level 级标题
+	heading(level int, title string) string
+	// inlineCode 生成行内代码
+	inlineCode(s string) string
+	// blockQuote 生成引用段落
+	blockQuote(s string) string
+	// strong 生成加粗文本
+	strong(s string) string
+	// docLink 生成指向 target 的链接
+	docLink(text string, target string) string
+	// fileHeadingText 文件章节的标题文本；目录树链接与标题共用，保证锚点一致
+	fileHeadingText(displayPath string) string
+	// contentsHeading 文件章节之前的 "File Contents" 标题，不需要时返回空串
+	contentsHeading(level int) string
+	// codeBlock 写入代码块
+	codeBlock(w *bufio.Writer, lang string, content []byte)
+	// separator 写入章节之间的分隔线
+	separator(w *bufio.Writer)
+	// table 写入带表头的表格，单元格中的表格语法字符由各格式转义
+	table(w *bufio.Writer, header []string, rows [][]string)
+}
+
+// treeLinker 支持 --tree-links 的格式：目录树输出为列表，文件名链接到对应的内容章节
+type treeLinker interface {
+	// escapeLinkText 转义文件名中会被当作标记语法的字符
+	escapeLinkText(s string) string
+	// sectionLink 链接到同一文档中的章节
+	sectionLink(text string, anchor string) string
+	// fileAnchors 为将要输出的文件计算章节锚点 (文件系统路径 -> 锚点)，顺序与章节写入顺序一致
+	fileAnchors(candidates []candidateFile) map[string]string
+}
+
+// renderers 各输出格式的写入方式；内置的文档格式在此登记，并在 outputExts 中登记文件后缀，
+// 只输出文件记录的格式也可以通过 RegisterRenderer 登记
+var renderers = map[string]renderer{
+	"md":        markdownRenderer{},
+	"rst":       rstRenderer{},
+	"org":       orgRenderer{},
+	"plain":     plainRenderer{},
+	"rag-jsonl": ragRenderer{},
+}
+
+// Renderer 只输出文件记录、没有目录树与附加章节的输出格式 (如 JSON)，通过 RegisterRenderer 登记后可用 --format 选择。
+// 依次调用 Begin、对每个文件调用 WriteFile，最后调用 End；返回的错误计为警告
+type Renderer interface {
+	Ext() string // 输出文件后缀，如 ".json"
+	Begin(w io.Writer) error
+	WriteFile(w io.Writer, f File) error
+	End(w io.Writer) error
+}
+
+// RegisterRenderer 登记名为 name 的输出格式，应在 init 中调用；与已有格式重名时 panic
+func RegisterRenderer(name string, r Renderer) {
+	if _, ok := renderers[name]; ok {
+		panic("dir2txt: 输出格式重复登记: " + name)
+	}
+	renderers[name] = registeredRenderer{r: r}
+	outputExts[name] = r.Ext()
+}
+
+// registeredRenderer 将 Renderer 接入写入流程：没有目录树与附录，表格等排版沿用 plain 格式
+type registeredRenderer struct {
+	plainRenderer
+	r Renderer
+}
+
+func (registeredRenderer) document() bool { return false }
+
+func (p registeredRenderer) Begin(w *docWriter) {
+	reportRenderError(p.r.Begin(w))
+}
+
+func (registeredRenderer) WriteTree(header *projectHeader, w *docWriter) {}
+
+func (p registeredRenderer) WriteFile(fc *fileContent, w *docWriter) {
+	reportRenderError(p.r.WriteFile(w, fileRecord(fc)))
+}
+
+func (p registeredRenderer) End(w *docWriter) {
+	reportRenderError(p.r.End(w))
+}
+
+func reportRenderError(err error) {
+	if err != nil {
+		logf("[WARN] 输出格式写入失败: %v\n", err)
+		stats.Warnings++
+	}
+}
+
+// docWriter 按某一输出格式写入的输出；写入文档的函数都经由它取得格式，而不是读取 config.Format
+type docWriter struct {
+	*bufio.Writer
+	renderer
+}
+
+// newDocWriter 以 format 格式写入 w
+func newDocWriter(w *bufio.Writer, format string) *docWriter {
+	return &docWriter{Writer: w, renderer: renderers[format]}
+}
+
+// writeCodeBlock 按输出格式写入代码块
+func (w *docWriter) writeCodeBlock(lang string, content []byte) {
+	w.codeBlock(w.Writer, lang, content)
+}
+
+// writeSeparator 章节之间的分隔线
+func (w *docWriter) writeSeparator() {
+	w.separator(w.Writer)
+}
+
+// writeTable 按输出格式写入表格
+func (w *docWriter) writeTable(header []string, rows [][]string) {
+	w.table(w.Writer, header, rows)
+}
+
+// documentSections 文档格式共用的结构：开头的目录树与附加章节、逐个文件的章节、结尾的附录
+type documentSections struct{}
+
+func (documentSections) Begin(w *docWriter) {
+	writePreamble(w)
+}
+
+func (documentSections) WriteTree(header *projectHeader, w *docWriter) {
+	writeProjectHeader(header, w)
+}
+
+func (documentSections) WriteFile(fc *fileContent, w *docWriter) {
+	renderFileSection(fc, w)
+}
+
+func (documentSections) End(w *docWriter) {
+	writeOmittedAppendix(w)
+	if config.SummarizeExcluded {
+		writeExcludedSummaries(w)
+	}
+	writeSnapshotChanges(w)
+	writePostamble(w)
+}
+
+// ragRenderer rag-jsonl：只输出文件内容的分块，没有目录树与附录
+type ragRenderer struct{ plainRenderer }
+
+func (ragRenderer) document() bool { return false }
+
+func (ragRenderer) Begin(w *docWriter) {}
+
+func (ragRenderer) WriteTree(header *projectHeader, w *docWriter) {}
+
+func (ragRenderer) WriteFile(fc *fileContent, w *docWriter) {
+	writeRAGChunks(fc, w)
+}
+
+func (ragRenderer) End(w *docWriter) {}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// renderDoc 以 format 格式写入 files，返回输出
+func renderDoc(t *testing.T, format string, files []*fileContent) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	w := newDocWriter(bw, format)
+	w.Begin(w)
+	w.WriteTree(nil, w)
+	for _, fc := range files {
+		w.WriteFile(fc, w)
+	}
+	w.End(w)
+	bw.Flush()
+	return buf.Bytes()
+}
+
+// TestJSONRenderer --format json 的输出是合法的 JSON 数组，没有文件时为空数组
+func TestJSONRenderer(t *testing.T) {
+	files := []*fileContent{
+		{Path: "/src/proj/main.go", RelPath: "proj/main.go", Content: []byte("package main\n"), Size: 13},
+		{Path: "/src/proj/README.md", RelPath: "proj/README.md", Content: []byte("# \"hi\"\n"), Size: 7},
+	}
+	for _, n := range []int{0, 1, 2} {
+		var got []jsonFile
+		out := renderDoc(t, "json", files[:n])
+		if err := json.Unmarshal(out, &got); err != nil {
+			t.Fatalf("%d files: invalid JSON %q: %v", n, out, err)
+		}
+		if len(got) != n {
+			t.Fatalf("%d files: got %d elements", n, len(got))
+		}
+	}
+	var got []jsonFile
+	json.Unmarshal(renderDoc(t, "json", files), &got)
+	if got[0].Path != "proj/main.go" || got[0].Language != "go" || got[1].Content != "# \"hi\"\n" {
+		t.Errorf("json output = %+v", got)
+	}
+}
+
+// countRenderer 测试用的登记格式，每个文件写一行路径
+type countRenderer struct{}
+
+func (countRenderer) Ext() string { return ".count" }
+
+func (countRenderer) Begin(w io.Writer) error {
+	_, err := io.WriteString(w, "begin\n")
+	return err
+}
+
+func (countRenderer) WriteFile(w io.Writer, f File) error {
+	_, err := fmt.Fprintf(w, "%s %d\n", f.RelPath, len(f.Content))
+	return err
+}
+
+func (countRenderer) End(w io.Writer) error {
+	_, err := io.WriteString(w, "end\n")
+	return err
+}
+
+// TestRegisterRenderer 登记的格式可按名称选择，只收到文件记录，没有目录树与附录
+func TestRegisterRenderer(t *testing.T) {
+	RegisterRenderer("test-count", countRenderer{})
+	t.Cleanup(func() {
+		delete(renderers, "test-count")
+		delete(outputExts, "test-count")
+	})
+	if outputExts["test-count"] != ".count" {
+		t.Errorf("outputExts[test-count] = %q, want .count", outputExts["test-count"])
+	}
+	out := renderDoc(t, "test-count", []*fileContent{{Path: "/a/b.txt", RelPath: "a/b.txt", Content: []byte("xyz")}})
+	if want := "begin\na/b.txt 3\nend\n"; string(out) != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("registering md again did not panic")
+		}
+	}()
+	RegisterRenderer("md", countRenderer{})
+}
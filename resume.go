@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// resumeSaveInterval 两次保存断点之间的最小间隔，避免每个文件都写一次状态文件
+const resumeSaveInterval = time.Second
+
+// errResumeMismatch 断点之前的文件列表与本次遍历结果不一致
+var errResumeMismatch = errors.New("目录内容与上次运行不一致，请去掉 --resume 重新运行")
+
+// resumeState 断点状态，记录已完整写入的文件数以及此时输出文件的长度
+type resumeState struct {
+	Args     []string `json:"args"`      // 除 --resume 外的命令行参数，参数变化时不能续传
+	Done     int      `json:"done"`      // 已完整写入的候选文件数
+	LastFile string   `json:"last_file"` // 最后一个完整写入的文件，用于确认目录内容未变化
+	Bytes    int64    `json:"bytes"`     // 此时输出文件的字节数
+	ASCII    int64    `json:"ascii"`
+	Wide     int64    `json:"wide"`
+	Stats    runStats `json:"stats"`
+}
+
+// resumeRun 当前运行的断点信息
+var resumeRun struct {
+	path     string       // 状态文件路径，为空表示本次运行不支持断点续传
+	args     []string     // 本次运行的参数
+	resumed  *resumeState // 非空表示本次从该断点继续
+	lastSave time.Time
+}
+
+// resumable 判断当前参数下能否断点续传：只有按遍历顺序逐个写入单个输出文件时才支持
+// 预算、排序与 --max-files 需要先读取全部文件再决定输出，与 processDirs 中的缓冲模式条件一致
+func resumable() bool {
+	if _, ok := renderers[config.Format].(registeredRenderer); ok {
+		return false
+	}
+	return config.BudgetTokens == 0 && !config.Rank && !config.ShowRank && config.MaxFiles == 0 &&
+		config.Compress == "" && config.Explode == "" && config.ApplyDiff == ""
+}
+
+// resumeStatePath 状态文件与输出文件放在同一目录，以点开头以免出现在目录树中
+func resumeStatePath(outPath string) string {
+	return filepath.Join(filepath.Dir(outPath), "."+filepath.Base(outPath)+".resume")
+}
+
+// resumeArgs 返回去掉 --resume 后的参数列表 (-- 之后的同名目录保留)
+func resumeArgs(args []string) []string {
+	var kept []string
+	for i, arg := range args {
+		if arg == "--" {
+			return append(kept, args[i:]...)
+		}
+		if arg != "--resume" {
+			kept = append(kept, arg)
+		}
+	}
+	return kept
+}
+
+// loadResumeState 读取并校验断点状态，参数不一致或输出文件长度不足时返回错误
+func loadResumeState(statePath string, outPath string, args []string) (*resumeState, error) {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil, fmt.Errorf("没有找到断点状态文件: %s", statePath)
+	}
+	var state resumeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("断点状态文件已损坏: %v", err)
+	}
+	if fmt.Sprint(state.Args) != fmt.Sprint(args) {
+		return nil, fmt.Errorf("命令行参数与上次运行不一致 (上次: %v)", state.Args)
+	}
+	info, err := os.Stat(outPath)
+	if err != nil || info.Size() < state.Bytes {
+		return nil, fmt.Errorf("输出文件缺失或已被修改: %s", outPath)
+	}
+	return &state, nil
+}
+
+// openResumedOutput 打开上次的输出文件，截断到最后一个完整写入的文件之后
+func openResumedOutput(outPath string, state *resumeState) (*os.File, error) {
+	f, err := os.OpenFile(outPath, os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(state.Bytes); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(state.Bytes, 0); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// checkpoint 在完整写入一个文件后调用，按间隔保存断点；force 为 true 时立即保存
+func checkpoint(writer *bufio.Writer, done int, lastFile string, force bool) {
+	if resumeRun.path == "" {
+		return
+	}
+	if !force && time.Since(resumeRun.lastSave) < resumeSaveInterval {
+		return
+	}
+	resumeRun.lastSave = time.Now()
+	if err := writer.Flush(); err != nil {
+		return
+	}
+	state := resumeState{
+		Args:     resumeRun.args,
+		Done:     done,
+		LastFile: lastFile,
+		Bytes:    output.bytes,
+		ASCII:    output.ascii,
+		Wide:     output.wide,
+		Stats:    stats,
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	// 先写临时文件再改名，避免崩溃时留下不完整的状态文件
+	tmp := resumeRun.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	os.Rename(tmp, resumeRun.path)
+}
+
+// clearResumeState 运行完整结束后删除状态文件
+func clearResumeState() {
+	if resumeRun.path != "" {
+		os.Remove(resumeRun.path)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// rootFiles 作为位置参数直接给出的文件，不经过目录遍历与过滤规则，直接输出内容
+var rootFiles []candidateFile
+
+// splitRootFiles 从位置参数中分离出文件，返回其余的目录参数；
+// 文件的逻辑路径为相对当前目录的路径 (位于当前目录之外时只保留文件名)
+func splitRootFiles(args []string) ([]string, []candidateFile, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, err
+	}
+	var dirs []string
+	var files []candidateFile
+	seen := map[string]bool{}
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil || info.IsDir() {
+			// 不存在的路径仍按目录处理，由遍历报告错误
+			dirs = append(dirs, arg)
+			continue
+		}
+		if kind := specialModeKind(info.Mode()); kind != "" {
+			return nil, nil, fmt.Errorf("不能读取特殊文件 (%s): %s", kind, arg)
+		}
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		rel, err := filepath.Rel(cwd, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			rel = filepath.Base(abs)
+		}
+		files = append(files, candidateFile{Path: abs, Rel: filepath.ToSlash(rel)})
+	}
+	return dirs, files, nil
+}
+
+// appendRootFiles 将直接给出的文件加入候选列表，已在某个根目录中出现的文件不重复加入
+func appendRootFiles(candidates []candidateFile) []candidateFile {
+	listed := map[string]bool{}
+	for _, c := range candidates {
+		listed[c.Path] = true
+	}
+	for _, f := range rootFiles {
+		if listed[f.Path] {
+			logf("[WARN] 文件 %s 已在目录参数中输出，只输出一次\n", f.Rel)
+			continue
+		}
+		logf("直接输出文件: %s\n", f.Rel)
+		candidates = append(candidates, f)
+	}
+	return candidates
+}
+
+// rootFilesTree 为直接给出的文件构造目录树，按逻辑路径补齐中间目录
+func rootFilesTree() *treeNode {
+	root := &treeNode{Name: ".", IsDir: true}
+	for _, f := range rootFiles {
+		parent := root
+		parts := strings.Split(f.Rel, "/")
+		for _, part := range parts[:len(parts)-1] {
+			var next *treeNode
+			for _, child := range parent.Children {
+				if child.IsDir && child.Name == part {
+					next = child
+					break
+				}
+			}
+			if next == nil {
+				next = &treeNode{Name: part, IsDir: true}
+				parent.Children = append(parent.Children, next)
+			}
+			parent = next
+		}
+		parent.Children = append(parent.Children, &treeNode{Name: parts[len(parts)-1], fsPath: f.Path, logical: f.Rel})
+	}
+	sortRootFilesTree(root)
+	return root
+}
+
+// sortRootFilesTree 按名称与 --tree-order 排列同级节点
+func sortRootFilesTree(n *treeNode) {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		if a.IsDir != b.IsDir {
+			switch config.TreeOrder {
+			case "dirs-first":
+				return a.IsDir
+			case "files-first":
+				return b.IsDir
+			}
+		}
+		return a.Name < b.Name
+	})
+	for _, child := range n.Children {
+		sortRootFilesTree(child)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// usedRules 本次运行中至少命中过一次路径的过滤规则 (规范化后的形式)
+var usedRules = map[string]bool{}
+
+// markRuleUsed 记录决定了路径去留的规则，空规则 (没有规则命中) 忽略
+func markRuleUsed(rule string) {
+	if rule != "" {
+		usedRules[rule] = true
+	}
+}
+
+// printUnusedRules 列出从未命中任何路径的过滤规则，便于清理长期使用的 --config 文件中失效的规则
+func printUnusedRules(softFilters, hardFilters []string) {
+	type unusedRule struct{ kind, rule string }
+	var unused []unusedRule
+	seen := map[string]bool{}
+	add := func(kind string, rules []string) {
+		for _, rule := range rules {
+			if rule == "" || usedRules[rule] || seen[kind+"\x00"+rule] {
+				continue
+			}
+			seen[kind+"\x00"+rule] = true
+			unused = append(unused, unusedRule{kind, rule})
+		}
+	}
+	add("软过滤", softFilters)
+	add("硬过滤", hardFilters)
+	for _, kind := range []struct {
+		name  string
+		roots map[string][]string
+	}{{"软过滤", config.RootSoft}, {"硬过滤", config.RootHard}} {
+		var roots []string
+		for root := range kind.roots {
+			roots = append(roots, root)
+		}
+		sort.Strings(roots)
+		for _, root := range roots {
+			add(kind.name+" ("+root+")", normalizeFilters(kind.roots[root]))
+		}
+	}
+	if len(unused) == 0 {
+		return
+	}
+	fmt.Printf("[INFO] 以下 %d 条过滤规则在本次运行中未匹配任何路径，可考虑删除:\n", len(unused))
+	for _, u := range unused {
+		fmt.Printf("  %s: %s\n", u.kind, u.rule)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// maxWindowsPath Windows 传统 API 的路径长度上限 (MAX_PATH 260，预留结尾与盘符)
+const maxWindowsPath = 250
+
+// windowsReservedNames Windows 上不能作为文件名 (不区分大小写，忽略后缀) 的设备名
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// renamedFile 因文件名不合法或路径过长而改名写出的文件
+type renamedFile struct {
+	Original string
+	Written  string
+}
+
+// renamedFiles --explode 模式下改名写出的文件，在索引末尾列出对照表
+var renamedFiles []renamedFile
+
+// portableNames 是否按 Windows 规则处理文件名：在 Windows 上运行，或输出会打包为 zip 分享时
+func portableNames() bool {
+	return runtime.GOOS == "windows" || config.Archive == "zip"
+}
+
+// sanitizeSegment 处理单个路径段：控制字符总是替换；Windows 规则下还替换 <>:"|?*\ 、去掉结尾的点和空格，并避开设备名
+func sanitizeSegment(seg string) string {
+	windows := portableNames()
+	var sb strings.Builder
+	for _, r := range seg {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			sb.WriteRune('_')
+		case windows && strings.ContainsRune(`<>:"|?*\`, r):
+			sb.WriteRune('_')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	out := sb.String()
+	if !windows {
+		return out
+	}
+	out = strings.TrimRight(out, ". ")
+	if out == "" {
+		out = "_"
+	}
+	stem, _, _ := strings.Cut(out, ".")
+	if windowsReservedNames[strings.ToUpper(stem)] {
+		out = "_" + out
+	}
+	return out
+}
+
+// sanitizeRelPath 处理拆分输出中的相对路径 (正斜杠分隔)；Windows 规则下完整路径过长时缩短文件名并附加哈希
+func sanitizeRelPath(base string, rel string) string {
+	segments := strings.Split(rel, "/")
+	for i, seg := range segments {
+		segments[i] = sanitizeSegment(seg)
+	}
+	out := strings.Join(segments, "/")
+	if !portableNames() {
+		return out
+	}
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		absBase = base
+	}
+	over := len(absBase) + 1 + len(out) - maxWindowsPath
+	if over <= 0 {
+		return out
+	}
+	last := segments[len(segments)-1]
+	ext := filepath.Ext(strings.TrimSuffix(last, config.ExplodeExt)) + config.ExplodeExt
+	stem := strings.TrimSuffix(last, ext)
+	sum := sha256.Sum256([]byte(rel))
+	hash := "~" + hex.EncodeToString(sum[:4])
+	keep := len(stem) - over - len(hash)
+	if keep < 1 {
+		keep = 1
+	}
+	if keep < len(stem) {
+		stem = strings.ToValidUTF8(stem[:keep], "")
+	}
+	segments[len(segments)-1] = stem + hash + ext
+	out = strings.Join(segments, "/")
+	// 目录部分本身已经过长时，缩短文件名也无济于事，改为写到输出根目录下的 _long 目录
+	if len(absBase)+1+len(out) > maxWindowsPath {
+		out = "_long/" + hash[1:] + ext
+	}
+	return out
+}
+
+// sanitizeHeading 标题中的换行等控制字符会破坏 Markdown 结构，转义为可见形式
+func sanitizeHeading(s string) string {
+	if strings.IndexFunc(s, func(r rune) bool { return r < 0x20 || r == 0x7f || (r >= 0x80 && r <= 0x9f) }) < 0 {
+		return s
+	}
+	return escapeControl(s)
+}
+
+// linkTarget Markdown 链接目标：按段转义空格、括号等字符，避免链接失效
+func linkTarget(rel string) string {
+	segments := strings.Split(rel, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
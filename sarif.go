@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SARIF 2.1.0 中用到的结构，只包含代码扫描平台需要的字段
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           sarifRegion   `json:"region"`
+}
+
+type sarifArtifact struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifRuleDescriptions 各类发现对应的规则说明
+var sarifRuleDescriptions = map[string]string{
+	"pii/email":       "Possible email address in source",
+	"pii/phone":       "Possible phone number in source",
+	"pii/national-id": "Possible national ID or social security number in source",
+	"todo/TODO":       "TODO comment",
+	"todo/FIXME":      "FIXME comment",
+	"todo/HACK":       "HACK comment",
+	"todo/XXX":        "XXX comment",
+}
+
+// writeSARIF 将 --pii-scan 与 --todos 的发现导出为 SARIF，供安全与代码质量平台导入
+func writeSARIF(path string) error {
+	var results []sarifResult
+	for _, h := range piiHits {
+		results = append(results, sarifResult{
+			RuleID:    "pii/" + h.Kind,
+			Level:     "warning",
+			Message:   sarifMessage{Text: fmt.Sprintf("Possible %s: %s", h.Kind, h.Sample)},
+			Locations: []sarifLocation{sarifLocationFor(h.DisplayPath, h.Line)},
+		})
+	}
+	for _, item := range todoFindings {
+		text := item.Tag
+		if item.Text != "" {
+			text += ": " + item.Text
+		}
+		results = append(results, sarifResult{
+			RuleID:    "todo/" + item.Tag,
+			Level:     "note",
+			Message:   sarifMessage{Text: text},
+			Locations: []sarifLocation{sarifLocationFor(item.File, item.Line)},
+		})
+	}
+
+	used := map[string]bool{}
+	for _, r := range results {
+		used[r.RuleID] = true
+	}
+	var rules []sarifRule
+	for id := range used {
+		rules = append(rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: sarifRuleDescriptions[id]}})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:  "dir2txt",
+				Rules: rules,
+			}},
+			Results: results,
+		}},
+	}
+	if log.Runs[0].Results == nil {
+		// 没有发现时也输出空数组，部分平台不接受 null
+		log.Runs[0].Results = []sarifResult{}
+		log.Runs[0].Tool.Driver.Rules = []sarifRule{}
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// sarifLocationFor 当前目录下的文件使用相对路径 (相对于 %SRCROOT%)，代码扫描平台据此关联到仓库中的文件；
+// 其他位置的文件使用 file:// URI
+func sarifLocationFor(path string, line int) sarifLocation {
+	artifact := sarifArtifact{}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	wd, _ := os.Getwd()
+	if rel, err := filepath.Rel(wd, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		artifact.URI = linkTarget(filepath.ToSlash(rel))
+		artifact.URIBaseID = "%SRCROOT%"
+	} else {
+		u := url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+		if !strings.HasPrefix(u.Path, "/") {
+			// Windows 盘符路径
+			u.Path = "/" + u.Path
+		}
+		artifact.URI = u.String()
+	}
+	return sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: artifact,
+		Region:           sarifRegion{StartLine: line},
+	}}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// install-shell 子命令：安装一个 shell 函数 (默认名为 ctx)，在当前 git 仓库根目录运行 dir2txt
+// (自动使用仓库中的 .dir2txt 配置)，并把结果复制到剪贴板
+
+// shellSnippets 各 shell 的函数模板，__NAME__ 替换为函数名
+// 使用 --ci 以获得固定的输出文件名 dir2txt_context.md，额外参数原样传给 dir2txt
+var shellSnippets = map[string]string{
+	"bash": posixSnippet,
+	"zsh":  posixSnippet,
+	"fish": `function __NAME__ --description 'dir2txt: 输出当前仓库并复制到剪贴板'
+    set -l root (git rev-parse --show-toplevel 2>/dev/null; or pwd)
+    set -l tmp /tmp
+    set -q TMPDIR; and set tmp $TMPDIR
+    set -l out $tmp/dir2txt-__NAME__
+    pushd $root
+    dir2txt --ci -o $out/ $argv >/dev/null
+    set -l rc $status
+    popd
+    test $rc -eq 0; or return $rc
+    set -l file $out/dir2txt_context.md
+    if command -q pbcopy
+        pbcopy < $file
+    else if command -q wl-copy
+        wl-copy < $file
+    else if command -q xclip
+        xclip -selection clipboard < $file
+    else if command -q clip.exe
+        clip.exe < $file
+    else
+        echo "未找到剪贴板工具，输出位于 $file" >&2
+        return 1
+    end
+    echo "已复制到剪贴板: $file"
+end`,
+	"powershell": `function __NAME__ {
+    $root = git rev-parse --show-toplevel 2>$null
+    if (-not $root) { $root = (Get-Location).Path }
+    $out = Join-Path ([IO.Path]::GetTempPath()) "dir2txt-__NAME__"
+    Push-Location $root
+    try { dir2txt --ci -o "$out\" @args | Out-Null } finally { Pop-Location }
+    if ($LASTEXITCODE -ne 0) { return }
+    $file = Join-Path $out "dir2txt_context.md"
+    Get-Content -Raw -Encoding UTF8 $file | Set-Clipboard
+    Write-Host "已复制到剪贴板: $file"
+}`,
+}
+
+const posixSnippet = `__NAME__() {
+    local root out file
+    root=$(git rev-parse --show-toplevel 2>/dev/null || pwd)
+    out="${TMPDIR:-/tmp}/dir2txt-__NAME__"
+    (cd "$root" && dir2txt --ci -o "$out/" "$@" >/dev/null) || return
+    file="$out/dir2txt_context.md"
+    if command -v pbcopy >/dev/null 2>&1; then
+        pbcopy < "$file"
+    elif command -v wl-copy >/dev/null 2>&1; then
+        wl-copy < "$file"
+    elif command -v xclip >/dev/null 2>&1; then
+        xclip -selection clipboard < "$file"
+    elif command -v clip.exe >/dev/null 2>&1; then
+        clip.exe < "$file"
+    else
+        echo "未找到剪贴板工具，输出位于 $file" >&2
+        return 1
+    fi
+    echo "已复制到剪贴板: $file"
+}`
+
+// shellTarget 返回 shell 函数的写入位置，以及是否为独立文件 (fish 的 functions 目录)
+func shellTarget(shell, name string) (string, bool, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false, fmt.Errorf("无法确定用户主目录: %v", err)
+	}
+	switch shell {
+	case "bash":
+		return filepath.Join(home, ".bashrc"), false, nil
+	case "zsh":
+		return filepath.Join(home, ".zshrc"), false, nil
+	case "fish":
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", false, err
+		}
+		return filepath.Join(configDir, "fish", "functions", name+".fish"), true, nil
+	case "powershell":
+		dir := filepath.Join(home, "Documents", "WindowsPowerShell")
+		if runtime.GOOS != "windows" {
+			dir = filepath.Join(home, ".config", "powershell")
+		}
+		return filepath.Join(dir, "Microsoft.PowerShell_profile.ps1"), false, nil
+	}
+	return "", false, fmt.Errorf("不支持的 shell: %s (可选 bash、zsh、fish、powershell)", shell)
+}
+
+// detectShell 根据 $SHELL 推断当前 shell，Windows 下默认为 PowerShell
+func detectShell() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	switch name := filepath.Base(os.Getenv("SHELL")); name {
+	case "zsh", "fish":
+		return name
+	case "pwsh":
+		return "powershell"
+	}
+	return "bash"
+}
+
+// parseInstallShellArgs 解析 install-shell 子命令参数
+func parseInstallShellArgs(args []string) (shell string, name string, uninstall bool, print bool, help bool, err error) {
+	name = "ctx"
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--name":
+			if i+1 >= len(args) {
+				return shell, name, uninstall, print, help, fmt.Errorf("--name 需要一个函数名")
+			}
+			i++
+			name = args[i]
+		case strings.HasPrefix(arg, "--name="):
+			name = strings.TrimPrefix(arg, "--name=")
+		case arg == "--uninstall":
+			uninstall = true
+		case arg == "--print":
+			print = true
+		case arg == "--help" || arg == "-h":
+			help = true
+		case strings.HasPrefix(arg, "-"):
+			return shell, name, uninstall, print, help, fmt.Errorf("未知参数: %s", arg)
+		default:
+			if shell != "" {
+				return shell, name, uninstall, print, help, fmt.Errorf("只能指定一个 shell")
+			}
+			shell = strings.ToLower(arg)
+			if shell == "pwsh" {
+				shell = "powershell"
+			}
+		}
+	}
+	if shell == "" {
+		shell = detectShell()
+	}
+	for _, r := range name {
+		if !(r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return shell, name, uninstall, print, help, fmt.Errorf("无效的函数名: %s", name)
+		}
+	}
+	return shell, name, uninstall, print, help, nil
+}
+
+// runInstallShell 执行 install-shell 子命令
+func runInstallShell(args []string) error {
+	shell, name, uninstall, print, help, err := parseInstallShellArgs(args)
+	if help {
+		fmt.Println("用法: dir2txt install-shell [bash|zsh|fish|powershell] [--name ctx] [--print] [--uninstall]")
+		fmt.Println("  安装一个 shell 函数：在当前 git 仓库根目录运行 dir2txt (使用仓库中的 .dir2txt 配置) 并将结果复制到剪贴板")
+		fmt.Println("  函数的参数会原样传给 dir2txt，如 ctx --rank")
+		fmt.Println("  --print      只打印函数定义，不写入启动文件")
+		fmt.Println("  --uninstall  移除之前安装的函数")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	template, ok := shellSnippets[shell]
+	if !ok {
+		return fmt.Errorf("不支持的 shell: %s (可选 bash、zsh、fish、powershell)", shell)
+	}
+	snippet := strings.ReplaceAll(template, "__NAME__", name)
+	if print {
+		fmt.Println(snippet)
+		return nil
+	}
+
+	target, standalone, err := shellTarget(shell, name)
+	if err != nil {
+		return err
+	}
+	begin := fmt.Sprintf("# >>> dir2txt install-shell %s >>>", name)
+	end := fmt.Sprintf("# <<< dir2txt install-shell %s <<<", name)
+
+	if uninstall {
+		var changed bool
+		if standalone {
+			err = os.Remove(target)
+			changed = err == nil
+			if os.IsNotExist(err) {
+				err = nil
+			}
+		} else {
+			changed, err = removeMarkedBlock(target, begin, end)
+		}
+		if err != nil {
+			return err
+		}
+		if !changed {
+			fmt.Printf("未找到已安装的 %s 函数: %s\n", name, target)
+			return nil
+		}
+		fmt.Printf("[SUCCESS] 已移除 %s 函数: %s\n", name, target)
+		return nil
+	}
+
+	if standalone {
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, []byte(snippet+"\n"), 0o644); err != nil {
+			return err
+		}
+	} else {
+		// 重复安装时替换旧的定义
+		if _, err := removeMarkedBlock(target, begin, end); err != nil {
+			return err
+		}
+		if err := appendMarkedBlock(target, begin, end, snippet); err != nil {
+			return fmt.Errorf("无法写入 %s: %v", target, err)
+		}
+	}
+	fmt.Printf("[SUCCESS] 已安装 %s 函数 (%s): %s\n", name, shell, target)
+	fmt.Println("重新打开终端后，在仓库中运行 " + name + " 即可将上下文复制到剪贴板")
+	return nil
+}
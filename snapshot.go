@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotVersion 快照库格式变化时递增
+const snapshotVersion = 1
+
+// snapshotKeep 快照库中每组根目录保留的快照数，更早的快照在写入新快照时删除
+const snapshotKeep = 20
+
+// snapshotDB --snapshot 指定的快照库，按时间顺序保存最近的若干次运行。
+// 快照库是单个 gob 文件，每次运行整体读入并整体重写：dir2txt 只依赖标准库与少量纯 Go 包，
+// 而 --since-last 只需要上一次快照，不需要 SQLite/Bolt 的随机读写与查询
+type snapshotDB struct {
+	Version   int
+	Snapshots []snapshot
+}
+
+// snapshot 一次运行时通过过滤规则的全部文件 (键为 根目录名/相对路径)
+type snapshot struct {
+	Time  time.Time
+	Dirs  []string // 根目录的绝对路径 (已排序)，只与同一组根目录的快照对比
+	Files map[string]snapshotFile
+}
+
+// snapshotFile 单个文件的大小、修改时间与内容哈希
+type snapshotFile struct {
+	Size    int64
+	ModTime int64 // UnixNano
+	SHA256  string
+}
+
+// snapshotRun 本次运行的快照状态
+var snapshotRun struct {
+	db       *snapshotDB
+	roots    []string  // 本次运行的根目录，见 snapshotRoots
+	base     *snapshot // 同一组根目录的上一次快照，大小与修改时间未变时沿用其中的哈希
+	prev     *snapshot // --since-last 对比的上一次快照 (即 base)
+	current  map[string]snapshotFile
+	added    int
+	modified int
+}
+
+// loadSnapshotDB 读取快照库，文件不存在时返回空库
+func loadSnapshotDB(path string) (*snapshotDB, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &snapshotDB{Version: snapshotVersion}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法读取快照库: %v", err)
+	}
+	var db snapshotDB
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&db); err != nil {
+		return nil, fmt.Errorf("快照库已损坏: %s: %v", path, err)
+	}
+	if db.Version != snapshotVersion {
+		return nil, fmt.Errorf("快照库版本 %d 与当前程序 (%d) 不符: %s", db.Version, snapshotVersion, path)
+	}
+	return &db, nil
+}
+
+// snapshotRoots 返回排序后的根目录绝对路径；快照库可记录多个项目，按根目录区分各自的历史
+func snapshotRoots(dirs []string) []string {
+	roots := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		roots = append(roots, dir)
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// lastFor 快照库中同一组根目录最近的一次快照
+func (db *snapshotDB) lastFor(roots []string) *snapshot {
+	for i := len(db.Snapshots) - 1; i >= 0; i-- {
+		if slices.Equal(db.Snapshots[i].Dirs, roots) {
+			return &db.Snapshots[i]
+		}
+	}
+	return nil
+}
+
+// prune 每组根目录只保留最近 keep 次快照，一个项目频繁运行不会挤掉同一快照库中其他项目的历史
+func (db *snapshotDB) prune(keep int) {
+	counts := map[string]int{}
+	kept := make([]snapshot, 0, len(db.Snapshots))
+	for i := len(db.Snapshots) - 1; i >= 0; i-- {
+		key := strings.Join(db.Snapshots[i].Dirs, "\x00")
+		if counts[key] >= keep {
+			continue
+		}
+		counts[key]++
+		kept = append(kept, db.Snapshots[i])
+	}
+	slices.Reverse(kept)
+	db.Snapshots = kept
+}
+
+// initSnapshot 打开 --snapshot 快照库，取出同一组根目录的上一次快照；--since-last 时用于对比
+func initSnapshot(dirs []string) error {
+	if config.Snapshot == "" {
+		return nil
+	}
+	db, err := loadSnapshotDB(config.Snapshot)
+	if err != nil {
+		return err
+	}
+	snapshotRun.db = db
+	snapshotRun.roots = snapshotRoots(dirs)
+	snapshotRun.base = db.lastFor(snapshotRun.roots)
+	snapshotRun.current = map[string]snapshotFile{}
+	if abs, err := filepath.Abs(config.Snapshot); err == nil {
+		priorOutputs[abs] = true
+	}
+	if !config.SinceLast {
+		return nil
+	}
+	if snapshotRun.base == nil {
+		logf("[WARN] 快照库中还没有这些目录的快照，输出全部文件: %s\n", config.Snapshot)
+		return nil
+	}
+	snapshotRun.prev = snapshotRun.base
+	logf("与 %s 的快照对比，只输出新增与修改的文件\n", snapshotRun.prev.Time.Format("2006-01-02 15:04:05"))
+	return nil
+}
+
+// noteSnapshotFile 记录通过过滤规则的文件，返回与上一次快照相比是否有变化 (没有对比的快照时总是返回 true)；
+// 大小与修改时间都相同时沿用上一次的哈希，不再读取文件
+// 通过符号链接输出的文件记录链接目标的大小与修改时间
+func noteSnapshotFile(key string, path string) bool {
+	if snapshotRun.current == nil {
+		return true
+	}
+	info, err := statFile(path)
+	if err != nil {
+		return true
+	}
+	entry := snapshotFile{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+	var old snapshotFile
+	var existed bool
+	if base := snapshotRun.base; base != nil {
+		old, existed = base.Files[key]
+	}
+	if existed && old.Size == entry.Size && old.ModTime == entry.ModTime {
+		snapshotRun.current[key] = old
+		return false
+	}
+	if entry.SHA256, err = fileSHA256(path); err != nil {
+		return true
+	}
+	snapshotRun.current[key] = entry
+	if !existed {
+		snapshotRun.added++
+		return true
+	}
+	if old.SHA256 == entry.SHA256 {
+		return false
+	}
+	snapshotRun.modified++
+	return true
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// removedSinceSnapshot 上一次快照中存在、本次没有出现的文件 (已删除或不再通过过滤规则)
+func removedSinceSnapshot() []string {
+	if snapshotRun.prev == nil {
+		return nil
+	}
+	var removed []string
+	for key := range snapshotRun.prev.Files {
+		if _, ok := snapshotRun.current[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// sinceLastBaseline --since-last 且有可对比的快照：此时没有文件变化是正常结果，仍然输出文档、保存快照并以 0 退出
+func sinceLastBaseline() bool {
+	return config.SinceLast && snapshotRun.prev != nil
+}
+
+// writeSnapshotChanges --since-last 时在文档末尾列出自上次快照以来删除的文件
+func writeSnapshotChanges(writer *docWriter) {
+	if sinceLastBaseline() && stats.Included == 0 {
+		writer.WriteString("No files added or modified since the last snapshot.\n\n")
+	}
+	removed := removedSinceSnapshot()
+	if !config.SinceLast || len(removed) == 0 {
+		return
+	}
+	writer.WriteString(writer.docHeading("Removed Since Last Snapshot"))
+	for _, key := range removed {
+		writer.WriteString(fmt.Sprintf("- %s\n", writer.inlineCode(key)))
+	}
+	writer.WriteString("\n")
+}
+
+// saveSnapshot 将本次运行的文件记录为新快照写入快照库 (先写临时文件再改名)
+func saveSnapshot() error {
+	db := snapshotRun.db
+	if db == nil {
+		return nil
+	}
+	if config.SinceLast {
+		logf("与上次快照相比: 新增 %d 个、修改 %d 个、删除 %d 个文件\n", snapshotRun.added, snapshotRun.modified, len(removedSinceSnapshot()))
+	}
+	db.Snapshots = append(db.Snapshots, snapshot{Time: time.Now(), Dirs: snapshotRun.roots, Files: snapshotRun.current})
+	db.prune(snapshotKeep)
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(db); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Snapshot), 0o755); err != nil {
+		return err
+	}
+	tmp := partialPath(config.Snapshot)
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, config.Snapshot); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	logf("已记录快照 (%d 个文件): %s\n", len(snapshotRun.current), config.Snapshot)
+	return nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestSnapshotPrune 每组根目录各自保留最近的快照，不同项目互不挤占
+func TestSnapshotPrune(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := &snapshotDB{Version: snapshotVersion}
+	for i, dir := range []string{"/a", "/b", "/a", "/a", "/b", "/a"} {
+		db.Snapshots = append(db.Snapshots, snapshot{Time: base.Add(time.Duration(i) * time.Hour), Dirs: []string{dir}})
+	}
+	db.prune(2)
+
+	var got []string
+	for _, s := range db.Snapshots {
+		got = append(got, s.Dirs[0]+"@"+s.Time.Format("15"))
+	}
+	want := []string{"/b@01", "/a@03", "/b@04", "/a@05"}
+	if !slices.Equal(got, want) {
+		t.Errorf("prune(2) = %v, want %v", got, want)
+	}
+}
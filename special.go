@@ -0,0 +1,45 @@
+package main
+
+import "os"
+
+// specialModes FIFO、套接字与设备文件；读取命名管道会一直阻塞到有写入方，设备文件可能无限输出
+const specialModes = os.ModeNamedPipe | os.ModeSocket | os.ModeDevice | os.ModeCharDevice
+
+// specialFileKind 返回特殊文件的类型 (fifo/socket/device)，普通文件与目录返回空串；
+// 符号链接按其目标判断
+func specialFileKind(path string, d os.DirEntry) string {
+	mode := d.Type()
+	if mode&os.ModeSymlink != 0 {
+		info, err := os.Stat(path)
+		if err != nil {
+			return ""
+		}
+		mode = info.Mode()
+	}
+	return specialModeKind(mode)
+}
+
+func specialModeKind(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&(os.ModeDevice|os.ModeCharDevice) != 0:
+		return "device"
+	}
+	return ""
+}
+
+// reportedSpecial 已报告过的特殊文件，多次遍历时只报告一次
+var reportedSpecial = map[string]bool{}
+
+// reportSpecialFile 记录跳过的特殊文件
+func reportSpecialFile(path string, kind string) {
+	if reportedSpecial[path] {
+		return
+	}
+	reportedSpecial[path] = true
+	logf("[SKIP] 特殊文件 (%s): %s\n", kind, path)
+	noteExclusion("特殊文件 (FIFO、套接字、设备)")
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 退出码，供 CI 判断输出是否完整
+const (
+	exitOK          = 0 // 成功
+	exitError       = 1 // 参数错误或无法创建输出等致命错误
+	exitNoFiles     = 2 // 没有任何文件内容被写入
+	exitOverBudget  = 3 // 输出超过 --fail-over-tokens / --fail-over-size 限制
+	exitEncoding    = 4 // --strict 下存在无法识别编码的文件
+	exitPartialWalk = 5 // 部分目录遍历失败，输出不完整
+	exitWarnings    = 6 // --strict 下存在其他警告 (如文件读取失败)
+)
+
+// runStats 记录一次运行的统计信息
+type runStats struct {
+	Included       int // 写入内容的文件数
+	EncodingErrors int // 因无法识别编码而跳过的文件数
+	Warnings       int // 其他警告数 (文件读取失败、目录树生成失败等)
+	WalkErrors     int // 目录遍历失败数
+	CacheHits      int // --cache 命中的文件数
+}
+
+var stats runStats
+
+// output 统计写入输出文件的字节数与 token 数
+var output *countingWriter
+
+// countingWriter 统计写入的字节数并粗略估算 token 数
+type countingWriter struct {
+	w     io.Writer
+	bytes int64
+	ascii int64 // ASCII 字节数，约 4 个字节一个 token
+	wide  int64 // 非 ASCII 字符数 (按 UTF-8 首字节计)，约 1 个字符一个 token
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	for _, b := range p[:n] {
+		if b < 0x80 {
+			c.ascii++
+		} else if b >= 0xC0 {
+			c.wide++
+		}
+	}
+	c.bytes += int64(n)
+	return n, err
+}
+
+// Tokens 返回估算的 token 数
+func (c *countingWriter) Tokens() int64 {
+	return (c.ascii+3)/4 + c.wide
+}
+
+// estimateTokens 粗略估算一段内容的 token 数，与 countingWriter 使用相同的算法
+func estimateTokens(content []byte) int64 {
+	c := countingWriter{w: io.Discard}
+	c.Write(content)
+	return c.Tokens()
+}
+
+// parseSize 解析带单位的大小，如 "512K"、"10M"、"1G"、"2048"
+func parseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	str = strings.TrimSuffix(str, "B")
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(str, "K"):
+		multiplier = 1024
+	case strings.HasSuffix(str, "M"):
+		multiplier = 1024 * 1024
+	case strings.HasSuffix(str, "G"):
+		multiplier = 1024 * 1024 * 1024
+	}
+	if multiplier != 1 {
+		str = str[:len(str)-1]
+	}
+	n, err := strconv.ParseFloat(str, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("无效的大小: %s", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// formatSize 将字节数格式化为易读的形式
+func formatSize(n int64) string {
+	switch {
+	case n >= 1024*1024*1024:
+		return fmt.Sprintf("%.1fG", float64(n)/(1024*1024*1024))
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1fM", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1fK", float64(n)/1024)
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// exitCode 根据统计信息与输出大小计算最终退出码，并打印原因
+func exitCode(out *countingWriter) int {
+	if stats.WalkErrors > 0 {
+		errorf("[ERROR] %d 个目录遍历失败，输出不完整\n", stats.WalkErrors)
+		return exitPartialWalk
+	}
+	if stats.Included == 0 && !config.AllowEmpty && !sinceLastBaseline() {
+		errorf("[ERROR] 没有任何文件内容被写入输出\n")
+		return exitNoFiles
+	}
+	if config.FailOverTokens > 0 && out.Tokens() > config.FailOverTokens {
+		errorf("[ERROR] 输出约 %d tokens，超过限制 %d\n", out.Tokens(), config.FailOverTokens)
+		return exitOverBudget
+	}
+	if config.FailOverSize > 0 && out.bytes > config.FailOverSize {
+		errorf("[ERROR] 输出大小 %s，超过限制 %s\n", formatSize(out.bytes), formatSize(config.FailOverSize))
+		return exitOverBudget
+	}
+	if config.Strict && stats.EncodingErrors > 0 {
+		errorf("[ERROR] --strict: %d 个文件因无法识别编码被跳过\n", stats.EncodingErrors)
+		return exitEncoding
+	}
+	if config.Strict && stats.Warnings > 0 {
+		errorf("[ERROR] --strict: 运行中出现 %d 条警告\n", stats.Warnings)
+		return exitWarnings
+	}
+	return exitOK
+}
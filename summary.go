@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// maxSummaryText 摘要保留的最大字符数
+const maxSummaryText = 120
+
+// excludedFile 因预算或数量限制未输出内容的文件
+type excludedFile struct {
+	Path        string
+	DisplayPath string
+}
+
+// excludedFiles --summarize-excluded 需要生成摘要的文件
+var excludedFiles []excludedFile
+
+// noteExcluded 记录未输出内容的文件
+func noteExcluded(path, displayPath string) {
+	if config.SummarizeExcluded {
+		excludedFiles = append(excludedFiles, excludedFile{path, displayPath})
+	}
+}
+
+// commentPrefixes 识别注释行的前缀，按长度从长到短排列
+var commentPrefixes = []string{"<!--", "///", "//!", "//", "/**", "/*", "\"\"\"", "'''", "--", "#", "*", ";", "%"}
+
+// fileSummary 返回文件的一行摘要：前 30 行中第一条有内容的注释 (跳过许可证与生成代码声明)，否则为第一个非空行
+func fileSummary(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head, _ := io.ReadAll(io.LimitReader(f, 16*1024))
+	if isBinary(head) {
+		return "(binary)"
+	}
+	if converted, _, err := convertToUTF8(head); err == nil {
+		head = converted
+	}
+
+	firstLine := ""
+	for i, line := range strings.Split(string(head), "\n") {
+		if i >= 30 {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#!") {
+			continue
+		}
+		if firstLine == "" {
+			firstLine = trimmed
+		}
+		for _, prefix := range commentPrefixes {
+			if !strings.HasPrefix(trimmed, prefix) {
+				continue
+			}
+			text := strings.TrimSpace(strings.TrimPrefix(trimmed, prefix))
+			text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(text, "*/"), "-->"))
+			lower := strings.ToLower(text)
+			if len(text) < 3 || strings.Contains(lower, "copyright") || strings.Contains(lower, "license") ||
+				strings.Contains(lower, "spdx") || strings.Contains(lower, "do not edit") || strings.Trim(text, "-=*#/ ") == "" {
+				break
+			}
+			return truncateSummary(text)
+		}
+	}
+	return truncateSummary(firstLine)
+}
+
+func truncateSummary(text string) string {
+	if r := []rune(text); len(r) > maxSummaryText {
+		text = string(r[:maxSummaryText]) + "..."
+	}
+	return text
+}
+
+// writeExcludedSummaries 在附录中为每个未输出的文件写一行摘要
+func writeExcludedSummaries(writer *docWriter) {
+	if len(excludedFiles) == 0 {
+		return
+	}
+	writer.WriteString(writer.docHeading("Omitted File Summaries"))
+	var rows [][]string
+	for _, e := range excludedFiles {
+		size := ""
+		if info, err := os.Stat(e.Path); err == nil {
+			size = formatSize(info.Size())
+		}
+		rows = append(rows, []string{e.DisplayPath, size, fileSummary(e.Path)})
+	}
+	writer.writeTable([]string{"File", "Size", "Summary"}, rows)
+}
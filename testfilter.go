@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runTestFilter 实现 dir2txt test-filter 子命令：
+// 对给定路径逐条求值过滤规则，打印求值过程以及最终会被软过滤、硬过滤还是包含
+func runTestFilter(args []string) error {
+	paths, softRaw, hardRaw, _, help, _, _, err := parseCommandLine(args)
+	if help {
+		fmt.Println("用法: dir2txt test-filter <path> [-f <pattern> ...] [-F <pattern> ...] [-c/-Fc <file>]")
+		fmt.Println("  打印给定路径命中的规则、求值过程，以及它会被软过滤、硬过滤还是包含")
+		fmt.Println("  其他影响过滤的参数 (--hidden、--unignore、--keep 等) 同样生效")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("test-filter 需要至少一个路径")
+	}
+
+	softFilters := normalizeFilters([]string(softRaw))
+	hardFilters := normalizeFilters([]string(hardRaw))
+	for i, p := range paths {
+		if i > 0 {
+			fmt.Println()
+		}
+		explainPath(testFilterRel(p), softFilters, hardFilters)
+	}
+	return nil
+}
+
+// testFilterRel 将路径转换为过滤规则使用的相对路径 (正斜杠分隔)
+func testFilterRel(p string) string {
+	if filepath.IsAbs(p) {
+		if cwd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(cwd, p); err == nil && !strings.HasPrefix(rel, "..") {
+				p = rel
+			}
+		}
+	}
+	rel := filepath.ToSlash(filepath.Clean(p))
+	return strings.TrimPrefix(rel, "./")
+}
+
+// explainPath 打印单个路径的过滤求值过程与结论
+func explainPath(rel string, softFilters []string, hardFilters []string) {
+	fmt.Printf("路径: %s\n", rel)
+	parts := strings.Split(rel, "/")
+
+	// 1. 内置规则：任一路径段为垃圾文件/目录即完全隐藏
+	for i, part := range parts {
+		if isJunk(part) {
+			fmt.Printf("内置规则: %s 被视为垃圾文件/目录 (忽略目录、忽略文件名或隐藏文件)\n", strings.Join(parts[:i+1], "/"))
+			fmt.Println("结果: 硬过滤 (不出现在目录树和内容中)")
+			return
+		}
+	}
+
+	// 2. 硬过滤：上级目录被排除时不会继续遍历，子项无法被重新包含
+	if verdict, ok := explainRules("硬过滤", parts, hardFilters); ok {
+		fmt.Printf("结果: 硬过滤 (不出现在目录树和内容中) — %s\n", verdict)
+		return
+	}
+
+	// 3. 软过滤
+	if verdict, ok := explainRules("软过滤", parts, softFilters); ok {
+		fmt.Printf("结果: 软过滤 (出现在目录树中，但不输出内容) — %s\n", verdict)
+		return
+	}
+
+	// 4. 内置的资源文件/隐藏文件规则只跳过内容
+	name := parts[len(parts)-1]
+	for _, part := range parts {
+		if isHiddenTreeOnly(part) {
+			fmt.Printf("内置规则: %s 为隐藏文件，--hidden tree-only 策略下只在目录树中显示\n", part)
+			fmt.Println("结果: 仅目录树 (不输出内容)")
+			return
+		}
+	}
+	if isAsset(name) {
+		fmt.Printf("内置规则: %s 属于资源文件/锁文件 (按文件名或后缀识别)\n", name)
+		fmt.Println("结果: 仅目录树 (不输出内容)")
+		return
+	}
+
+	fmt.Println("结果: 包含 (出现在目录树中并输出内容，仍需通过大小、二进制和编码检查)")
+}
+
+// explainRules 按遍历顺序从最上级目录开始求值规则并打印过程，返回第一个被排除的层级说明
+func explainRules(kind string, parts []string, filters []string) (string, bool) {
+	set := newFilterSet(filters)
+	if set.Len() == 0 {
+		fmt.Printf("%s: 无规则\n", kind)
+		return "", false
+	}
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], "/")
+		isSelf := i == len(parts)-1
+		d := set.Match(prefix)
+		if !isSelf && !d.Matched {
+			continue
+		}
+
+		if isSelf {
+			fmt.Printf("%s规则求值 (%s):\n", kind, prefix)
+		} else {
+			fmt.Printf("%s规则求值 (上级目录 %s):\n", kind, prefix)
+		}
+		for j, r := range set.rules {
+			state := "未命中"
+			if hit := r.match(prefix); hit && r.negate {
+				state = "命中 -> 重新包含"
+			} else if hit {
+				state = "命中 -> 排除"
+			}
+			fmt.Printf("  [%d] %-24s %s\n", j+1, r.source, state)
+		}
+
+		if d.Matched {
+			if isSelf {
+				return fmt.Sprintf("最后命中的规则: \"%s\"", d.Rule), true
+			}
+			return fmt.Sprintf("上级目录 %s 被规则 \"%s\" 排除，其子项不会再被遍历", prefix, d.Rule), true
+		}
+		if d.Rule != "" {
+			fmt.Printf("  最后命中的规则 \"%s\" 为取反规则，路径被保留\n", d.Rule)
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// jsExts JavaScript/TypeScript 源文件扩展名，测试文件可与源文件扩展名不同 (如 foo.ts 与 foo.spec.js)
+var jsExts = map[string]bool{".js": true, ".jsx": true, ".ts": true, ".tsx": true, ".mjs": true, ".cjs": true}
+
+// testTarget 判断文件是否为测试文件，返回被测源文件的语言与文件名主干
+// 支持 Go 的 foo_test.go、Python 的 test_foo.py / foo_test.py、JS/TS 的 foo.spec.ts / foo.test.ts
+func testTarget(name string) (lang, stem string, ok bool) {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	switch {
+	case ext == ".go":
+		stem, ok = strings.CutSuffix(base, "_test")
+		return "go", stem, ok
+	case ext == ".py":
+		if stem, ok = strings.CutPrefix(base, "test_"); ok {
+			return "py", stem, true
+		}
+		stem, ok = strings.CutSuffix(base, "_test")
+		return "py", stem, ok
+	case jsExts[ext]:
+		for _, suffix := range []string{".spec", ".test"} {
+			if stem, ok = strings.CutSuffix(base, suffix); ok {
+				return "js", stem, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// sourceLang 返回有测试约定的源文件语言，其他文件 (以及 __init__.py、.d.ts 等) 返回空
+func sourceLang(name string) string {
+	ext := path.Ext(name)
+	switch {
+	case ext == ".go":
+		return "go"
+	case ext == ".py":
+		if name == "__init__.py" || name == "conftest.py" || name == "setup.py" {
+			return ""
+		}
+		return "py"
+	case jsExts[ext]:
+		if strings.HasSuffix(name, ".d.ts") || strings.Contains(name, ".config.") {
+			return ""
+		}
+		return "js"
+	}
+	return ""
+}
+
+// testMapping 一个源文件及其测试文件
+type testMapping struct {
+	Source string
+	Tests  []string
+}
+
+// buildTestMap 在输出的文件中为源文件配对测试文件
+// Go 测试必须与源文件在同一目录；Python 与 JS/TS 优先同目录 (或其 __tests__ 子目录)，
+// 否则在文件名主干唯一时匹配任意目录 (如 tests/test_foo.py)
+func buildTestMap(candidates []candidateFile) []testMapping {
+	type key struct{ lang, stem string }
+	tests := map[key][]string{}
+	var sources []string
+	sourceStems := map[key]int{}
+	for _, c := range candidates {
+		name := path.Base(c.Rel)
+		if lang, stem, ok := testTarget(name); ok {
+			tests[key{lang, stem}] = append(tests[key{lang, stem}], c.Rel)
+			continue
+		}
+		if lang := sourceLang(name); lang != "" {
+			sources = append(sources, c.Rel)
+			sourceStems[key{lang, strings.TrimSuffix(name, path.Ext(name))}]++
+		}
+	}
+
+	mappings := make([]testMapping, 0, len(sources))
+	for _, src := range sources {
+		name := path.Base(src)
+		k := key{sourceLang(name), strings.TrimSuffix(name, path.Ext(name))}
+		dir := path.Dir(src)
+		var near, far []string
+		for _, t := range tests[k] {
+			if td := path.Dir(t); td == dir || td == dir+"/__tests__" {
+				near = append(near, t)
+			} else {
+				far = append(far, t)
+			}
+		}
+		m := testMapping{Source: src, Tests: near}
+		if len(near) == 0 && k.lang != "go" && sourceStems[k] == 1 {
+			m.Tests = far
+		}
+		mappings = append(mappings, m)
+	}
+	sort.SliceStable(mappings, func(i, j int) bool {
+		return mappings[i].Source < mappings[j].Source
+	})
+	return mappings
+}
+
+// writeTestMap 输出源文件与测试文件的对应表，没有测试的文件单独标出
+func writeTestMap(mappings []testMapping, writer *docWriter) {
+	writer.WriteString(writer.docHeading("Test Map"))
+	if len(mappings) == 0 {
+		writer.WriteString("No Go, Python or JavaScript/TypeScript source files found.\n\n")
+		writer.writeSeparator()
+		return
+	}
+	tested := 0
+	for _, m := range mappings {
+		if len(m.Tests) > 0 {
+			tested++
+		}
+	}
+	writer.WriteString(fmt.Sprintf("Summary: %d of %d source files have tests (%.0f%%), %d untested.\n\n",
+		tested, len(mappings), float64(tested)*100/float64(len(mappings)), len(mappings)-tested))
+	var rows [][]string
+	for _, m := range mappings {
+		cell := writer.strong("untested")
+		if len(m.Tests) > 0 {
+			cell = strings.Join(m.Tests, ", ")
+		}
+		rows = append(rows, []string{m.Source, cell})
+	}
+	writer.writeTable([]string{"Source", "Tests"}, rows)
+	writer.writeSeparator()
+}
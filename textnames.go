@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// fileLang 返回常见无后缀文件 (如 Dockerfile、Makefile) 的代码块语言，只按完整文件名匹配，
+// README.md、NOTICE.txt 等带后缀的文件仍按后缀决定；Dockerfile.dev、app.dockerfile 等 Dockerfile 变体除外。
+// 不是已知文件名时 ok 为 false
+func fileLang(name string) (lang string, ok bool) {
+	if lang, ok := config.FileLangs[name]; ok {
+		return lang, true
+	}
+	lower := strings.ToLower(name)
+	if strings.HasPrefix(lower, "dockerfile.") || strings.HasPrefix(lower, "containerfile.") || strings.HasSuffix(lower, ".dockerfile") {
+		return "dockerfile", true
+	}
+	return "", false
+}
+
+// isKnownTextFile 判断是否为常见的文本文件名，不受 --unknown-ext skip 影响；二进制检测仍然进行
+func isKnownTextFile(name string) bool {
+	_, ok := fileLang(name)
+	return ok
+}
+
+// isUnknownExt 判断文件是否既不在文本后缀白名单中，也不是常见的文本文件名 (--unknown-ext skip 时跳过内容)
+func isUnknownExt(name string) bool {
+	if isKnownTextFile(name) {
+		return false
+	}
+	return !config.TextExts[strings.ToLower(filepath.Ext(name))]
+}
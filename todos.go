@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// todoRe 匹配注释中的 TODO/FIXME/HACK/XXX 标记，要求标记前有常见的注释符号
+var todoRe = regexp.MustCompile(`(?://|#|/\*|\*|--|<!--|;|%)\s*(TODO|FIXME|HACK|XXX)\b(?:\([^)]*\))?[\s:\-]*(.*)$`)
+
+// maxTodoText 表格中每条注释保留的最大字符数
+const maxTodoText = 120
+
+// todoItem 一条待办注释
+type todoItem struct {
+	Path string
+	File string // 文件系统路径，--sarif 导出时使用
+	Line int
+	Tag  string
+	Text string
+}
+
+// collectTodos 扫描将被输出的文件中的待办注释
+func collectTodos(ctx context.Context, candidates []candidateFile) []todoItem {
+	var items []todoItem
+	for _, c := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+		content, err := os.ReadFile(c.Path)
+		if err != nil || isBinary(content) {
+			continue
+		}
+		if converted, _, err := convertToUTF8(content); err == nil {
+			content = converted
+		}
+		for i, line := range strings.Split(string(content), "\n") {
+			m := todoRe.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			text := strings.TrimSpace(m[2])
+			text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(text, "*/"), "-->"))
+			if r := []rune(text); len(r) > maxTodoText {
+				text = string(r[:maxTodoText]) + "..."
+			}
+			items = append(items, todoItem{Path: c.Rel, File: c.Path, Line: i + 1, Tag: m[1], Text: text})
+		}
+	}
+	return items
+}
+
+// todoFindings 本次运行汇总的待办注释，供 --sarif 导出
+var todoFindings []todoItem
+
+// writeTodos 输出待办注释汇总表
+func writeTodos(items []todoItem, writer *docWriter) {
+	writer.WriteString(writer.docHeading("TODOs"))
+	if len(items) == 0 {
+		writer.WriteString("No TODO/FIXME/HACK/XXX comments found.\n\n")
+		writer.writeSeparator()
+		return
+	}
+	var rows [][]string
+	for _, item := range items {
+		rows = append(rows, []string{item.Path, strconv.Itoa(item.Line), item.Tag, item.Text})
+	}
+	writer.writeTable([]string{"File", "Line", "Tag", "Text"}, rows)
+	writer.writeSeparator()
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// transientErrnos SMB 共享与被其他进程短暂占用的文件上常见的瞬时错误，重试后通常可以成功
+var transientErrnos = []syscall.Errno{
+	windows.ERROR_SHARING_VIOLATION,
+	windows.ERROR_LOCK_VIOLATION,
+	windows.ERROR_NETWORK_BUSY,
+	windows.ERROR_UNEXP_NET_ERR,
+	windows.ERROR_NETNAME_DELETED,
+	windows.ERROR_SEM_TIMEOUT,
+	windows.ERROR_NETWORK_UNREACHABLE,
+}
+
+// isTransientIOError 判断错误是否值得重试
+func isTransientIOError(err error) bool {
+	for _, errno := range transientErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// treeFormats --tree-format 支持的目录树格式
+var treeFormats = map[string]bool{"ascii": true, "indent": true, "paths": true, "json": true}
+
+// treeOrders --tree-order 支持的排列方式
+var treeOrders = map[string]bool{"dirs-first": true, "mixed": true, "files-first": true}
+
+// treeNode 目录树中的一个节点
+type treeNode struct {
+	Name       string
+	Target     string // 符号链接的目标
+	IsDir      bool
+	Note       bool   // 说明节点，如折叠提示、未跨越的挂载点、目录环
+	Denied     bool   // --skip-unreadable 时无读取权限的文件
+	Special    string // FIFO、套接字与设备文件的类型 (fifo/socket/device)，只在树中列出
+	HardlinkOf string // 硬链接副本对应的已输出文件
+	Filtered   bool   // --tree-mark-filtered 时被硬过滤的项
+	Error      string // 根节点生成失败的原因
+	Children   []*treeNode
+
+	fsPath  string // 实际路径 (符号链接目录为其目标)
+	logical string // 逻辑路径，用于过滤与环检测
+}
+
+// noteNode 创建说明节点
+func noteNode(text string) *treeNode {
+	return &treeNode{Name: text, Note: true}
+}
+
+// foldTreeNodes 同级节点超过 maxDisplayFiles 时只保留首尾各几个，中间以说明节点代替
+func foldTreeNodes(nodes []*treeNode) []*treeNode {
+	if config.NoFold || len(nodes) <= maxDisplayFiles {
+		return nodes
+	}
+	hidden := nodes[keepHeadFiles : len(nodes)-keepTailFiles]
+	hiddenDirs := 0
+	for _, n := range hidden {
+		if n.IsDir {
+			hiddenDirs++
+		}
+	}
+	var text string
+	switch hiddenFiles := len(hidden) - hiddenDirs; {
+	case hiddenDirs == 0:
+		text = fmt.Sprintf("... (%d files hidden) ...", hiddenFiles)
+	case hiddenFiles == 0:
+		text = fmt.Sprintf("... (%d dirs hidden) ...", hiddenDirs)
+	default:
+		text = fmt.Sprintf("... (%d dirs, %d files hidden) ...", hiddenDirs, hiddenFiles)
+	}
+	display := make([]*treeNode, 0, keepHeadFiles+keepTailFiles+1)
+	display = append(display, nodes[:keepHeadFiles]...)
+	display = append(display, noteNode(text))
+	return append(display, nodes[len(nodes)-keepTailFiles:]...)
+}
+
+// label 节点的显示文本，dirSlash 为 true 时目录名后加 /
+func (n *treeNode) label(dirSlash bool) string {
+	label := n.Name
+	// 被过滤的目录不展开，始终加 / 以区别于文件
+	if (dirSlash || n.Filtered) && n.IsDir && !n.Note {
+		label += "/"
+	}
+	if n.Target != "" {
+		label += " -> " + n.Target
+	}
+	if n.Denied {
+		label += " (permission denied)"
+	}
+	if n.Special != "" {
+		label += " [" + n.Special + "]"
+	}
+	if n.HardlinkOf != "" {
+		label += " (hardlink of " + n.HardlinkOf + ")"
+	}
+	if n.Filtered {
+		label += " [filtered]"
+	}
+	return label
+}
+
+// buildProjectTree 生成各根目录的目录树，被中断时返回 false
+func buildProjectTree(ctx context.Context, dirs []string, hardFilters []string) ([]*treeNode, bool) {
+	var roots []*treeNode
+	for _, dir := range dirs {
+		root := &treeNode{Name: dir, IsDir: true}
+		roots = append(roots, root)
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			root.Error = fmt.Sprintf("Error generating tree: %v", err)
+			continue
+		}
+		root.Name = filepath.Base(absDir)
+		rootHard := filtersForRoot(absDir, hardFilters, config.RootHard)
+		root.Children, err = buildTree(ctx, newLocalFS(absDir), absDir, absDir, absDir, rootHard)
+		if ctx.Err() != nil {
+			return nil, false
+		}
+		if err != nil {
+			root.Error = fmt.Sprintf("Error generating tree for %s: %v", dir, err)
+			stats.Warnings++
+		}
+	}
+	if len(rootFiles) > 0 {
+		roots = append(roots, rootFilesTree())
+	}
+	return roots, true
+}
+
+// writeProjectTree 按 --tree-format 输出 "Project Structure" 章节
+func writeProjectTree(roots []*treeNode, candidates []candidateFile, writer *docWriter) {
+	lang := "text"
+	if config.TreeFormat == "json" {
+		lang = "json"
+	}
+	// 代码块中的链接不会被渲染，--tree-links 时以列表形式输出
+	linker, linked := writer.renderer.(treeLinker)
+	linked = linked && config.TreeLinks && config.Explode == ""
+	writer.WriteString(writer.docHeading("Project Structure"))
+
+	switch {
+	case linked:
+		anchors := linker.fileAnchors(candidates)
+		for _, root := range roots {
+			writer.WriteString("- " + linker.escapeLinkText(root.Name) + "/\n")
+			writeLinkedTree(root.Children, 1, anchors, linker, writer.Writer)
+			if root.Error != "" {
+				writer.WriteString("  - *" + linker.escapeLinkText(root.Error) + "*\n")
+			}
+		}
+		writer.WriteString("\n")
+		writer.writeSeparator()
+		return
+	}
+
+	// 目录树先写入缓冲区，再按输出格式包裹为代码块
+	var block bytes.Buffer
+	w := bufio.NewWriter(&block)
+	switch {
+	case config.TreeFormat == "json":
+		writeJSONTree(roots, w)
+	case config.TreeFormat == "paths":
+		var paths []string
+		for _, root := range roots {
+			paths = appendTreePaths(paths, root.Name, root.Children)
+			if root.Error != "" {
+				paths = append(paths, root.Name+"/ "+root.Error)
+			}
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			w.WriteString(p + "\n")
+		}
+	default:
+		for _, root := range roots {
+			w.WriteString(root.Name + "/\n")
+			if config.TreeFormat == "indent" {
+				writeIndentTree(root.Children, 1, w)
+			} else {
+				writeASCIITree(root.Children, "", w)
+			}
+			if root.Error != "" {
+				w.WriteString(root.Error + "\n")
+			}
+			w.WriteString("\n")
+		}
+	}
+	w.Flush()
+	writer.writeCodeBlock(lang, block.Bytes())
+	writer.writeSeparator()
+}
+
+// treeGlyphs 目录树的连接符：分支、最后一个分支、竖线延续
+type treeGlyphs struct{ branch, last, pipe string }
+
+var (
+	unicodeGlyphs = treeGlyphs{"├── ", "└── ", "│   "}
+	asciiGlyphs   = treeGlyphs{"|-- ", "`-- ", "|   "}
+)
+
+// writeASCIITree 以框线字符输出目录树 (默认格式)，--ascii 时只使用 ASCII 字符
+func writeASCIITree(nodes []*treeNode, prefix string, w *bufio.Writer) {
+	glyphs := unicodeGlyphs
+	if config.ASCIIGlyphs {
+		glyphs = asciiGlyphs
+	}
+	for i, n := range nodes {
+		marker, childPrefix := glyphs.branch, prefix+glyphs.pipe
+		if i == len(nodes)-1 {
+			marker, childPrefix = glyphs.last, prefix+"    "
+		}
+		w.WriteString(prefix + marker + n.label(false) + "\n")
+		writeASCIITree(n.Children, childPrefix, w)
+	}
+}
+
+// writeIndentTree 以两个空格缩进输出目录树，目录名后加 /，比框线字符更节省 token
+func writeIndentTree(nodes []*treeNode, depth int, w *bufio.Writer) {
+	indent := strings.Repeat("  ", depth)
+	for _, n := range nodes {
+		w.WriteString(indent + n.label(true) + "\n")
+		writeIndentTree(n.Children, depth+1, w)
+	}
+}
+
+// writeLinkedTree 以 Markdown 嵌套列表输出目录树，会输出内容的文件链接到其章节
+func writeLinkedTree(nodes []*treeNode, depth int, anchors map[string]string, linker treeLinker, w *bufio.Writer) {
+	indent := strings.Repeat("  ", depth)
+	for _, n := range nodes {
+		text := linker.escapeLinkText(n.label(true))
+		switch anchor, ok := anchors[n.fsPath]; {
+		case n.Note:
+			text = "*" + text + "*"
+		case ok && !n.IsDir:
+			text = linker.sectionLink(n.label(true), anchor)
+		}
+		w.WriteString(indent + "- " + text + "\n")
+		writeLinkedTree(n.Children, depth+1, anchors, linker, w)
+	}
+}
+
+// appendTreePaths 展开为完整路径列表：文件与空目录各占一行，目录以 / 结尾
+func appendTreePaths(paths []string, parent string, nodes []*treeNode) []string {
+	for _, n := range nodes {
+		p := parent + "/" + n.Name
+		if n.IsDir && len(n.Children) > 0 {
+			paths = appendTreePaths(paths, p, n.Children)
+			continue
+		}
+		paths = append(paths, parent+"/"+n.label(true))
+	}
+	return paths
+}
+
+// jsonTreeNode --tree-format json 的节点结构
+type jsonTreeNode struct {
+	Name             string          `json:"name"`
+	Type             string          `json:"type"` // dir/file/note，特殊文件为 fifo/socket/device
+	Target           string          `json:"target,omitempty"`
+	PermissionDenied bool            `json:"permission_denied,omitempty"`
+	HardlinkOf       string          `json:"hardlink_of,omitempty"`
+	Filtered         bool            `json:"filtered,omitempty"`
+	Error            string          `json:"error,omitempty"`
+	Children         []*jsonTreeNode `json:"children,omitempty"`
+}
+
+func toJSONTree(n *treeNode) *jsonTreeNode {
+	node := &jsonTreeNode{Name: n.Name, Type: "file", Target: n.Target, PermissionDenied: n.Denied, HardlinkOf: n.HardlinkOf, Filtered: n.Filtered, Error: n.Error}
+	switch {
+	case n.Note:
+		node.Type = "note"
+	case n.IsDir:
+		node.Type = "dir"
+	case n.Special != "":
+		node.Type = n.Special
+	}
+	for _, child := range n.Children {
+		node.Children = append(node.Children, toJSONTree(child))
+	}
+	return node
+}
+
+// writeJSONTree 以嵌套 JSON 输出目录树，多个根目录时为数组
+func writeJSONTree(roots []*treeNode, w *bufio.Writer) {
+	var value any
+	if len(roots) == 1 {
+		value = toJSONTree(roots[0])
+	} else {
+		var nodes []*jsonTreeNode
+		for _, root := range roots {
+			nodes = append(nodes, toJSONTree(root))
+		}
+		value = nodes
+	}
+	data, _ := json.MarshalIndent(value, "", "  ")
+	w.Write(data)
+	w.WriteString("\n")
+}
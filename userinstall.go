@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// 用户级安装 (--install --user)：无需 root/管理员权限
+// Linux/macOS 安装到 ~/.local/bin，Windows 安装到 %LOCALAPPDATA%\Programs\dir2txt
+
+const (
+	pathBlockBegin = "# >>> dir2txt --install --user >>>"
+	pathBlockEnd   = "# <<< dir2txt --install --user <<<"
+	// pathMarkerName Windows 下记录 PATH 条目由本程序添加的标记文件，卸载时据此清理
+	pathMarkerName = ".path-added"
+)
+
+// manageUserInstallation 按平台执行用户级安装/卸载
+func manageUserInstallation(isInstall bool) error {
+	if runtime.GOOS == "windows" {
+		return manageWindowsUser(isInstall)
+	}
+	return manageUnixUser(isInstall)
+}
+
+// copyExecutable 将当前运行的程序复制到目标路径
+func copyExecutable(targetPath string) error {
+	exePath, cleanup, err := installSource()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	fmt.Printf("正在安装: %s -> %s\n", exePath, targetPath)
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
+		return fmt.Errorf("无法创建目录: %v", err)
+	}
+
+	srcFile, err := os.Open(exePath)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	dstFile, err := os.OpenFile(targetPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o755)
+	if err != nil {
+		return fmt.Errorf("无法写入目标路径: %v", err)
+	}
+	defer dstFile.Close()
+	_, err = io.Copy(dstFile, srcFile)
+	return err
+}
+
+// inPath 判断目录是否已在当前 PATH 中
+func inPath(dir string) bool {
+	for _, p := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.Clean(p) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// shellProfiles 可能写入 PATH 设置的 shell 启动文件，第一个为按 $SHELL 选择的写入目标
+func shellProfiles(home string) []string {
+	profiles := []string{".profile", ".bashrc", ".zshrc"}
+	switch filepath.Base(os.Getenv("SHELL")) {
+	case "bash":
+		profiles = []string{".bashrc", ".profile", ".zshrc"}
+	case "zsh":
+		profiles = []string{".zshrc", ".profile", ".bashrc"}
+	}
+	for i, p := range profiles {
+		profiles[i] = filepath.Join(home, p)
+	}
+	return profiles
+}
+
+// hasMarkedBlock 判断启动文件中是否已有以 beginMarker 开头的片段
+func hasMarkedBlock(profile string, beginMarker string) bool {
+	data, err := os.ReadFile(profile)
+	return err == nil && strings.Contains(string(data), beginMarker)
+}
+
+// appendMarkedBlock 在启动文件末尾追加由首尾标记行包围的片段，卸载时据此删除
+func appendMarkedBlock(profile string, beginMarker, endMarker, body string) error {
+	if err := os.MkdirAll(filepath.Dir(profile), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(profile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "\n%s\n%s\n%s\n", beginMarker, strings.TrimRight(body, "\n"), endMarker)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// removeMarkedBlock 从启动文件中删除首尾标记行之间的片段，返回是否有修改
+func removeMarkedBlock(profile string, beginMarker, endMarker string) (bool, error) {
+	data, err := os.ReadFile(profile)
+	if err != nil {
+		return false, nil
+	}
+	content := string(data)
+	begin := strings.Index(content, beginMarker)
+	if begin < 0 {
+		return false, nil
+	}
+	end := strings.Index(content[begin:], endMarker)
+	if end < 0 {
+		return false, fmt.Errorf("%s 中由 dir2txt 添加的设置不完整，请手动删除", profile)
+	}
+	end += begin + len(endMarker)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	// 连同写入时添加的空行一起删除
+	if before := content[:begin]; before == "\n" || strings.HasSuffix(before, "\n\n") {
+		begin--
+	}
+	return true, os.WriteFile(profile, []byte(content[:begin]+content[end:]), 0o644)
+}
+
+func manageUnixUser(isInstall bool) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("无法确定用户主目录: %v", err)
+	}
+	binDir := filepath.Join(home, ".local", "bin")
+	targetPath := filepath.Join(binDir, "dir2txt")
+
+	if !isInstall {
+		fmt.Printf("正在卸载: %s\n", targetPath)
+		if err := os.Remove(targetPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("卸载失败: %v", err)
+		}
+		for _, profile := range shellProfiles(home) {
+			changed, err := removeMarkedBlock(profile, pathBlockBegin, pathBlockEnd)
+			if err != nil {
+				return err
+			}
+			if changed {
+				fmt.Printf("已从 %s 中移除 PATH 设置\n", profile)
+			}
+		}
+		fmt.Println("[SUCCESS] 卸载成功")
+		return nil
+	}
+
+	if err := copyExecutable(targetPath); err != nil {
+		return err
+	}
+	if inPath(binDir) {
+		fmt.Println("[SUCCESS] 安装成功！现在可以在任意位置运行 dir2txt")
+		return nil
+	}
+
+	profile := shellProfiles(home)[0]
+	if hasMarkedBlock(profile, pathBlockBegin) {
+		fmt.Printf("%s 中已有 PATH 设置，跳过。\n", profile)
+	} else {
+		if err := appendMarkedBlock(profile, pathBlockBegin, pathBlockEnd, `export PATH="$HOME/.local/bin:$PATH"`); err != nil {
+			return fmt.Errorf("无法更新 %s，请手动将 %s 添加到 PATH: %v", profile, binDir, err)
+		}
+		fmt.Printf("已将 %s 添加到 PATH (%s)\n", binDir, profile)
+	}
+	fmt.Println("[SUCCESS] 安装成功！请重新打开终端或执行 source " + profile)
+	return nil
+}
+
+func manageWindowsUser(isInstall bool) error {
+	localAppData := os.Getenv("LOCALAPPDATA")
+	if localAppData == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("无法确定 LOCALAPPDATA: %v", err)
+		}
+		localAppData = filepath.Join(home, "AppData", "Local")
+	}
+	installDir := filepath.Join(localAppData, "Programs", "dir2txt")
+	targetExe := filepath.Join(installDir, "dir2txt.exe")
+
+	if !isInstall {
+		removeInstallDirFromPath(installDir)
+		fmt.Printf("正在移除文件: %s\n", targetExe)
+		os.Remove(targetExe)
+		os.Remove(installDir)
+		fmt.Println("[SUCCESS] 卸载完成。")
+		return nil
+	}
+
+	if err := copyExecutable(targetExe); err != nil {
+		return err
+	}
+	addInstallDirToPath(installDir)
+	return nil
+}
+
+// addInstallDirToPath 将 Windows 安装目录加入用户 PATH，并在安装目录中留下标记文件
+func addInstallDirToPath(installDir string) {
+	fmt.Println("正在配置环境变量...")
+	added, err := addUserPath(installDir)
+	if err != nil {
+		fmt.Printf("[WARNING] 环境变量自动设置失败: %v\n请手动将 %s 添加到 PATH\n", err, installDir)
+		return
+	}
+	if !added {
+		fmt.Println("环境变量已存在，跳过。")
+		return
+	}
+	// 只有本程序添加的 PATH 条目才在卸载时移除
+	os.WriteFile(filepath.Join(installDir, pathMarkerName), []byte(installDir+"\n"), 0o644)
+	fmt.Println("环境变量已更新。安装完成！请重启终端以生效。")
+}
+
+// removeInstallDirFromPath 卸载时移除安装时添加的 PATH 条目 (以标记文件为准)
+func removeInstallDirFromPath(installDir string) {
+	marker := filepath.Join(installDir, pathMarkerName)
+	if _, err := os.Stat(marker); err != nil {
+		return
+	}
+	fmt.Println("正在从用户 PATH 中移除安装目录...")
+	if _, err := removeUserPath(installDir); err != nil {
+		fmt.Printf("[WARNING] 无法更新 PATH: %v\n请手动从 PATH 中删除 %s\n", err, installDir)
+		return
+	}
+	os.Remove(marker)
+}
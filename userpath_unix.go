@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import "fmt"
+
+// 用户 PATH 的注册表操作只在 Windows 上可用
+
+func addUserPath(dir string) (bool, error) {
+	return false, fmt.Errorf("仅支持 Windows")
+}
+
+func removeUserPath(dir string) (bool, error) {
+	return false, fmt.Errorf("仅支持 Windows")
+}
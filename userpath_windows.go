@@ -0,0 +1,117 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// 直接读写 HKCU\Environment 中的 Path，不依赖 PowerShell (脚本执行被禁用的机器上也可用)
+
+// readUserPath 读取用户 PATH 及其注册表类型，值不存在时返回空字符串与 REG_EXPAND_SZ
+func readUserPath(key registry.Key) (string, uint32, error) {
+	value, valType, err := key.GetStringValue("Path")
+	if err == registry.ErrNotExist {
+		return "", registry.EXPAND_SZ, nil
+	}
+	return value, valType, err
+}
+
+// writeUserPath 按原有类型写回用户 PATH 并通知其他程序环境变量已变化
+func writeUserPath(key registry.Key, value string, valType uint32) error {
+	var err error
+	if valType == registry.SZ {
+		err = key.SetStringValue("Path", value)
+	} else {
+		err = key.SetExpandStringValue("Path", value)
+	}
+	if err != nil {
+		return err
+	}
+	broadcastEnvironmentChange()
+	return nil
+}
+
+// samePathEntry 比较 PATH 条目 (忽略大小写与末尾的反斜杠)
+func samePathEntry(a, b string) bool {
+	return strings.EqualFold(strings.TrimRight(a, `\/`), strings.TrimRight(filepath.Clean(b), `\/`))
+}
+
+// addUserPath 将目录追加到用户 PATH，已存在时返回 false
+func addUserPath(dir string) (bool, error) {
+	key, err := registry.OpenKey(registry.CURRENT_USER, "Environment", registry.QUERY_VALUE|registry.SET_VALUE)
+	if err != nil {
+		return false, fmt.Errorf("无法打开注册表 HKCU\\Environment: %v", err)
+	}
+	defer key.Close()
+
+	current, valType, err := readUserPath(key)
+	if err != nil {
+		return false, fmt.Errorf("无法读取用户 PATH: %v", err)
+	}
+	for _, entry := range strings.Split(current, ";") {
+		if samePathEntry(entry, dir) {
+			return false, nil
+		}
+	}
+	updated := dir
+	if trimmed := strings.TrimRight(current, ";"); trimmed != "" {
+		updated = trimmed + ";" + dir
+	}
+	if err := writeUserPath(key, updated, valType); err != nil {
+		return false, fmt.Errorf("无法写入用户 PATH: %v", err)
+	}
+	return true, nil
+}
+
+// removeUserPath 从用户 PATH 中删除该目录，不存在时返回 false
+func removeUserPath(dir string) (bool, error) {
+	key, err := registry.OpenKey(registry.CURRENT_USER, "Environment", registry.QUERY_VALUE|registry.SET_VALUE)
+	if err != nil {
+		return false, fmt.Errorf("无法打开注册表 HKCU\\Environment: %v", err)
+	}
+	defer key.Close()
+
+	current, valType, err := readUserPath(key)
+	if err != nil {
+		return false, fmt.Errorf("无法读取用户 PATH: %v", err)
+	}
+	var kept []string
+	removed := false
+	for _, entry := range strings.Split(current, ";") {
+		if samePathEntry(entry, dir) {
+			removed = true
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	if !removed {
+		return false, nil
+	}
+	if err := writeUserPath(key, strings.Join(kept, ";"), valType); err != nil {
+		return false, fmt.Errorf("无法写入用户 PATH: %v", err)
+	}
+	return true, nil
+}
+
+// broadcastEnvironmentChange 广播 WM_SETTINGCHANGE，使资源管理器等新启动的终端读取到新的 PATH
+func broadcastEnvironmentChange() {
+	const (
+		hwndBroadcast   = 0xffff
+		wmSettingChange = 0x001A
+		smtoAbortIfHung = 0x0002
+	)
+	env, err := windows.UTF16PtrFromString("Environment")
+	if err != nil {
+		return
+	}
+	var result uintptr
+	windows.NewLazySystemDLL("user32.dll").NewProc("SendMessageTimeoutW").Call(
+		hwndBroadcast, wmSettingChange, 0, uintptr(unsafe.Pointer(env)), smtoAbortIfHung, 5000, uintptr(unsafe.Pointer(&result)))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeTree 在临时目录中创建文件，返回根目录
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "proj")
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// TestWalk 逐个交出通过过滤的文件，内容已解码，二进制文件与 Hard Filter 排除的文件不出现
+func TestWalk(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.go":       "package main\n",
+		"lib/util.py":   "\xef\xbb\xbfprint('hi')\n",
+		"build/out.txt": "generated",
+		"logo.bin":      "\x00\x01\x02",
+	})
+	got := map[string]File{}
+	err := Walk(context.Background(), WalkOptions{Dirs: []string{root}, HardFilters: []string{"build/"}}, func(f File) error {
+		got[f.RelPath] = f
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	var names []string
+	for name := range got {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if want := []string{"proj/lib/util.py", "proj/main.go"}; !slices.Equal(names, want) {
+		t.Fatalf("files = %v, want %v", names, want)
+	}
+	if f := got["proj/main.go"]; f.Lang != "go" || string(f.Content) != "package main\n" || f.Size != 13 {
+		t.Errorf("main.go = %+v", f)
+	}
+	if f := got["proj/lib/util.py"]; string(f.Content) != "print('hi')\n" {
+		t.Errorf("util.py content = %q, want BOM removed", f.Content)
+	}
+}
+
+// TestWalkStop 回调返回错误时停止遍历并原样返回
+func TestWalkStop(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"})
+	stop := errors.New("stop")
+	calls := 0
+	err := Walk(context.Background(), WalkOptions{Dirs: []string{root}}, func(File) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("Walk = %v after %d calls, want stop after 1", err, calls)
+	}
+}
+
+// TestWalkCanceled ctx 已取消时不调用回调
+func TestWalkCanceled(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "a"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Walk(ctx, WalkOptions{Dirs: []string{root}}, func(File) error {
+		t.Error("fn called after cancel")
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Walk = %v, want context.Canceled", err)
+	}
+}
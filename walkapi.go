@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// File Walk 逐个交给回调的文件记录，内容已去除 BOM 并转换为 UTF-8
+type File struct {
+	Path    string // 文件系统路径
+	RelPath string // 以根目录名开头的逻辑相对路径 (正斜杠分隔)
+	Lang    string // 代码块使用的语言标识，无法识别时为 text
+	Content []byte
+	Size    int64
+	ModTime time.Time
+}
+
+// fileRecord 由已读取的文件生成交给 Walk 回调与 Renderer 的记录
+func fileRecord(fc *fileContent) File {
+	return File{
+		Path:    fc.Path,
+		RelPath: fc.RelPath,
+		Lang:    codeLang(fc.Path),
+		Content: fc.data(),
+		Size:    fc.Size,
+		ModTime: fc.ModTime,
+	}
+}
+
+// WalkOptions Walk 遍历的目录与过滤规则，规则语法与命令行的 Soft/Hard Filter 相同
+type WalkOptions struct {
+	Dirs        []string
+	SoftFilters []string
+	HardFilters []string
+}
+
+// Walk 按与生成文档相同的过滤、忽略与解码规则遍历目录，每读取一个文件就交给 fn，不缓冲整个快照
+// fn 返回错误时停止遍历并返回该错误；其余选项 (如 --hidden、--binary-threshold、--max-files-per-dir) 沿用全局配置
+// 这是 package main 内部的接口，供同一程序内的扩展代码调用，不能被其他模块导入
+func Walk(ctx context.Context, opts WalkOptions, fn func(File) error) error {
+	dirs := opts.Dirs
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	candidates, firstErr := collectCandidates(ctx, dirs, opts.SoftFilters, opts.HardFilters, "")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if config.MaxFilesPerDir > 0 {
+		candidates = capFilesPerDir(candidates, config.MaxFilesPerDir)
+	}
+	for _, c := range candidates {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fc := c.prepare()
+		if fc == nil {
+			continue
+		}
+		applyPIIScan(fc)
+		if err := fn(fileRecord(fc)); err != nil {
+			return err
+		}
+	}
+	return firstErr
+}
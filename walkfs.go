@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// localWalk 本地目录遍历特有的处理，由 localFS 实现；遍历其他 fs.FS (embed.FS、fstest.MapFS、zip 等) 时不需要
+type localWalk interface {
+	// readDir 读取目录 (fsPath 为实际文件系统路径)
+	readDir(fsPath string) ([]fs.DirEntry, error)
+	// entry 解析目录条目：跟随符号链接目录，返回实际路径与是否为目录；skip 为 true 时不交给回调
+	// (同时作为根目录给出的子目录、FIFO 等特殊文件)
+	entry(parent string, d fs.DirEntry) (fsPath string, isDir bool, skip bool)
+	// descend 是否进入目录 (logicalRel 为相对根目录的逻辑路径)：跨文件系统 (--one-file-system) 或目录环时返回 false
+	descend(fsPath string, logicalRel string) bool
+}
+
+// localFS 本地目录 root 的 fs.FS：打开文件由 os.DirFS 完成，遍历时跟随符号链接目录，并按设备号与 inode 识别目录环
+type localFS struct {
+	fs.FS
+	root string
+	seen map[string]string
+}
+
+func newLocalFS(root string) *localFS {
+	return &localFS{FS: os.DirFS(root), root: root, seen: newSeenDirs(root)}
+}
+
+func (l *localFS) readDir(fsPath string) ([]fs.DirEntry, error) {
+	return readDir(fsPath)
+}
+
+func (l *localFS) entry(parent string, d fs.DirEntry) (string, bool, bool) {
+	fsPath := filepath.Join(parent, d.Name())
+	isDir := d.IsDir()
+	// 跟随符号链接目录
+	if d.Type()&os.ModeSymlink != 0 {
+		if target, err := filepath.EvalSymlinks(fsPath); err == nil {
+			if info, err := os.Stat(target); err == nil && info.IsDir() {
+				fsPath, isDir = target, true
+			}
+		}
+	}
+	// 同时作为根目录给出的子目录由其自身的遍历输出
+	if isDir && nestedRoots[fsPath] {
+		return fsPath, isDir, true
+	}
+	// FIFO、套接字与设备文件不交给回调，避免读取时阻塞
+	if !isDir {
+		if kind := specialFileKind(fsPath, d); kind != "" {
+			reportSpecialFile(fsPath, kind)
+			return fsPath, isDir, true
+		}
+	}
+	return fsPath, isDir, false
+}
+
+func (l *localFS) descend(fsPath string, logicalRel string) bool {
+	logical := filepath.Join(l.root, logicalRel)
+	if crossesFileSystem(l.root, fsPath, logical) {
+		return false
+	}
+	// 按设备号+inode 识别符号链接环与 bind mount 回环
+	if id, ok := dirIdentity(fsPath); ok {
+		if first, dup := l.seen[id]; dup {
+			loopMarker(logical, first)
+			return false
+		}
+		l.seen[id] = logical
+	}
+	return true
+}
+
+// walkFollowSymlinks 遍历本地目录，跟随符号链接的目录，保持逻辑路径用于过滤
+// ctx 被取消时停止遍历并返回 ctx.Err()
+func walkFollowSymlinks(ctx context.Context, root string, fn func(logicalRel string, fullPath string, d os.DirEntry) error) error {
+	return walkFS(ctx, newLocalFS(root), root, fn)
+}
+
+// walkFS 遍历 fsys 中 root 之下的全部条目：先把一个目录的条目依次交给 fn，再进入其中的子目录；
+// fn 返回 filepath.SkipDir 时不进入该目录。logicalRel 为相对 root 的逻辑路径 (使用符号链接名字串接)，
+// fullPath 为条目的实际路径：本地目录 (localFS) 为文件系统路径，其他 fs.FS 为其中的名称。
+// ctx 被取消时停止遍历并返回 ctx.Err()
+func walkFS(ctx context.Context, fsys fs.FS, root string, fn func(logicalRel string, fullPath string, d fs.DirEntry) error) error {
+	type node struct {
+		fsPath string // 实际路径（可能为解析后的目标路径）
+		rel    string // 相对 root 的逻辑路径，以 / 分隔
+	}
+	local, isLocal := fsys.(localWalk)
+
+	stack := []node{{fsPath: root, rel: ""}}
+	for len(stack) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		var entries []fs.DirEntry
+		var err error
+		if isLocal {
+			entries, err = local.readDir(n.fsPath)
+		} else {
+			entries, err = fs.ReadDir(fsys, n.fsPath)
+		}
+		if err != nil {
+			// 根目录无法读取时整个遍历失败；子目录 (权限不足等) 只记录警告并跳过
+			if n.rel == "" {
+				return err
+			}
+			reportUnreadableDir(n.fsPath, err)
+			continue
+		}
+
+		for _, entry := range entries {
+			rel := path.Join(n.rel, entry.Name())
+			logicalRel := filepath.FromSlash(rel)
+			childFSPath, childIsDir := path.Join(n.fsPath, entry.Name()), entry.IsDir()
+			if isLocal {
+				var skip bool
+				if childFSPath, childIsDir, skip = local.entry(n.fsPath, entry); skip {
+					continue
+				}
+			}
+
+			// 先把当前条目交给回调
+			if err := fn(logicalRel, childFSPath, entry); err != nil {
+				if errors.Is(err, filepath.SkipDir) {
+					continue
+				}
+				return err
+			}
+
+			if childIsDir {
+				if isLocal && !local.descend(childFSPath, logicalRel) {
+					continue
+				}
+				stack = append(stack, node{fsPath: childFSPath, rel: rel})
+			}
+		}
+	}
+
+	return nil
+}
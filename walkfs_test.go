@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// walkNames 遍历 fsys，返回交给回调的逻辑路径 (按回调顺序)；skip 中的目录返回 SkipDir
+func walkNames(t *testing.T, ctx context.Context, fsys fs.FS, root string, skip ...string) ([]string, error) {
+	t.Helper()
+	var names []string
+	err := walkFS(ctx, fsys, root, func(logicalRel string, fullPath string, d fs.DirEntry) error {
+		names = append(names, filepath.ToSlash(logicalRel))
+		if slices.Contains(skip, filepath.ToSlash(logicalRel)) {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return names, err
+}
+
+// TestWalkFS 先交出一个目录的全部条目再进入子目录，SkipDir 的目录不展开
+func TestWalkFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":          {Data: []byte("a")},
+		"src/main.go":    {Data: []byte("package main")},
+		"src/util/u.go":  {Data: []byte("package util")},
+		"vendor/x/x.go":  {Data: []byte("package x")},
+		"empty":          {Mode: fs.ModeDir},
+		"docs/README.md": {Data: []byte("# docs")},
+	}
+	names, err := walkNames(t, context.Background(), fsys, ".", "vendor")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"a.txt", "docs", "docs/README.md", "empty", "src", "src/main.go", "src/util", "src/util/u.go", "vendor"}
+	got := slices.Sorted(slices.Values(names))
+	if !slices.Equal(got, want) {
+		t.Fatalf("walkFS = %v, want %v", got, want)
+	}
+	// 根目录的条目都在子目录的条目之前
+	for _, top := range []string{"a.txt", "docs", "empty", "src", "vendor"} {
+		if slices.Index(names, top) > slices.Index(names, "src/main.go") {
+			t.Errorf("%s 在 src/main.go 之后: %v", top, names)
+		}
+	}
+}
+
+// TestWalkFSSubdir 从 fs.FS 中的子目录开始遍历时，逻辑路径相对该子目录，fullPath 为 FS 中的名称
+func TestWalkFSSubdir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/main.go":   {Data: []byte("package main")},
+		"src/util/u.go": {Data: []byte("package util")},
+	}
+	full := map[string]string{}
+	err := walkFS(context.Background(), fsys, "src", func(logicalRel string, fullPath string, d fs.DirEntry) error {
+		full[filepath.ToSlash(logicalRel)] = fullPath
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"main.go": "src/main.go", "util": "src/util", "util/u.go": "src/util/u.go"}
+	if len(full) != len(want) {
+		t.Fatalf("walkFS = %v, want %v", full, want)
+	}
+	for rel, path := range want {
+		if full[rel] != path {
+			t.Errorf("%s: fullPath = %q, want %q", rel, full[rel], path)
+		}
+	}
+}
+
+// TestWalkFSErrors 根目录无法读取时返回错误，ctx 被取消时返回 ctx.Err()
+func TestWalkFSErrors(t *testing.T) {
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("a")}}
+	if _, err := walkNames(t, context.Background(), fsys, "missing"); err == nil {
+		t.Error("遍历不存在的根目录没有返回错误")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := walkNames(t, ctx, fsys, "."); !errors.Is(err, context.Canceled) {
+		t.Errorf("ctx 取消后 walkFS 返回 %v", err)
+	}
+}
+
+// TestWalkFollowSymlinks 本地目录跟随符号链接目录，指回上级的链接只展开一次
+func TestWalkFollowSymlinks(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a", "b"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a", "b", "f.txt"), []byte("f"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "a"), filepath.Join(root, "a", "b", "loop")); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "a", "b"), filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	err := walkFollowSymlinks(context.Background(), root, func(logicalRel string, fullPath string, d os.DirEntry) error {
+		names = append(names, filepath.ToSlash(logicalRel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a/b/loop 指回 a，link 与 a/b 是同一目录：按设备号与 inode 各只展开一次
+	for _, name := range names {
+		if strings.Contains(name, "loop/") {
+			t.Errorf("目录环 loop 应列出但不展开: %v", names)
+			break
+		}
+	}
+	if slices.Contains(names, "a/b/f.txt") == slices.Contains(names, "link/f.txt") {
+		t.Errorf("a/b 与 link 应只展开其中一个: %v", names)
+	}
+}
+
+// treeNames 按目录树顺序列出节点的逻辑路径 (目录以 / 结尾)
+func treeNames(nodes []*treeNode, prefix string) []string {
+	var names []string
+	for _, n := range nodes {
+		name := prefix + n.Name
+		if n.IsDir {
+			names = append(names, name+"/")
+			names = append(names, treeNames(n.Children, name+"/")...)
+		} else {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// TestBuildTreeFS 目录树同样可以由任意 fs.FS 生成，硬过滤按逻辑路径生效
+func TestBuildTreeFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"b.txt":          {Data: []byte("b")},
+		"src/main.go":    {Data: []byte("package main")},
+		"vendor/x/x.go":  {Data: []byte("package x")},
+		"docs/README.md": {Data: []byte("# docs")},
+	}
+	nodes, err := buildTree(context.Background(), fsys, ".", ".", ".", newFilterSet([]string{"vendor"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"docs/", "docs/README.md", "src/", "src/main.go", "b.txt"}
+	if got := treeNames(nodes, ""); !slices.Equal(got, want) {
+		t.Errorf("buildTree = %v, want %v", got, want)
+	}
+}
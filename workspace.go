@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// workspacePackage monorepo 中的一个工作区包
+type workspacePackage struct {
+	Name     string // 清单中声明的包名 (package.json name、go.mod module、Cargo.toml package.name)
+	Dir      string // 包目录 (绝对路径)
+	Manifest string // 声明该包的工作区清单文件名
+}
+
+// workspaceManifests 支持的工作区清单及其成员解析函数，返回成员目录的 glob
+var workspaceManifests = []struct {
+	file    string
+	members func(content []byte) []string
+}{
+	{"pnpm-workspace.yaml", pnpmWorkspaceMembers},
+	{"package.json", packageJSONWorkspaceMembers},
+	{"lerna.json", lernaMembers},
+	{"go.work", goWorkMembers},
+	{"Cargo.toml", cargoWorkspaceMembers},
+}
+
+// discoverWorkspaces 读取根目录下的工作区清单，列出所有成员包
+func discoverWorkspaces(root string) ([]workspacePackage, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []workspacePackage
+	seen := map[string]bool{}
+	for _, m := range workspaceManifests {
+		content, err := os.ReadFile(filepath.Join(absRoot, m.file))
+		if err != nil {
+			continue
+		}
+		var include, exclude []string
+		for _, pattern := range m.members(content) {
+			if rest, ok := strings.CutPrefix(pattern, "!"); ok {
+				exclude = append(exclude, expandWorkspaceGlob(absRoot, rest)...)
+			} else {
+				include = append(include, expandWorkspaceGlob(absRoot, pattern)...)
+			}
+		}
+		excluded := map[string]bool{}
+		for _, dir := range exclude {
+			excluded[dir] = true
+		}
+		for _, dir := range include {
+			if excluded[dir] || seen[dir] {
+				continue
+			}
+			name := workspacePackageName(dir)
+			if name == "" {
+				continue
+			}
+			seen[dir] = true
+			pkgs = append(pkgs, workspacePackage{Name: name, Dir: dir, Manifest: m.file})
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Dir < pkgs[j].Dir
+	})
+	return pkgs, nil
+}
+
+// resolveWorkspaces 将 --workspace 指定的包名解析为目录；名称可以是声明的包名，也可以是目录名或相对路径
+func resolveWorkspaces(root string, names []string) ([]string, error) {
+	pkgs, err := discoverWorkspaces(root)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("%s 中没有找到工作区清单 (pnpm-workspace.yaml、package.json workspaces、lerna.json、go.work、Cargo.toml [workspace])", root)
+	}
+	absRoot, _ := filepath.Abs(root)
+	var dirs []string
+	for _, name := range names {
+		var matched []string
+		for _, p := range pkgs {
+			rel, _ := filepath.Rel(absRoot, p.Dir)
+			if p.Name == name || filepath.Base(p.Dir) == name || filepath.ToSlash(rel) == strings.Trim(name, "/") {
+				matched = append(matched, p.Dir)
+			}
+		}
+		switch len(matched) {
+		case 0:
+			var available []string
+			for _, p := range pkgs {
+				available = append(available, p.Name)
+			}
+			return nil, fmt.Errorf("未找到工作区包 %s，可用的包: %s", name, strings.Join(available, ", "))
+		case 1:
+			dirs = append(dirs, matched[0])
+		default:
+			return nil, fmt.Errorf("工作区包名 %s 有歧义，匹配到: %s", name, strings.Join(matched, ", "))
+		}
+	}
+	return dirs, nil
+}
+
+// expandWorkspaceGlob 展开成员 glob，支持 ** 匹配任意层目录
+func expandWorkspaceGlob(root, pattern string) []string {
+	pattern = strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(pattern), "./"), "/")
+	if pattern == "" {
+		return nil
+	}
+	prefix, _, recursive := strings.Cut(pattern, "**")
+	if !recursive {
+		matches, _ := filepath.Glob(filepath.Join(root, filepath.FromSlash(pattern)))
+		return matches
+	}
+	var dirs []string
+	base := filepath.Join(root, filepath.FromSlash(strings.TrimSuffix(prefix, "/")))
+	filepath.WalkDir(base, func(p string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if p != base && (config.IgnoredDirs[d.Name()] || isHidden(d.Name())) {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, p)
+		return nil
+	})
+	return dirs
+}
+
+// workspacePackageName 读取成员目录中的清单获取包名，没有清单时返回空
+func workspacePackageName(dir string) string {
+	if content, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		var pkg struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(content, &pkg) == nil && pkg.Name != "" {
+			return pkg.Name
+		}
+		return filepath.Base(dir)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+				return strings.Trim(strings.TrimSpace(rest), `"`)
+			}
+		}
+		return filepath.Base(dir)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "Cargo.toml")); err == nil {
+		section := ""
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "[") {
+				section = strings.Trim(line, "[] ")
+				continue
+			}
+			if key, value, ok := strings.Cut(line, "="); ok && section == "package" && strings.TrimSpace(key) == "name" {
+				return strings.Trim(strings.TrimSpace(value), `"'`)
+			}
+		}
+		return filepath.Base(dir)
+	}
+	return ""
+}
+
+// pnpmWorkspaceMembers 解析 pnpm-workspace.yaml 中的 packages 列表
+func pnpmWorkspaceMembers(content []byte) []string {
+	var members []string
+	inPackages := false
+	for _, raw := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(raw, " ") && !strings.HasPrefix(raw, "-") {
+			inPackages = strings.HasPrefix(trimmed, "packages:")
+			continue
+		}
+		if item, ok := strings.CutPrefix(trimmed, "-"); ok && inPackages {
+			members = append(members, strings.Trim(strings.TrimSpace(item), `"'`))
+		}
+	}
+	return members
+}
+
+// packageJSONWorkspaceMembers 解析 npm/yarn 的 workspaces 字段 (数组或 {packages: [...]})
+func packageJSONWorkspaceMembers(content []byte) []string {
+	var pkg struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if json.Unmarshal(content, &pkg) != nil || len(pkg.Workspaces) == 0 {
+		return nil
+	}
+	var members []string
+	if json.Unmarshal(pkg.Workspaces, &members) == nil {
+		return members
+	}
+	var nested struct {
+		Packages []string `json:"packages"`
+	}
+	json.Unmarshal(pkg.Workspaces, &nested)
+	return nested.Packages
+}
+
+// lernaMembers 解析 lerna.json 的 packages 字段 (未声明时为 lerna 的默认值 packages/*)
+func lernaMembers(content []byte) []string {
+	var cfg struct {
+		Packages []string `json:"packages"`
+	}
+	if json.Unmarshal(content, &cfg) != nil {
+		return nil
+	}
+	if len(cfg.Packages) == 0 {
+		return []string{"packages/*"}
+	}
+	return cfg.Packages
+}
+
+// goWorkMembers 解析 go.work 中的 use 指令 (单行与块形式)
+func goWorkMembers(content []byte) []string {
+	var members []string
+	inBlock := false
+	for _, line := range strings.Split(string(content), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			members = append(members, strings.Trim(line, `"`))
+		case line == "use (":
+			inBlock = true
+		case strings.HasPrefix(line, "use "):
+			members = append(members, strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "use ")), `"`))
+		}
+	}
+	return members
+}
+
+var tomlStringRe = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+
+// cargoWorkspaceMembers 解析 Cargo.toml [workspace] 中的 members 与 exclude 数组 (可跨多行)
+func cargoWorkspaceMembers(content []byte) []string {
+	var members []string
+	section, key := "", ""
+	for _, line := range strings.Split(string(content), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && !strings.HasPrefix(line, "[\"") && key == "" {
+			section = strings.Trim(line, "[] ")
+			continue
+		}
+		if section != "workspace" {
+			continue
+		}
+		if key == "" {
+			k, value, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			if k = strings.TrimSpace(k); k != "members" && k != "exclude" {
+				continue
+			}
+			key, line = k, value
+		}
+		for _, m := range tomlStringRe.FindAllStringSubmatch(line, -1) {
+			member := m[1] + m[2]
+			if key == "exclude" {
+				member = "!" + member
+			}
+			members = append(members, member)
+		}
+		if strings.Contains(line, "]") {
+			key = ""
+		}
+	}
+	return members
+}